@@ -37,12 +37,19 @@ func runDueDateReminder() {
 	now := time.Now()
 	thresholds := []int{1, 3}
 
+	archived, err := database.GetCollection("projects").Distinct(ctx, "_id", bson.M{"is_archived": true})
+	if err != nil {
+		return
+	}
+
 	for _, days := range thresholds {
 		windowStart := now.Add(time.Duration(days)*24*time.Hour - 30*time.Minute)
 		windowEnd := now.Add(time.Duration(days)*24*time.Hour + 30*time.Minute)
 
 		cursor, err := database.GetCollection("cards").Find(ctx, bson.M{
-			"due_date": bson.M{"$gte": windowStart, "$lte": windowEnd},
+			"due_date":    bson.M{"$gte": windowStart, "$lte": windowEnd},
+			"project_id":  bson.M{"$nin": archived},
+			"archived_at": nil,
 		})
 		if err != nil {
 			continue
@@ -53,40 +60,166 @@ func runDueDateReminder() {
 		cursor.Close(ctx)
 
 		for _, card := range cards {
+			msg := fmt.Sprintf("Task \"%s\" is due in %d day(s)", card.Title, days)
 			for _, email := range card.Assignees {
-				var user models.User
-				if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
-					continue
-				}
+				remindDue(ctx, email, "due_soon", msg, card, now)
+			}
+		}
 
-				cutoff := now.Add(-24 * time.Hour)
-				count, _ := database.GetCollection("notifications").CountDocuments(ctx, bson.M{
-					"user_id":    user.ID,
-					"type":       "due_soon",
-					"card_id":    card.ID,
-					"created_at": bson.M{"$gte": cutoff},
-				})
-				if count > 0 {
-					continue
-				}
+		// Subtasks with their own due date remind their assignee.
+		cursor, err = database.GetCollection("cards").Find(ctx, bson.M{
+			"subtasks":    bson.M{"$elemMatch": bson.M{"due_date": bson.M{"$gte": windowStart, "$lte": windowEnd}, "done": false}},
+			"project_id":  bson.M{"$nin": archived},
+			"archived_at": nil,
+		})
+		if err != nil {
+			continue
+		}
+
+		cards = nil
+		cursor.All(ctx, &cards)
+		cursor.Close(ctx)
 
-				msg := fmt.Sprintf("Task \"%s\" is due in %d day(s)", card.Title, days)
-				n := &models.Notification{
-					ID:        primitive.NewObjectID(),
-					UserID:    user.ID,
-					Type:      "due_soon",
-					Message:   msg,
-					ProjectID: card.ProjectID,
-					CardID:    card.ID,
-					Read:      false,
-					CreatedAt: now,
+		for _, card := range cards {
+			for _, st := range card.Subtasks {
+				if st.Done || st.Assignee == "" || st.DueDate == nil || st.DueDate.Before(windowStart) || st.DueDate.After(windowEnd) {
+					continue
 				}
-				database.GetCollection("notifications").InsertOne(ctx, n)
+				msg := fmt.Sprintf("Subtask \"%s\" on \"%s\" is due in %d day(s)", st.Text, card.Title, days)
+				remindDue(ctx, st.Assignee, "subtask_due_soon", msg, card, now)
 			}
 		}
 	}
 }
 
+// remindDue notifies the user with email about a due card or subtask,
+// at most once a day per message, as long as they can see the project.
+func remindDue(ctx context.Context, email, notifType, msg string, card models.Card, now time.Time) {
+	var user models.User
+	if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+		return
+	}
+	if !handlers.IsProjectMember(ctx, card.ProjectID, user.ID) {
+		return
+	}
+
+	cutoff := now.Add(-24 * time.Hour)
+	count, _ := database.GetCollection("notifications").CountDocuments(ctx, bson.M{
+		"user_id":    user.ID,
+		"type":       notifType,
+		"card_id":    card.ID,
+		"message":    msg,
+		"created_at": bson.M{"$gte": cutoff},
+	})
+	if count > 0 {
+		return
+	}
+
+	n := &models.Notification{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		Type:      notifType,
+		Message:   msg,
+		ProjectID: card.ProjectID,
+		CardID:    card.ID,
+		Read:      false,
+		CreatedAt: now,
+	}
+	database.GetCollection("notifications").InsertOne(ctx, n)
+}
+
+func startRankRebalancer() {
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		runRankRebalance()
+		for range ticker.C {
+			runRankRebalance()
+		}
+	}()
+}
+
+func runRankRebalance() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	handlers.RebalanceRanks(ctx)
+}
+
+func startRecurrenceScheduler() {
+	ticker := time.NewTicker(15 * time.Minute)
+	go func() {
+		runRecurrences()
+		for range ticker.C {
+			runRecurrences()
+		}
+	}()
+}
+
+func runRecurrences() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	handlers.RunRecurrences(ctx)
+}
+
+func startAutomationScheduler() {
+	ticker := time.NewTicker(5 * time.Minute)
+	go func() {
+		runAutomations()
+		for range ticker.C {
+			runAutomations()
+		}
+	}()
+}
+
+func runAutomations() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	handlers.RunAutomations(ctx)
+}
+
+func startArchivePurge() {
+	ticker := time.NewTicker(6 * time.Hour)
+	go func() {
+		runArchivePurge()
+		for range ticker.C {
+			runArchivePurge()
+		}
+	}()
+}
+
+func runArchivePurge() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	handlers.PurgeArchived(ctx)
+}
+
+// startCardKeyBackfill numbers cards from before card keys existed in the
+// background, so a large backlog does not hold up startup.
+func startCardKeyBackfill() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+
+		handlers.BackfillCardKeys(ctx)
+	}()
+}
+
+func ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	handlers.EnsureSearchIndexes(ctx)
+	handlers.EnsureTimeEntryIndexes(ctx)
+	handlers.EnsureWatchIndexes(ctx)
+	handlers.EnsureBoardEventIndexes(ctx)
+	handlers.EnsureAutomationIndexes(ctx)
+	handlers.EnsureCardKeys(ctx)
+	handlers.EnsureSprintIndexes(ctx)
+}
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
@@ -94,7 +227,13 @@ func main() {
 	}
 
 	database.Connect()
+	ensureIndexes()
+	startCardKeyBackfill()
 	startDueDateReminder()
+	startRankRebalancer()
+	startRecurrenceScheduler()
+	startAutomationScheduler()
+	startArchivePurge()
 
 	app := fiber.New(fiber.Config{
 		AppName: "FPMB API",
@@ -108,8 +247,9 @@ func main() {
 		},
 	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, If-Match",
+		ExposeHeaders: "ETag",
 	}))
 
 	api := app.Group("/api")
@@ -124,6 +264,8 @@ func main() {
 	auth.Post("/refresh", handlers.RefreshToken)
 	auth.Post("/logout", middleware.Protected(), handlers.Logout)
 
+	api.Get("/search", middleware.Protected(), handlers.Search)
+
 	// Public avatar/media routes (no auth needed for <img> tags)
 	api.Get("/avatar/:userId", handlers.ServePublicAvatar)
 	api.Get("/team-media/:teamId/:imageType", handlers.ServePublicTeamImage)
@@ -141,6 +283,9 @@ func main() {
 	users.Get("/me/api-keys", handlers.ListAPIKeys)
 	users.Post("/me/api-keys", handlers.CreateAPIKey)
 	users.Delete("/me/api-keys/:keyId", handlers.RevokeAPIKey)
+	users.Get("/me/timer", handlers.GetTimer)
+	users.Put("/me/timer/stop", handlers.StopTimer)
+	users.Get("/me/watches", handlers.ListMyWatches)
 
 	teams := api.Group("/teams", middleware.Protected())
 	teams.Get("/", handlers.ListTeams)
@@ -173,16 +318,64 @@ func main() {
 	projects.Get("/:projectId", handlers.GetProject)
 	projects.Put("/:projectId", handlers.UpdateProject)
 	projects.Put("/:projectId/archive", handlers.ArchiveProject)
+	projects.Put("/:projectId/unarchive", handlers.UnarchiveProject)
+	projects.Put("/:projectId/watch", handlers.Watch)
+	projects.Delete("/:projectId/watch", handlers.Unwatch)
+	projects.Get("/:projectId/transfer", handlers.GetProjectTransfer)
+	projects.Post("/:projectId/transfer", handlers.TransferProject)
 	projects.Delete("/:projectId", handlers.DeleteProject)
 	projects.Get("/:projectId/members", handlers.ListProjectMembers)
 	projects.Post("/:projectId/members", handlers.AddProjectMember)
 	projects.Put("/:projectId/members/:userId", handlers.UpdateProjectMemberRole)
 	projects.Delete("/:projectId/members/:userId", handlers.RemoveProjectMember)
 	projects.Get("/:projectId/board", handlers.GetBoard)
+	projects.Get("/:projectId/analytics", handlers.GetProjectAnalytics)
+	projects.Get("/:projectId/activity", handlers.ListProjectActivity)
+	projects.Get("/:projectId/archived", handlers.ListArchived)
+	projects.Get("/:projectId/labels", handlers.ListLabels)
+	projects.Post("/:projectId/labels", handlers.CreateLabel)
+	projects.Put("/:projectId/labels/:labelId", handlers.UpdateLabel)
+	projects.Delete("/:projectId/labels/:labelId", handlers.DeleteLabel)
+	projects.Get("/:projectId/lanes", handlers.ListSwimlanes)
+	projects.Post("/:projectId/lanes", handlers.CreateSwimlane)
+	projects.Put("/:projectId/lanes/:laneId", handlers.UpdateSwimlane)
+	projects.Delete("/:projectId/lanes/:laneId", handlers.DeleteSwimlane)
+	projects.Get("/:projectId/fields", handlers.ListCustomFields)
+	projects.Post("/:projectId/fields", handlers.CreateCustomField)
+	projects.Put("/:projectId/fields/:fieldId", handlers.UpdateCustomField)
+	projects.Delete("/:projectId/fields/:fieldId", handlers.DeleteCustomField)
+	projects.Get("/:projectId/sprints", handlers.ListSprints)
+	projects.Post("/:projectId/sprints", handlers.CreateSprint)
+	projects.Get("/:projectId/sprints/velocity", handlers.GetSprintVelocity)
+	projects.Get("/:projectId/sprints/:sprintId", handlers.GetSprint)
+	projects.Put("/:projectId/sprints/:sprintId", handlers.UpdateSprint)
+	projects.Delete("/:projectId/sprints/:sprintId", handlers.DeleteSprint)
+	projects.Put("/:projectId/sprints/:sprintId/start", handlers.StartSprint)
+	projects.Put("/:projectId/sprints/:sprintId/complete", handlers.CompleteSprint)
+	projects.Get("/:projectId/backlog", handlers.GetBacklog)
+	projects.Get("/:projectId/views", handlers.ListProjectViews)
+	projects.Post("/:projectId/views", handlers.CreateProjectView)
+	projects.Get("/:projectId/recurrences", handlers.ListRecurrences)
+	projects.Post("/:projectId/recurrences", handlers.CreateRecurrence)
+	projects.Put("/:projectId/recurrences/:recurrenceId", handlers.UpdateRecurrence)
+	projects.Put("/:projectId/recurrences/:recurrenceId/pause", handlers.PauseRecurrence)
+	projects.Put("/:projectId/recurrences/:recurrenceId/resume", handlers.ResumeRecurrence)
+	projects.Delete("/:projectId/recurrences/:recurrenceId", handlers.DeleteRecurrence)
+	projects.Get("/:projectId/automations", handlers.ListAutomations)
+	projects.Post("/:projectId/automations", handlers.CreateAutomation)
+	projects.Get("/:projectId/automations/runs", handlers.ListAutomationRuns)
+	projects.Post("/:projectId/automations/test", handlers.TestAutomation)
+	projects.Put("/:projectId/automations/:automationId", handlers.UpdateAutomation)
+	projects.Delete("/:projectId/automations/:automationId", handlers.DeleteAutomation)
 	projects.Post("/:projectId/columns", handlers.CreateColumn)
 	projects.Put("/:projectId/columns/:columnId", handlers.UpdateColumn)
 	projects.Put("/:projectId/columns/:columnId/position", handlers.ReorderColumn)
 	projects.Delete("/:projectId/columns/:columnId", handlers.DeleteColumn)
+	projects.Put("/:projectId/columns/:columnId/archive", handlers.ArchiveColumn)
+	projects.Put("/:projectId/columns/:columnId/restore", handlers.RestoreColumn)
+	projects.Post("/:projectId/cards/bulk", handlers.BulkCards)
+	projects.Put("/:projectId/columns/:columnId/watch", handlers.Watch)
+	projects.Delete("/:projectId/columns/:columnId/watch", handlers.Unwatch)
 	projects.Post("/:projectId/columns/:columnId/cards", handlers.CreateCard)
 	projects.Get("/:projectId/events", handlers.ListProjectEvents)
 	projects.Post("/:projectId/events", handlers.CreateProjectEvent)
@@ -195,9 +388,50 @@ func main() {
 	projects.Put("/:projectId/whiteboard", handlers.SaveWhiteboard)
 
 	cards := api.Group("/cards", middleware.Protected())
+	cards.Get("/search", handlers.SearchCards)
+	cards.Get("/by-key/:key", handlers.GetCardByKey)
 	cards.Put("/:cardId", handlers.UpdateCard)
 	cards.Put("/:cardId/move", handlers.MoveCard)
 	cards.Delete("/:cardId", handlers.DeleteCard)
+	cards.Put("/:cardId/archive", handlers.ArchiveCard)
+	cards.Put("/:cardId/restore", handlers.RestoreCard)
+	cards.Get("/:cardId/activity", handlers.ListCardActivity)
+	cards.Get("/:cardId/comments", handlers.ListCardComments)
+	cards.Post("/:cardId/comments", handlers.CreateCardComment)
+	cards.Put("/:cardId/comments/:commentId", handlers.UpdateCardComment)
+	cards.Delete("/:cardId/comments/:commentId", handlers.DeleteCardComment)
+	cards.Get("/:cardId/links", handlers.ListCardLinks)
+	cards.Post("/:cardId/links", handlers.CreateCardLink)
+	cards.Delete("/:cardId/links/:linkId", handlers.DeleteCardLink)
+	cards.Get("/:cardId/attachments", handlers.ListCardAttachments)
+	cards.Post("/:cardId/attachments", handlers.UploadCardAttachment)
+	cards.Post("/:cardId/attachments/link", handlers.LinkCardAttachment)
+	cards.Delete("/:cardId/attachments/:fileId", handlers.UnlinkCardAttachment)
+	cards.Put("/:cardId/cover", handlers.SetCardCover)
+	cards.Get("/:cardId/time-entries", handlers.ListCardTimeEntries)
+	cards.Post("/:cardId/time-entries", handlers.CreateTimeEntry)
+	cards.Put("/:cardId/time-entries/:entryId", handlers.UpdateTimeEntry)
+	cards.Delete("/:cardId/time-entries/:entryId", handlers.DeleteTimeEntry)
+	cards.Post("/:cardId/timer", handlers.StartTimer)
+	cards.Get("/:cardId/watchers", handlers.ListCardWatchers)
+	cards.Put("/:cardId/watch", handlers.Watch)
+	cards.Delete("/:cardId/watch", handlers.Unwatch)
+	cards.Post("/:cardId/subtasks", handlers.CreateSubtask)
+	cards.Put("/:cardId/subtasks/:subtaskId", handlers.UpdateSubtask)
+	cards.Put("/:cardId/subtasks/:subtaskId/position", handlers.MoveSubtask)
+	cards.Delete("/:cardId/subtasks/:subtaskId", handlers.DeleteSubtask)
+	cards.Post("/:cardId/subtasks/:subtaskId/convert", handlers.ConvertSubtask)
+	cards.Post("/:cardId/checklists", handlers.CreateChecklist)
+	cards.Put("/:cardId/checklists/:checklistId", handlers.UpdateChecklist)
+	cards.Delete("/:cardId/checklists/:checklistId", handlers.DeleteChecklist)
+
+	api.Get("/timesheets", middleware.Protected(), handlers.GetTimesheet)
+
+	views := api.Group("/views", middleware.Protected())
+	views.Get("/", handlers.ListViews)
+	views.Post("/", handlers.CreateView)
+	views.Put("/:viewId", handlers.UpdateView)
+	views.Delete("/:viewId", handlers.DeleteView)
 
 	events := api.Group("/events", middleware.Protected())
 	events.Put("/:eventId", handlers.UpdateEvent)
@@ -231,6 +465,7 @@ func main() {
 	})
 	app.Get("/ws/whiteboard/:id", websocket.New(handlers.WhiteboardWS))
 	app.Get("/ws/team/:id/chat", websocket.New(handlers.TeamChatWS))
+	app.Get("/ws/project/:id/board", websocket.New(handlers.BoardWS))
 
 	app.Static("/", "../build")
 	app.Get("/*", func(c *fiber.Ctx) error {
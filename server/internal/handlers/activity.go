@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func recordActivity(ctx context.Context, projectID, actorID primitive.ObjectID, verb, objectType string, objectID primitive.ObjectID, objectName string, changes []models.ActivityChange) {
+	a := &models.Activity{
+		ID:         primitive.NewObjectID(),
+		ProjectID:  projectID,
+		ActorID:    actorID,
+		Verb:       verb,
+		ObjectType: objectType,
+		ObjectID:   objectID,
+		ObjectName: objectName,
+		Changes:    changes,
+		CreatedAt:  time.Now(),
+	}
+	database.GetCollection("activities").InsertOne(ctx, a)
+}
+
+// recordCoalescedActivity behaves like recordActivity but replaces the
+// actor's previous entry for the same object when it is recent, so
+// autosaving editors such as the whiteboard produce one entry per session.
+// The old entry is deleted rather than refreshed because the feed is paged
+// by _id, which would leave a refreshed entry out of order.
+func recordCoalescedActivity(ctx context.Context, projectID, actorID primitive.ObjectID, verb, objectType string, objectID primitive.ObjectID, objectName string, window time.Duration) {
+	database.GetCollection("activities").DeleteMany(ctx, bson.M{
+		"project_id":  projectID,
+		"actor_id":    actorID,
+		"verb":        verb,
+		"object_type": objectType,
+		"object_id":   objectID,
+		"created_at":  bson.M{"$gte": time.Now().Add(-window)},
+	})
+	recordActivity(ctx, projectID, actorID, verb, objectType, objectID, objectName, nil)
+}
+
+func appendChange(changes []models.ActivityChange, field string, from, to interface{}) []models.ActivityChange {
+	if reflect.DeepEqual(from, to) {
+		return changes
+	}
+	return append(changes, models.ActivityChange{Field: field, From: from, To: to})
+}
+
+func formatDueDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatMinutes(m *int) interface{} {
+	if m == nil {
+		return nil
+	}
+	return *m
+}
+
+func subtaskProgress(subtasks []models.Subtask) string {
+	done := 0
+	for _, s := range subtasks {
+		if s.Done {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d", done, len(subtasks))
+}
+
+// cardChanges lists the user-visible fields that differ between two versions
+// of a card. Descriptions are only flagged as edited, not copied.
+func cardChanges(before, after models.Card) []models.ActivityChange {
+	changes := []models.ActivityChange{}
+	changes = appendChange(changes, "title", before.Title, after.Title)
+	if before.Description != after.Description {
+		changes = append(changes, models.ActivityChange{Field: "description"})
+	}
+	changes = appendChange(changes, "priority", before.Priority, after.Priority)
+	changes = appendChange(changes, "color", before.Color, after.Color)
+	changes = appendChange(changes, "due_date", formatDueDate(before.DueDate), formatDueDate(after.DueDate))
+	if !reflect.DeepEqual(before.Assignees, after.Assignees) && (len(before.Assignees) > 0 || len(after.Assignees) > 0) {
+		changes = append(changes, models.ActivityChange{Field: "assignees", From: before.Assignees, To: after.Assignees})
+	}
+	changes = appendChange(changes, "subtasks", subtaskProgress(before.Subtasks), subtaskProgress(after.Subtasks))
+	changes = appendChange(changes, "estimated_minutes", formatMinutes(before.EstimatedMinutes), formatMinutes(after.EstimatedMinutes))
+	changes = appendChange(changes, "actual_minutes", formatMinutes(before.ActualMinutes), formatMinutes(after.ActualMinutes))
+	return changes
+}
+
+func userName(ctx context.Context, userID primitive.ObjectID) string {
+	var user models.User
+	if err := database.GetCollection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return ""
+	}
+	return user.Name
+}
+
+func columnTitle(ctx context.Context, columnID primitive.ObjectID) string {
+	var col models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID}).Decode(&col); err != nil {
+		return ""
+	}
+	return col.Title
+}
+
+func listActivities(c *fiber.Ctx, ctx context.Context, filter bson.M) error {
+	limit := int64(50)
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.ParseInt(l, 10, 64); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+	if before := c.Query("before"); before != "" {
+		if beforeID, err := primitive.ObjectIDFromHex(before); err == nil {
+			filter["_id"] = bson.M{"$lt": beforeID}
+		}
+	}
+
+	cursor, err := database.GetCollection("activities").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit+1))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch activity"})
+	}
+	defer cursor.Close(ctx)
+
+	var activities []models.Activity
+	cursor.All(ctx, &activities)
+
+	hasMore := int64(len(activities)) > limit
+	if hasMore {
+		activities = activities[:limit]
+	}
+
+	type ActivityResponse struct {
+		models.Activity
+		ActorName string `json:"actor_name"`
+	}
+
+	names := map[primitive.ObjectID]string{}
+	result := []ActivityResponse{}
+	for _, a := range activities {
+		name, ok := names[a.ActorID]
+		if !ok {
+			var user models.User
+			if err := database.GetCollection("users").FindOne(ctx, bson.M{"_id": a.ActorID}).Decode(&user); err == nil {
+				name = user.Name
+			}
+			names[a.ActorID] = name
+		}
+		result = append(result, ActivityResponse{Activity: a, ActorName: name})
+	}
+
+	var nextBefore interface{}
+	if hasMore && len(activities) > 0 {
+		nextBefore = activities[len(activities)-1].ID
+	}
+
+	return c.JSON(fiber.Map{"activities": result, "has_more": hasMore, "next_before": nextBefore})
+}
+
+func ListProjectActivity(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	filter := bson.M{"project_id": projectID}
+	if objectType := c.Query("type"); objectType != "" {
+		filter["object_type"] = objectType
+	}
+
+	return listActivities(c, ctx, filter)
+}
+
+func ListCardActivity(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	if _, err := getProjectRole(ctx, card.ProjectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	return listActivities(c, ctx, bson.M{"project_id": card.ProjectID, "object_type": "card", "object_id": cardID})
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// isDoneColumn reports whether cards in the column count as completed. Boards
+// created before the is_done flag existed fall back to a column titled "Done".
+func isDoneColumn(col models.BoardColumn) bool {
+	return col.IsDone || strings.EqualFold(strings.TrimSpace(col.Title), "done")
+}
+
+func columnIsDone(ctx context.Context, columnID primitive.ObjectID) bool {
+	var col models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID}).Decode(&col); err != nil {
+		return false
+	}
+	return isDoneColumn(col)
+}
+
+// recordCardTransition appends a column change to card_transitions. A nil from
+// marks creation and a nil to marks deletion.
+func recordCardTransition(ctx context.Context, card models.Card, from, to *primitive.ObjectID, userID primitive.ObjectID) {
+	t := &models.CardTransition{
+		ID:               primitive.NewObjectID(),
+		ProjectID:        card.ProjectID,
+		CardID:           card.ID,
+		FromColumnID:     from,
+		ToColumnID:       to,
+		EstimatedMinutes: card.EstimatedMinutes,
+		ActualMinutes:    card.ActualMinutes,
+		UserID:           userID,
+		CreatedAt:        time.Now(),
+	}
+
+	switch {
+	case from == nil:
+		t.Type = "created"
+	case to == nil:
+		t.Type = "deleted"
+	default:
+		t.Type = "moved"
+	}
+	if from != nil {
+		t.FromDone = columnIsDone(ctx, *from)
+	}
+	if to != nil {
+		t.ToDone = columnIsDone(ctx, *to)
+	}
+
+	database.GetCollection("card_transitions").InsertOne(ctx, t)
+}
+
+type cardState struct {
+	at       time.Time
+	columnID *primitive.ObjectID
+	done     bool
+}
+
+type cardTimeline struct {
+	states    []cardState
+	createdAt time.Time
+	estimate  *int
+	actual    *int
+}
+
+// stateBefore returns the last state the card entered strictly before t.
+func (tl *cardTimeline) stateBefore(t time.Time) (cardState, bool) {
+	var found cardState
+	ok := false
+	for _, s := range tl.states {
+		if !s.at.Before(t) {
+			break
+		}
+		found = s
+		ok = true
+	}
+	return found, ok
+}
+
+// buildCardTimelines replays card_transitions per card. Cards whose history
+// predates transition tracking are assumed to have sat in their current (or
+// first recorded origin) column since creation.
+func buildCardTimelines(cards []models.Card, transitions []models.CardTransition, doneColumns map[primitive.ObjectID]bool) map[primitive.ObjectID]*cardTimeline {
+	timelines := map[primitive.ObjectID]*cardTimeline{}
+
+	for _, t := range transitions {
+		tl := timelines[t.CardID]
+		if tl == nil {
+			tl = &cardTimeline{}
+			if t.Type == "created" {
+				tl.createdAt = t.CreatedAt
+			} else if t.FromColumnID != nil {
+				tl.states = append(tl.states, cardState{columnID: t.FromColumnID, done: t.FromDone})
+			}
+			timelines[t.CardID] = tl
+		}
+		tl.states = append(tl.states, cardState{at: t.CreatedAt, columnID: t.ToColumnID, done: t.ToDone})
+		tl.estimate = t.EstimatedMinutes
+		tl.actual = t.ActualMinutes
+	}
+
+	for _, card := range cards {
+		tl := timelines[card.ID]
+		if tl == nil {
+			columnID := card.ColumnID
+			tl = &cardTimeline{states: []cardState{{at: card.CreatedAt, columnID: &columnID, done: doneColumns[card.ColumnID]}}}
+			timelines[card.ID] = tl
+		} else if tl.states[0].at.IsZero() {
+			tl.states[0].at = card.CreatedAt
+		}
+		tl.createdAt = card.CreatedAt
+		tl.estimate = card.EstimatedMinutes
+		tl.actual = card.ActualMinutes
+	}
+
+	return timelines
+}
+
+func weekStart(t time.Time) time.Time {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+func roundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*10) / 10
+}
+
+func GetProjectAnalytics(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -29)
+	if s := c.Query("from"); s != "" {
+		parsed, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from date, expected YYYY-MM-DD"})
+		}
+		from = parsed
+	}
+	if s := c.Query("to"); s != "" {
+		parsed, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to date, expected YYYY-MM-DD"})
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must not be after to"})
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Date range cannot exceed one year"})
+	}
+	rangeEnd := to.AddDate(0, 0, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	colCursor, err := database.GetCollection("board_columns").Find(ctx,
+		bson.M{"project_id": projectID, "archived_at": nil},
+		options.Find().SetSort(rankSort))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch columns"})
+	}
+	var columns []models.BoardColumn
+	colCursor.All(ctx, &columns)
+	colCursor.Close(ctx)
+
+	cardCursor, err := database.GetCollection("cards").Find(ctx, bson.M{"project_id": projectID, "archived_at": nil})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch cards"})
+	}
+	var cards []models.Card
+	cardCursor.All(ctx, &cards)
+	cardCursor.Close(ctx)
+
+	trCursor, err := database.GetCollection("card_transitions").Find(ctx,
+		bson.M{"project_id": projectID, "created_at": bson.M{"$lt": rangeEnd}},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch transitions"})
+	}
+	var transitions []models.CardTransition
+	trCursor.All(ctx, &transitions)
+	trCursor.Close(ctx)
+
+	type ColumnInfo struct {
+		ID     primitive.ObjectID `json:"id"`
+		Title  string             `json:"title"`
+		IsDone bool               `json:"is_done"`
+	}
+
+	doneColumns := map[primitive.ObjectID]bool{}
+	columnInfo := []ColumnInfo{}
+	known := map[primitive.ObjectID]bool{}
+	for _, col := range columns {
+		doneColumns[col.ID] = isDoneColumn(col)
+		known[col.ID] = true
+		columnInfo = append(columnInfo, ColumnInfo{ID: col.ID, Title: col.Title, IsDone: isDoneColumn(col)})
+	}
+
+	timelines := buildCardTimelines(cards, transitions, doneColumns)
+
+	type FlowPoint struct {
+		Date   string         `json:"date"`
+		Counts map[string]int `json:"counts"`
+	}
+	type BurndownPoint struct {
+		Date             string `json:"date"`
+		RemainingCards   int    `json:"remaining_cards"`
+		RemainingMinutes int    `json:"remaining_minutes"`
+	}
+
+	flow := []FlowPoint{}
+	burndown := []BurndownPoint{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		counts := map[string]int{}
+		for _, col := range columnInfo {
+			counts[col.ID.Hex()] = 0
+		}
+		point := BurndownPoint{Date: day.Format("2006-01-02")}
+		for _, tl := range timelines {
+			s, ok := tl.stateBefore(end)
+			if !ok || s.columnID == nil {
+				continue
+			}
+			if !known[*s.columnID] {
+				known[*s.columnID] = true
+				columnInfo = append(columnInfo, ColumnInfo{ID: *s.columnID, Title: "Deleted column", IsDone: s.done})
+			}
+			counts[s.columnID.Hex()]++
+			if !s.done {
+				point.RemainingCards++
+				if tl.estimate != nil {
+					point.RemainingMinutes += *tl.estimate
+				}
+			}
+		}
+		flow = append(flow, FlowPoint{Date: point.Date, Counts: counts})
+		burndown = append(burndown, point)
+	}
+
+	weekly := map[time.Time]int{}
+	var cycleTotal, leadTotal time.Duration
+	var completed, cycleCount, leadCount int
+	var estimatedTotal, actualTotal, accuracyCards int
+	var errorTotal float64
+
+	for _, tl := range timelines {
+		prevDone := false
+		var startedAt time.Time
+		for i, s := range tl.states {
+			if s.columnID == nil {
+				continue
+			}
+			if i > 0 && startedAt.IsZero() {
+				startedAt = s.at
+			}
+			if s.done && !prevDone && !s.at.IsZero() && !s.at.Before(from) && s.at.Before(rangeEnd) {
+				completed++
+				weekly[weekStart(s.at)]++
+				if !startedAt.IsZero() {
+					cycleTotal += s.at.Sub(startedAt)
+					cycleCount++
+				}
+				if !tl.createdAt.IsZero() {
+					leadTotal += s.at.Sub(tl.createdAt)
+					leadCount++
+				}
+				if tl.estimate != nil && *tl.estimate > 0 && tl.actual != nil {
+					accuracyCards++
+					estimatedTotal += *tl.estimate
+					actualTotal += *tl.actual
+					errorTotal += math.Abs(float64(*tl.actual-*tl.estimate)) / float64(*tl.estimate)
+				}
+			}
+			prevDone = s.done
+		}
+	}
+
+	type ThroughputPoint struct {
+		WeekStart string `json:"week_start"`
+		Completed int    `json:"completed"`
+	}
+	throughput := []ThroughputPoint{}
+	for w := weekStart(from); !w.After(to); w = w.AddDate(0, 0, 7) {
+		throughput = append(throughput, ThroughputPoint{WeekStart: w.Format("2006-01-02"), Completed: weekly[w]})
+	}
+
+	var avgCycle, avgLead float64
+	if cycleCount > 0 {
+		avgCycle = roundHours(cycleTotal / time.Duration(cycleCount))
+	}
+	if leadCount > 0 {
+		avgLead = roundHours(leadTotal / time.Duration(leadCount))
+	}
+
+	accuracy := fiber.Map{
+		"cards":             accuracyCards,
+		"estimated_minutes": estimatedTotal,
+		"actual_minutes":    actualTotal,
+		"ratio":             nil,
+		"mean_abs_error":    nil,
+	}
+	if accuracyCards > 0 && estimatedTotal > 0 {
+		accuracy["ratio"] = math.Round(float64(actualTotal)/float64(estimatedTotal)*100) / 100
+		accuracy["mean_abs_error"] = math.Round(errorTotal/float64(accuracyCards)*100) / 100
+	}
+
+	return c.JSON(fiber.Map{
+		"project_id":        projectID,
+		"from":              from.Format("2006-01-02"),
+		"to":                to.Format("2006-01-02"),
+		"columns":           columnInfo,
+		"cumulative_flow":   flow,
+		"burndown":          burndown,
+		"completed_cards":   completed,
+		"avg_cycle_hours":   avgCycle,
+		"avg_lead_hours":    avgLead,
+		"throughput":        throughput,
+		"estimate_accuracy": accuracy,
+	})
+}
@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultArchiveRetentionDays = 30
+
+// ArchiveRetention is how long archived cards and columns are kept before
+// PurgeArchived deletes them, from ARCHIVE_RETENTION_DAYS (default 30). Zero
+// keeps them forever.
+func ArchiveRetention() time.Duration {
+	days := defaultArchiveRetentionDays
+	if raw := os.Getenv("ARCHIVE_RETENTION_DAYS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func purgeAt(archivedAt *time.Time) *time.Time {
+	retention := ArchiveRetention()
+	if archivedAt == nil || retention == 0 {
+		return nil
+	}
+	t := archivedAt.Add(retention)
+	return &t
+}
+
+// archiveCard takes a card off the board. Analytics see it leave its
+// column, as if it were deleted.
+func archiveCard(ctx context.Context, card models.Card, userID primitive.ObjectID) error {
+	now := time.Now()
+	res, err := database.GetCollection("cards").UpdateOne(ctx,
+		bson.M{"_id": card.ID, "archived_at": nil},
+		bson.M{"$set": bson.M{"archived_at": now, "updated_at": now}, "$inc": bumpVersion})
+	if err != nil || res.ModifiedCount == 0 {
+		return err
+	}
+	recordCardTransition(ctx, card, &card.ColumnID, nil, userID)
+	recordActivity(ctx, card.ProjectID, userID, "archived", "card", card.ID, card.Title, nil)
+	return nil
+}
+
+// restoreCard puts an archived card back at the bottom of column.
+func restoreCard(ctx context.Context, card models.Card, column models.BoardColumn, userID primitive.ObjectID) (models.Card, error) {
+	col := database.GetCollection("cards")
+	_, err := col.UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{
+		"$set": bson.M{
+			"column_id":  column.ID,
+			"rank":       rankAt(ctx, "cards", bson.M{"column_id": column.ID}, card.ID, -1),
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"archived_at": "", "column_archived": ""},
+		"$inc":   bumpVersion,
+	})
+	if err != nil {
+		return card, err
+	}
+
+	var restored models.Card
+	col.FindOne(ctx, bson.M{"_id": card.ID}).Decode(&restored)
+	recordCardTransition(ctx, restored, nil, &column.ID, userID)
+	recordActivity(ctx, card.ProjectID, userID, "restored", "card", card.ID, card.Title,
+		[]models.ActivityChange{{Field: "column", To: column.Title}})
+	return restored, nil
+}
+
+// restoreTarget picks the column an archived card goes back to: columnID
+// when given, otherwise the card's own column if it is still on the board.
+func restoreTarget(ctx context.Context, card models.Card, columnID string) (models.BoardColumn, bool) {
+	id := card.ColumnID
+	if columnID != "" {
+		parsed, err := primitive.ObjectIDFromHex(columnID)
+		if err != nil {
+			return models.BoardColumn{}, false
+		}
+		id = parsed
+	}
+	var column models.BoardColumn
+	err := database.GetCollection("board_columns").FindOne(ctx,
+		bson.M{"_id": id, "project_id": card.ProjectID, "archived_at": nil}).Decode(&column)
+	return column, err == nil
+}
+
+func ArchiveCard(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if card.ArchivedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is already archived"})
+	}
+
+	if err := archiveCard(ctx, card, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to archive card"})
+	}
+	publishBoardEvent(ctx, card.ProjectID, userID, "card.archived", fiber.Map{"id": card.ID, "column_id": card.ColumnID})
+	return c.JSON(fiber.Map{"message": "Card archived"})
+}
+
+func RestoreCard(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		ColumnID string `json:"column_id"`
+		Force    bool   `json:"force"`
+	}
+	c.BodyParser(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if card.ArchivedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is not archived"})
+	}
+
+	column, ok := restoreTarget(ctx, card, body.ColumnID)
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The card's column is archived or gone; give column_id"})
+	}
+
+	violations := checkWIP(ctx, column, card)
+	if len(violations) > 0 && wipBlocks(column) && !body.Force {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "WIP limit reached", "violations": violations})
+	}
+
+	restored, err := restoreCard(ctx, card, column, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore card"})
+	}
+	if len(violations) > 0 {
+		notifyWIPExceeded(ctx, column, restored, userID, violations)
+	}
+	publishBoardEvent(ctx, card.ProjectID, userID, "card.restored", restored)
+
+	resp := withLinks(ctx, userID, restored)
+	resp.WIPWarnings = violations
+	setVersionETag(c, restored.Version)
+	return c.JSON(resp)
+}
+
+// setColumnArchived archives or restores a column together with the cards
+// that were on it. Cards archived on their own beforehand stay archived.
+func setColumnArchived(c *fiber.Ctx, archive bool) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	columnID, err := primitive.ObjectIDFromHex(c.Params("columnId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid column ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	columns := database.GetCollection("board_columns")
+	var column models.BoardColumn
+	if err := columns.FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID}).Decode(&column); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+	if (column.ArchivedAt != nil) == archive {
+		if archive {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Column is already archived"})
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Column is not archived"})
+	}
+
+	now := time.Now()
+	cards := database.GetCollection("cards")
+
+	if archive {
+		moved := findCards(ctx, bson.M{"column_id": columnID, "archived_at": nil})
+		columns.UpdateOne(ctx, bson.M{"_id": columnID}, bson.M{"$set": bson.M{"archived_at": now, "updated_at": now}, "$inc": bumpVersion})
+		cards.UpdateMany(ctx, bson.M{"column_id": columnID, "archived_at": nil},
+			bson.M{"$set": bson.M{"archived_at": now, "column_archived": true, "updated_at": now}, "$inc": bumpVersion})
+		for _, card := range moved {
+			recordCardTransition(ctx, card, &columnID, nil, userID)
+		}
+		// Recurrences feeding this column pause until it is restored or retargeted.
+		database.GetCollection("recurrences").UpdateMany(ctx,
+			bson.M{"project_id": projectID, "column_id": columnID},
+			bson.M{"$set": bson.M{"paused": true, "updated_at": now}},
+		)
+		recordActivity(ctx, projectID, userID, "archived", "column", columnID, column.Title,
+			[]models.ActivityChange{{Field: "cards", To: len(moved)}})
+		publishBoardEvent(ctx, projectID, userID, "column.archived", fiber.Map{"id": columnID})
+	} else {
+		returning := findCards(ctx, bson.M{"column_id": columnID, "column_archived": true})
+		columns.UpdateOne(ctx, bson.M{"_id": columnID}, bson.M{
+			"$set":   bson.M{"updated_at": now},
+			"$unset": bson.M{"archived_at": ""},
+			"$inc":   bumpVersion,
+		})
+		cards.UpdateMany(ctx, bson.M{"column_id": columnID, "column_archived": true}, bson.M{
+			"$set":   bson.M{"updated_at": now},
+			"$unset": bson.M{"archived_at": "", "column_archived": ""},
+			"$inc":   bumpVersion,
+		})
+		for _, card := range returning {
+			recordCardTransition(ctx, card, nil, &columnID, userID)
+		}
+		recordActivity(ctx, projectID, userID, "restored", "column", columnID, column.Title,
+			[]models.ActivityChange{{Field: "cards", To: len(returning)}})
+	}
+
+	columns.FindOne(ctx, bson.M{"_id": columnID}).Decode(&column)
+	if !archive {
+		publishBoardEvent(ctx, projectID, userID, "column.restored", fiber.Map{
+			"column": column,
+			"cards":  findCards(ctx, bson.M{"column_id": columnID, "archived_at": nil}, options.Find().SetSort(rankSort)),
+		})
+	}
+	return c.JSON(column)
+}
+
+func ArchiveColumn(c *fiber.Ctx) error {
+	return setColumnArchived(c, true)
+}
+
+func RestoreColumn(c *fiber.Ctx) error {
+	return setColumnArchived(c, false)
+}
+
+// ListArchived returns a project's archived columns and the cards archived
+// on their own, newest first, with the time each is due to be purged.
+func ListArchived(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	newestFirst := bson.D{{Key: "archived_at", Value: -1}}
+
+	type ArchivedColumn struct {
+		models.BoardColumn
+		CardCount int        `json:"card_count"`
+		PurgeAt   *time.Time `json:"purge_at"`
+	}
+
+	columns := []ArchivedColumn{}
+	cursor, err := database.GetCollection("board_columns").Find(ctx,
+		bson.M{"project_id": projectID, "archived_at": bson.M{"$ne": nil}},
+		options.Find().SetSort(newestFirst))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch archived columns"})
+	}
+	var archivedColumns []models.BoardColumn
+	cursor.All(ctx, &archivedColumns)
+	cursor.Close(ctx)
+	for _, col := range archivedColumns {
+		n, _ := database.GetCollection("cards").CountDocuments(ctx, bson.M{"column_id": col.ID, "column_archived": true})
+		columns = append(columns, ArchivedColumn{BoardColumn: col, CardCount: int(n), PurgeAt: purgeAt(col.ArchivedAt)})
+	}
+
+	type ArchivedCard struct {
+		models.Card
+		ColumnTitle string     `json:"column_title"`
+		PurgeAt     *time.Time `json:"purge_at"`
+	}
+
+	cards := []ArchivedCard{}
+	titles := map[primitive.ObjectID]string{}
+	for _, card := range findCards(ctx,
+		bson.M{"project_id": projectID, "archived_at": bson.M{"$ne": nil}, "column_archived": bson.M{"$ne": true}},
+		options.Find().SetSort(newestFirst)) {
+		if _, ok := titles[card.ColumnID]; !ok {
+			titles[card.ColumnID] = columnTitle(ctx, card.ColumnID)
+		}
+		cards = append(cards, ArchivedCard{Card: card, ColumnTitle: titles[card.ColumnID], PurgeAt: purgeAt(card.ArchivedAt)})
+	}
+
+	return c.JSON(fiber.Map{"columns": columns, "cards": cards})
+}
+
+// PurgeArchived permanently deletes cards and columns that have been
+// archived for longer than ArchiveRetention.
+func PurgeArchived(ctx context.Context) {
+	retention := ArchiveRetention()
+	if retention == 0 {
+		return
+	}
+	cutoff := time.Now().Add(-retention)
+
+	columnIDs := []primitive.ObjectID{}
+	cursor, err := database.GetCollection("board_columns").Find(ctx, bson.M{"archived_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		log.Printf("PurgeArchived: %v", err)
+		return
+	}
+	var columns []models.BoardColumn
+	cursor.All(ctx, &columns)
+	cursor.Close(ctx)
+	for _, col := range columns {
+		columnIDs = append(columnIDs, col.ID)
+	}
+
+	// Cards of a purged column go with it, whenever they were archived.
+	cardFilter := bson.M{"$or": bson.A{
+		bson.M{"archived_at": bson.M{"$lt": cutoff}},
+		bson.M{"column_id": bson.M{"$in": columnIDs}},
+	}}
+	cardIDs := []primitive.ObjectID{}
+	for _, card := range findCards(ctx, cardFilter, options.Find().SetProjection(bson.M{"_id": 1})) {
+		cardIDs = append(cardIDs, card.ID)
+	}
+
+	if len(cardIDs) > 0 {
+		database.GetCollection("cards").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": cardIDs}})
+		deleteCardData(ctx, cardIDs)
+	}
+	if len(columnIDs) > 0 {
+		database.GetCollection("board_columns").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": columnIDs}})
+		database.GetCollection("watches").DeleteMany(ctx, bson.M{"target_type": "column", "target_id": bson.M{"$in": columnIDs}})
+	}
+	if len(cardIDs) > 0 || len(columnIDs) > 0 {
+		log.Printf("PurgeArchived: removed %d cards and %d columns archived before %s",
+			len(cardIDs), len(columnIDs), cutoff.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func isImageFile(name string) bool {
+	return allowedImageExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// detachFiles removes deleted files from every card of the project that
+// references them, clearing the cover when it pointed at one of them.
+func detachFiles(ctx context.Context, projectID primitive.ObjectID, fileIDs []primitive.ObjectID) {
+	cards := database.GetCollection("cards")
+	cards.UpdateMany(ctx,
+		bson.M{"project_id": projectID, "attachment_ids": bson.M{"$in": fileIDs}},
+		bson.M{"$pull": bson.M{"attachment_ids": bson.M{"$in": fileIDs}}, "$inc": bumpVersion},
+	)
+	cards.UpdateMany(ctx,
+		bson.M{"project_id": projectID, "cover_file_id": bson.M{"$in": fileIDs}},
+		bson.M{"$unset": bson.M{"cover_file_id": ""}, "$inc": bumpVersion},
+	)
+}
+
+func attachFile(ctx context.Context, card models.Card, userID primitive.ObjectID, file models.File) {
+	database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{
+		"$addToSet": bson.M{"attachment_ids": file.ID},
+		"$set":      bson.M{"updated_at": time.Now()},
+		"$inc":      bumpVersion,
+	})
+	recordActivity(ctx, card.ProjectID, userID, "attached", "card", card.ID, card.Title,
+		[]models.ActivityChange{{Field: "attachment", To: file.Name}})
+}
+
+func ListCardAttachments(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	if _, err := getProjectRole(ctx, card.ProjectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	type Attachment struct {
+		models.File
+		IsImage bool `json:"is_image"`
+		IsCover bool `json:"is_cover"`
+	}
+
+	result := []Attachment{}
+	if len(card.AttachmentIDs) == 0 {
+		return c.JSON(result)
+	}
+
+	cursor, err := database.GetCollection("files").Find(ctx, bson.M{"_id": bson.M{"$in": card.AttachmentIDs}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch attachments"})
+	}
+	defer cursor.Close(ctx)
+
+	var files []models.File
+	cursor.All(ctx, &files)
+	byID := map[primitive.ObjectID]models.File{}
+	for _, f := range files {
+		byID[f.ID] = f
+	}
+
+	for _, id := range card.AttachmentIDs {
+		f, ok := byID[id]
+		if !ok {
+			continue
+		}
+		result = append(result, Attachment{
+			File:    f,
+			IsImage: isImageFile(f.Name),
+			IsCover: card.CoverFileID != nil && *card.CoverFileID == f.ID,
+		})
+	}
+	return c.JSON(result)
+}
+
+func UploadCardAttachment(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No file provided"})
+	}
+
+	base, err := storageBase(ctx, card.ProjectID)
+	if err != nil {
+		log.Printf("UploadCardAttachment storageBase error: %v (projectID=%s)", err, card.ProjectID.Hex())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve storage path"})
+	}
+
+	if err := os.MkdirAll(base, 0755); err != nil {
+		log.Printf("UploadCardAttachment MkdirAll error: %v (base=%s)", err, base)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create storage directory"})
+	}
+
+	destPath := uniqueFilePath(base, fh.Filename)
+	if err := c.SaveFile(fh, destPath); err != nil {
+		log.Printf("UploadCardAttachment SaveFile error: %v (destPath=%s)", err, destPath)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save file"})
+	}
+
+	now := time.Now()
+	file := models.File{
+		ID:         primitive.NewObjectID(),
+		ProjectID:  card.ProjectID,
+		Name:       fh.Filename,
+		Type:       "file",
+		SizeBytes:  fh.Size,
+		StorageURL: destPath[len("../data/"):],
+		CreatedBy:  userID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	database.GetCollection("files").InsertOne(ctx, file)
+	recordActivity(ctx, card.ProjectID, userID, "uploaded", "file", file.ID, file.Name, nil)
+	attachFile(ctx, card, userID, file)
+
+	return c.Status(fiber.StatusCreated).JSON(file)
+}
+
+func LinkCardAttachment(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		FileID string `json:"file_id"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.FileID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file_id is required"})
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(body.FileID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file_id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var file models.File
+	if err := database.GetCollection("files").FindOne(ctx, bson.M{"_id": fileID, "project_id": card.ProjectID}).Decode(&file); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "File not found in this project"})
+	}
+	if file.Type == "folder" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot attach a folder"})
+	}
+
+	attachFile(ctx, card, userID, file)
+	return c.JSON(file)
+}
+
+func UnlinkCardAttachment(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Params("fileId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	update := bson.M{
+		"$pull": bson.M{"attachment_ids": fileID},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$inc":  bumpVersion,
+	}
+	if card.CoverFileID != nil && *card.CoverFileID == fileID {
+		update["$unset"] = bson.M{"cover_file_id": ""}
+	}
+	database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": cardID}, update)
+
+	return c.JSON(fiber.Map{"message": "Attachment removed"})
+}
+
+func SetCardCover(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		FileID string `json:"file_id"`
+	}
+	c.BodyParser(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("cards")
+	if body.FileID == "" {
+		col.UpdateOne(ctx, bson.M{"_id": cardID}, bson.M{"$unset": bson.M{"cover_file_id": ""}, "$inc": bumpVersion})
+		return c.JSON(fiber.Map{"cover_file_id": nil})
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(body.FileID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file_id"})
+	}
+
+	attached := false
+	for _, id := range card.AttachmentIDs {
+		if id == fileID {
+			attached = true
+			break
+		}
+	}
+	if !attached {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cover must be one of the card's attachments"})
+	}
+
+	var file models.File
+	if err := database.GetCollection("files").FindOne(ctx, bson.M{"_id": fileID}).Decode(&file); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	if !isImageFile(file.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cover must be an image"})
+	}
+
+	col.UpdateOne(ctx, bson.M{"_id": cardID}, bson.M{"$set": bson.M{"cover_file_id": fileID}, "$inc": bumpVersion})
+	return c.JSON(fiber.Map{"cover_file_id": fileID})
+}
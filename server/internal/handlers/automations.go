@@ -0,0 +1,1033 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Automations are per-project rules: when the trigger fires for a card that
+// matches the conditions (a board query), the actions run in order on behalf
+// of the rule's author. Card events from the board fire rules in the
+// background; overdue and scheduled rules are run by RunAutomations.
+
+const (
+	// maxAutomationDepth bounds how far rules may set off other rules.
+	maxAutomationDepth     = 3
+	maxAutomationActions   = 10
+	automationCardPage     = 200
+	automationRunRetention = 30 * 24 * time.Hour
+)
+
+var automationTriggers = map[string]bool{
+	"card.created": true,
+	"card.updated": true,
+	"card.moved":   true,
+	"card.overdue": true,
+	"schedule":     true,
+}
+
+// automationFields are the fields a card.updated trigger can watch.
+var automationFields = map[string]bool{
+	"title":             true,
+	"description":       true,
+	"priority":          true,
+	"color":             true,
+	"due_date":          true,
+	"assignees":         true,
+	"labels":            true,
+	"subtasks":          true,
+	"estimated_minutes": true,
+	"actual_minutes":    true,
+}
+
+type automationEvent struct {
+	Type   string
+	Card   models.Card
+	Before *models.Card
+}
+
+// automationChain follows the rules set off by one change. Actions can fire
+// further triggers, so a chain stops at maxAutomationDepth and runs each
+// rule at most once.
+type automationChain struct {
+	depth int
+	fired map[primitive.ObjectID]bool
+}
+
+func newAutomationChain() *automationChain {
+	return &automationChain{fired: map[primitive.ObjectID]bool{}}
+}
+
+func EnsureAutomationIndexes(ctx context.Context) {
+	_, err := database.GetCollection("automation_runs").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "automation_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(automationRunRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		log.Printf("EnsureAutomationIndexes: %v", err)
+	}
+}
+
+// changedCardFields names the automationFields that differ between two
+// versions of a card.
+func changedCardFields(before, after models.Card) []string {
+	fields := []string{}
+	for _, ch := range cardChanges(before, after) {
+		fields = append(fields, ch.Field)
+	}
+	if (len(before.LabelIDs) > 0 || len(after.LabelIDs) > 0) && !reflect.DeepEqual(before.LabelIDs, after.LabelIDs) {
+		fields = append(fields, "labels")
+	}
+	return fields
+}
+
+// fireAutomations runs the project's rules for ev in the background, so the
+// request that caused it does not wait for them.
+func fireAutomations(ev automationEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		dispatchAutomations(ctx, ev, newAutomationChain())
+	}()
+}
+
+func dispatchAutomations(ctx context.Context, ev automationEvent, chain *automationChain) {
+	cursor, err := database.GetCollection("automations").Find(ctx,
+		bson.M{"project_id": ev.Card.ProjectID, "enabled": true, "trigger.type": ev.Type},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return
+	}
+	var rules []models.Automation
+	cursor.All(ctx, &rules)
+	cursor.Close(ctx)
+
+	for _, a := range rules {
+		runAutomation(ctx, a, ev, chain)
+	}
+}
+
+func automationTriggerMatches(t models.AutomationTrigger, ev automationEvent) bool {
+	if t.Type != ev.Type {
+		return false
+	}
+	switch t.Type {
+	case "card.moved":
+		return t.ColumnID == nil || *t.ColumnID == ev.Card.ColumnID
+	case "card.updated":
+		return t.Field == "" || ev.Before == nil || containsString(changedCardFields(*ev.Before, ev.Card), t.Field)
+	}
+	return true
+}
+
+// automationConditions compiles a rule's conditions as its author would
+// see them, so "me" in the query is the author.
+func automationConditions(ctx context.Context, a models.Automation) (bson.M, error) {
+	return compileBoardQuery(ctx, a.Conditions, newQueryScope(ctx, a.CreatedBy, []primitive.ObjectID{a.ProjectID}))
+}
+
+func automationConditionsMatch(ctx context.Context, a models.Automation, cardID primitive.ObjectID) (bool, error) {
+	filter, err := automationConditions(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	n, err := database.GetCollection("cards").CountDocuments(ctx, bson.M{"$and": bson.A{bson.M{"_id": cardID}, filter}})
+	return n > 0, err
+}
+
+// runAutomation runs one rule for ev and logs the outcome. Whatever the
+// rule changes on the card is dispatched one level deeper in chain.
+func runAutomation(ctx context.Context, a models.Automation, ev automationEvent, chain *automationChain) {
+	if !automationTriggerMatches(a.Trigger, ev) {
+		return
+	}
+
+	cardID := ev.Card.ID
+	run := models.AutomationRun{
+		ID:           primitive.NewObjectID(),
+		AutomationID: a.ID,
+		ProjectID:    a.ProjectID,
+		CardID:       &cardID,
+		Trigger:      ev.Type,
+		Status:       "ok",
+		Actions:      []string{},
+		Depth:        chain.depth,
+		CreatedAt:    time.Now(),
+	}
+	runs := database.GetCollection("automation_runs")
+
+	ok, err := automationConditionsMatch(ctx, a, cardID)
+	if err != nil {
+		run.Status, run.Error = "failed", "Invalid conditions: "+err.Error()
+		runs.InsertOne(ctx, run)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	// A rule acts as its author, so it stops once they are no longer a
+	// project admin.
+	if role, err := getProjectRole(ctx, a.ProjectID, a.CreatedBy); err != nil || !hasPermission(role, RoleAdmin) {
+		database.GetCollection("automations").UpdateOne(ctx, bson.M{"_id": a.ID},
+			bson.M{"$set": bson.M{"enabled": false, "updated_at": time.Now()}})
+		run.Status, run.Error = "skipped", "The rule's author is no longer a project admin; the rule was disabled"
+		runs.InsertOne(ctx, run)
+		return
+	}
+
+	switch {
+	case chain.fired[a.ID]:
+		run.Status, run.Error = "skipped", "Already ran for this change"
+	case chain.depth >= maxAutomationDepth:
+		run.Status, run.Error = "skipped", "Too many automations triggered in a row"
+	case ensureProjectWritable(ctx, a.ProjectID) != nil:
+		run.Status, run.Error = "skipped", "Project is read-only"
+	}
+	if run.Status != "ok" {
+		runs.InsertOne(ctx, run)
+		return
+	}
+	chain.fired[a.ID] = true
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID, "archived_at": nil}).Decode(&card); err != nil {
+		run.Status, run.Error = "skipped", "Card is archived or gone"
+		runs.InsertOne(ctx, run)
+		return
+	}
+	before := card
+
+	for _, act := range a.Actions {
+		desc, err := applyAutomationAction(ctx, a, act, &card, false)
+		if err != nil {
+			run.Status, run.Error = "failed", desc+": "+err.Error()
+			break
+		}
+		run.Actions = append(run.Actions, desc)
+	}
+	runs.InsertOne(ctx, run)
+
+	if card.ArchivedAt != nil {
+		return
+	}
+	next := &automationChain{depth: chain.depth + 1, fired: chain.fired}
+	if card.ColumnID != before.ColumnID {
+		dispatchAutomations(ctx, automationEvent{Type: "card.moved", Card: card, Before: &before}, next)
+	}
+	if len(changedCardFields(before, card)) > 0 {
+		dispatchAutomations(ctx, automationEvent{Type: "card.updated", Card: card, Before: &before}, next)
+	}
+}
+
+// saveAutomationCard writes set to the card and returns the new version.
+func saveAutomationCard(ctx context.Context, card models.Card, set bson.M) (models.Card, error) {
+	col := database.GetCollection("cards")
+	set["updated_at"] = time.Now()
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{"$set": set, "$inc": bumpVersion}); err != nil {
+		return card, err
+	}
+	var updated models.Card
+	err := col.FindOne(ctx, bson.M{"_id": card.ID}).Decode(&updated)
+	return updated, err
+}
+
+// updateAutomationCard applies a field change made by rule a, recording it
+// the way a user's edit would be.
+func updateAutomationCard(ctx context.Context, a models.Automation, card *models.Card, set bson.M) error {
+	updated, err := saveAutomationCard(ctx, *card, set)
+	if err != nil {
+		return err
+	}
+	changes := cardChanges(*card, updated)
+	changes = appendChange(changes, "labels", labelNames(ctx, card.LabelIDs), labelNames(ctx, updated.LabelIDs))
+	recordActivity(ctx, card.ProjectID, a.CreatedBy, "updated", "card", card.ID, card.Title,
+		append(changes, models.ActivityChange{Field: "automation", To: a.Name}))
+	notifyWatchers(ctx, updated, a.CreatedBy, watchEvents(ctx, *card, updated))
+	publishBoardEvent(ctx, card.ProjectID, a.CreatedBy, "card.updated", updated)
+	*card = updated
+	return nil
+}
+
+// applyAutomationAction performs one action on card, or with dryRun only
+// checks that it could. It returns a description of the action for the
+// run log and dry runs.
+func applyAutomationAction(ctx context.Context, a models.Automation, act models.AutomationAction, card *models.Card, dryRun bool) (string, error) {
+	switch act.Type {
+	case "move":
+		var target models.BoardColumn
+		if act.ColumnID == nil || database.GetCollection("board_columns").FindOne(ctx,
+			bson.M{"_id": act.ColumnID, "project_id": card.ProjectID, "archived_at": nil}).Decode(&target) != nil {
+			return "move", errors.New("target column not found")
+		}
+		desc := "move to \"" + target.Title + "\""
+		if card.ColumnID == target.ID {
+			return desc + " (already there)", nil
+		}
+		if isDoneColumn(target) && !columnIsDone(ctx, card.ColumnID) {
+			if blockers := unresolvedBlockers(ctx, a.CreatedBy, card.ID); len(blockers) > 0 {
+				return desc, errors.New("card is blocked by unfinished cards")
+			}
+		}
+		violations := checkWIP(ctx, target, *card)
+		if len(violations) > 0 && wipBlocks(target) {
+			return desc, errors.New("WIP limit reached")
+		}
+		if dryRun {
+			return desc, nil
+		}
+
+		updated, err := saveAutomationCard(ctx, *card, bson.M{
+			"column_id": target.ID,
+			"rank":      rankAt(ctx, "cards", bson.M{"column_id": target.ID}, card.ID, -1),
+		})
+		if err != nil {
+			return desc, err
+		}
+		recordCardTransition(ctx, updated, &card.ColumnID, &target.ID, a.CreatedBy)
+		recordActivity(ctx, card.ProjectID, a.CreatedBy, "moved", "card", card.ID, card.Title,
+			append(appendChange(nil, "column", columnTitle(ctx, card.ColumnID), target.Title),
+				models.ActivityChange{Field: "automation", To: a.Name}))
+		notifyWatchers(ctx, updated, a.CreatedBy, watchEvents(ctx, *card, updated), card.ColumnID)
+		if len(violations) > 0 {
+			notifyWIPExceeded(ctx, target, updated, a.CreatedBy, violations)
+		}
+		publishBoardEvent(ctx, card.ProjectID, a.CreatedBy, "card.moved", updated)
+		*card = updated
+		return desc, nil
+
+	case "set_priority":
+		desc := "set priority to " + act.Value
+		if card.Priority == act.Value {
+			return desc + " (already set)", nil
+		}
+		if dryRun {
+			return desc, nil
+		}
+		return desc, updateAutomationCard(ctx, a, card, bson.M{"priority": act.Value})
+
+	case "assign":
+		desc := "assign " + act.Value
+		if containsString(card.Assignees, act.Value) {
+			return desc + " (already assigned)", nil
+		}
+		if dryRun {
+			return desc, nil
+		}
+		if err := updateAutomationCard(ctx, a, card, bson.M{"assignees": mergeStrings(card.Assignees, []string{act.Value}, "add")}); err != nil {
+			return desc, err
+		}
+		autoWatchCard(ctx, *card)
+		var assignee models.User
+		if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": act.Value}).Decode(&assignee); err == nil {
+			createNotification(ctx, assignee.ID, "assign",
+				"You have been assigned to the task \""+card.Title+"\"",
+				card.ProjectID, card.ID)
+		}
+		return desc, nil
+
+	case "unassign":
+		desc := "unassign " + act.Value
+		if !containsString(card.Assignees, act.Value) {
+			return desc + " (not assigned)", nil
+		}
+		if dryRun {
+			return desc, nil
+		}
+		return desc, updateAutomationCard(ctx, a, card, bson.M{"assignees": mergeStrings(card.Assignees, []string{act.Value}, "remove")})
+
+	case "add_label", "remove_label":
+		names := []string{}
+		if act.LabelID != nil {
+			names = labelNames(ctx, []primitive.ObjectID{*act.LabelID})
+		}
+		if len(names) == 0 {
+			return strings.ReplaceAll(act.Type, "_", " "), errors.New("label not found")
+		}
+		mode := "add"
+		desc := "add label \"" + names[0] + "\""
+		if act.Type == "remove_label" {
+			mode = "remove"
+			desc = "remove label \"" + names[0] + "\""
+		}
+		labelIDs := mergeObjectIDs(card.LabelIDs, []primitive.ObjectID{*act.LabelID}, mode)
+		if reflect.DeepEqual(labelIDs, card.LabelIDs) {
+			return desc + " (no change)", nil
+		}
+		if dryRun {
+			return desc, nil
+		}
+		return desc, updateAutomationCard(ctx, a, card, bson.M{"label_ids": labelIDs})
+
+	case "archive":
+		if dryRun {
+			return "archive", nil
+		}
+		if err := archiveCard(ctx, *card, a.CreatedBy); err != nil {
+			return "archive", err
+		}
+		publishBoardEvent(ctx, card.ProjectID, a.CreatedBy, "card.archived", fiber.Map{"id": card.ID, "column_id": card.ColumnID})
+		now := time.Now()
+		card.ArchivedAt = &now
+		return "archive", nil
+
+	case "notify":
+		desc := "notify " + act.Target
+		if dryRun {
+			return desc, nil
+		}
+		msg := act.Message
+		if msg == "" {
+			msg = "Automation \"" + a.Name + "\" ran on \"{title}\""
+		}
+		msg = strings.ReplaceAll(msg, "{title}", card.Title)
+		for _, id := range automationRecipients(ctx, *card, act.Target) {
+			createNotification(ctx, id, "automation", msg, card.ProjectID, card.ID)
+		}
+		return desc, nil
+
+	case "prompt_time":
+		desc := "ask for the actual time spent"
+		if card.ActualMinutes != nil {
+			return desc + " (already logged)", nil
+		}
+		if dryRun {
+			return desc, nil
+		}
+		recipients := automationRecipients(ctx, *card, "assignees")
+		if len(recipients) == 0 {
+			recipients = automationRecipients(ctx, *card, "creator")
+		}
+		for _, id := range recipients {
+			createNotification(ctx, id, "time_prompt",
+				"How long did \""+card.Title+"\" take? Log the actual time on the card.",
+				card.ProjectID, card.ID)
+		}
+		return desc, nil
+	}
+	return act.Type, errors.New("unknown action")
+}
+
+// automationRecipients resolves a notify target to the project members it
+// names: "creator" (of the card), "assignees", "watchers" or an email.
+func automationRecipients(ctx context.Context, card models.Card, target string) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	switch target {
+	case "creator":
+		ids = []primitive.ObjectID{card.CreatedBy}
+	case "assignees":
+		ids = assigneeIDs(ctx, card.Assignees)
+	case "watchers":
+		ids = cardWatchers(ctx, card)
+	default:
+		var user models.User
+		if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": target}).Decode(&user); err == nil {
+			ids = []primitive.ObjectID{user.ID}
+		}
+	}
+
+	members := []primitive.ObjectID{}
+	for _, id := range ids {
+		if _, err := getProjectRole(ctx, card.ProjectID, id); err == nil {
+			members = append(members, id)
+		}
+	}
+	return members
+}
+
+// armAutomation resets the scheduling state of a rule whose trigger or
+// enabled flag changed. Overdue rules only see due dates that pass from
+// now on; scheduled rules skip occurrences missed while disabled.
+func armAutomation(a *models.Automation, triggerChanged bool, now time.Time) {
+	a.NextRunAt, a.CheckedAt = nil, nil
+	if triggerChanged || a.Trigger.Type != "schedule" {
+		a.StartAt = nil
+	}
+	if !a.Enabled {
+		return
+	}
+	switch a.Trigger.Type {
+	case "card.overdue":
+		a.CheckedAt = &now
+	case "schedule":
+		if a.StartAt == nil {
+			start := now.UTC().Truncate(24 * time.Hour)
+			a.StartAt = &start
+		}
+		rule, _ := parseRRule(a.Trigger.Rule)
+		a.NextRunAt = nextRecurrenceRun(rule, *a.StartAt, now)
+	}
+}
+
+type automationTriggerBody struct {
+	Type     string `json:"type"`
+	ColumnID string `json:"column_id"`
+	Field    string `json:"field"`
+	Rule     string `json:"rule"`
+}
+
+type automationActionBody struct {
+	Type     string `json:"type"`
+	ColumnID string `json:"column_id"`
+	LabelID  string `json:"label_id"`
+	Value    string `json:"value"`
+	Target   string `json:"target"`
+	Message  string `json:"message"`
+}
+
+type automationBody struct {
+	Name       *string                 `json:"name"`
+	Enabled    *bool                   `json:"enabled"`
+	Trigger    *automationTriggerBody  `json:"trigger"`
+	Conditions *string                 `json:"conditions"`
+	Actions    *[]automationActionBody `json:"actions"`
+}
+
+func projectColumnID(ctx context.Context, projectID primitive.ObjectID, raw string) (*primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return nil, false
+	}
+	n, _ := database.GetCollection("board_columns").CountDocuments(ctx, bson.M{"_id": id, "project_id": projectID, "archived_at": nil})
+	return &id, n > 0
+}
+
+func parseAutomationTrigger(ctx context.Context, projectID primitive.ObjectID, body automationTriggerBody) (models.AutomationTrigger, string) {
+	t := models.AutomationTrigger{Type: body.Type}
+	if !automationTriggers[t.Type] {
+		return t, "trigger.type must be card.created, card.updated, card.moved, card.overdue or schedule"
+	}
+	switch t.Type {
+	case "card.moved":
+		if body.ColumnID != "" {
+			id, ok := projectColumnID(ctx, projectID, body.ColumnID)
+			if !ok {
+				return t, "trigger.column_id must be a column of this project"
+			}
+			t.ColumnID = id
+		}
+	case "card.updated":
+		if body.Field != "" && !automationFields[body.Field] {
+			return t, "Unknown trigger.field " + strconv.Quote(body.Field)
+		}
+		t.Field = body.Field
+	case "schedule":
+		t.Rule = strings.TrimPrefix(strings.TrimSpace(body.Rule), "RRULE:")
+		if _, err := parseRRule(t.Rule); err != nil {
+			return t, "Invalid trigger.rule: " + err.Error()
+		}
+	}
+	return t, ""
+}
+
+func parseAutomationAction(ctx context.Context, projectID primitive.ObjectID, body automationActionBody) (models.AutomationAction, string) {
+	act := models.AutomationAction{Type: body.Type}
+	switch act.Type {
+	case "move":
+		id, ok := projectColumnID(ctx, projectID, body.ColumnID)
+		if !ok {
+			return act, "move needs the column_id of a column in this project"
+		}
+		act.ColumnID = id
+	case "set_priority":
+		if !containsString(priorityOrder, body.Value) {
+			return act, "set_priority needs a value of " + strings.Join(priorityOrder, ", ")
+		}
+		act.Value = body.Value
+	case "assign", "unassign":
+		email := strings.TrimSpace(body.Value)
+		if n, _ := database.GetCollection("users").CountDocuments(ctx, bson.M{"email": email}); email == "" || n == 0 {
+			return act, act.Type + " needs the email of a user"
+		}
+		act.Value = email
+	case "add_label", "remove_label":
+		ids, err := resolveLabelIDs(ctx, projectID, []string{body.LabelID})
+		if err != nil || len(ids) == 0 {
+			return act, act.Type + " needs the label_id of a label in this project"
+		}
+		act.LabelID = &ids[0]
+	case "notify":
+		act.Target = strings.TrimSpace(body.Target)
+		if act.Target == "" {
+			act.Target = "creator"
+		}
+		if act.Target != "creator" && act.Target != "assignees" && act.Target != "watchers" {
+			if n, _ := database.GetCollection("users").CountDocuments(ctx, bson.M{"email": act.Target}); n == 0 {
+				return act, "notify target must be creator, assignees, watchers or a user's email"
+			}
+		}
+		act.Message = strings.TrimSpace(body.Message)
+	case "archive", "prompt_time":
+	default:
+		return act, "Unknown action type " + strconv.Quote(body.Type)
+	}
+	return act, ""
+}
+
+// applyAutomationBody copies the provided fields of body onto a and
+// validates the result. It returns a client-facing error message.
+func applyAutomationBody(ctx context.Context, a *models.Automation, body automationBody) string {
+	if body.Name != nil {
+		a.Name = strings.TrimSpace(*body.Name)
+	}
+	if body.Enabled != nil {
+		a.Enabled = *body.Enabled
+	}
+	if body.Trigger != nil {
+		t, msg := parseAutomationTrigger(ctx, a.ProjectID, *body.Trigger)
+		if msg != "" {
+			return msg
+		}
+		a.Trigger = t
+	}
+	if body.Conditions != nil {
+		a.Conditions = strings.TrimSpace(*body.Conditions)
+	}
+	if body.Actions != nil {
+		actions := []models.AutomationAction{}
+		for _, raw := range *body.Actions {
+			act, msg := parseAutomationAction(ctx, a.ProjectID, raw)
+			if msg != "" {
+				return msg
+			}
+			actions = append(actions, act)
+		}
+		a.Actions = actions
+	}
+
+	if a.Name == "" {
+		return "Name is required"
+	}
+	if a.Trigger.Type == "" {
+		return "trigger is required"
+	}
+	if len(a.Actions) == 0 || len(a.Actions) > maxAutomationActions {
+		return fmt.Sprintf("An automation needs 1 to %d actions", maxAutomationActions)
+	}
+	if _, err := automationConditions(ctx, *a); err != nil {
+		return "Invalid conditions: " + err.Error()
+	}
+	if a.Trigger.Type == "schedule" && a.Conditions == "" {
+		return "Scheduled automations need conditions to pick their cards"
+	}
+	return ""
+}
+
+func ListAutomations(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	cursor, err := database.GetCollection("automations").Find(ctx, bson.M{"project_id": projectID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch automations"})
+	}
+	defer cursor.Close(ctx)
+
+	automations := []models.Automation{}
+	cursor.All(ctx, &automations)
+	return c.JSON(automations)
+}
+
+func CreateAutomation(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body automationBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	now := time.Now()
+	a := models.Automation{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		Enabled:   true,
+		Actions:   []models.AutomationAction{},
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if msg := applyAutomationBody(ctx, &a, body); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	armAutomation(&a, true, now)
+
+	if _, err := database.GetCollection("automations").InsertOne(ctx, a); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create automation"})
+	}
+	recordActivity(ctx, projectID, userID, "created", "automation", a.ID, a.Name,
+		[]models.ActivityChange{{Field: "trigger", To: a.Trigger.Type}})
+
+	return c.Status(fiber.StatusCreated).JSON(a)
+}
+
+func UpdateAutomation(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	automationID, err := primitive.ObjectIDFromHex(c.Params("automationId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid automation ID"})
+	}
+
+	var body automationBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("automations")
+	var a models.Automation
+	if err := col.FindOne(ctx, bson.M{"_id": automationID, "project_id": projectID}).Decode(&a); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Automation not found"})
+	}
+	before := a
+
+	if msg := applyAutomationBody(ctx, &a, body); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	now := time.Now()
+	triggerChanged := !reflect.DeepEqual(a.Trigger, before.Trigger)
+	if triggerChanged || a.Enabled != before.Enabled {
+		armAutomation(&a, triggerChanged, now)
+	}
+	a.UpdatedAt = now
+
+	col.ReplaceOne(ctx, bson.M{"_id": automationID}, a)
+
+	changes := appendChange(nil, "name", before.Name, a.Name)
+	changes = appendChange(changes, "enabled", before.Enabled, a.Enabled)
+	changes = appendChange(changes, "trigger", before.Trigger.Type, a.Trigger.Type)
+	changes = appendChange(changes, "conditions", before.Conditions, a.Conditions)
+	if len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "automation", a.ID, a.Name, changes)
+	}
+
+	return c.JSON(a)
+}
+
+func DeleteAutomation(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	automationID, err := primitive.ObjectIDFromHex(c.Params("automationId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid automation ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var a models.Automation
+	if err := database.GetCollection("automations").FindOne(ctx, bson.M{"_id": automationID, "project_id": projectID}).Decode(&a); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Automation not found"})
+	}
+
+	database.GetCollection("automations").DeleteOne(ctx, bson.M{"_id": automationID})
+	database.GetCollection("automation_runs").DeleteMany(ctx, bson.M{"automation_id": automationID})
+	recordActivity(ctx, projectID, userID, "deleted", "automation", a.ID, a.Name, nil)
+
+	return c.JSON(fiber.Map{"message": "Automation deleted"})
+}
+
+// ListAutomationRuns returns the execution log, newest first, optionally
+// for one automation or card.
+func ListAutomationRuns(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	filter := bson.M{"project_id": projectID}
+	for param, field := range map[string]string{"automation_id": "automation_id", "card_id": "card_id"} {
+		if raw := c.Query(param); raw != "" {
+			id, err := primitive.ObjectIDFromHex(raw)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid " + param})
+			}
+			filter[field] = id
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	limit := int64(50)
+	if n, err := strconv.ParseInt(c.Query("limit"), 10, 64); err == nil && n > 0 && n <= 200 {
+		limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	cursor, err := database.GetCollection("automation_runs").Find(ctx, filter,
+		options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch automation runs"})
+	}
+	defer cursor.Close(ctx)
+
+	runs := []models.AutomationRun{}
+	cursor.All(ctx, &runs)
+	return c.JSON(runs)
+}
+
+// TestAutomation dry-runs a saved automation (automation_id) or an unsaved
+// rule against a card, reporting what would happen without changing
+// anything.
+func TestAutomation(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		automationBody
+		AutomationID string `json:"automation_id"`
+		CardID       string `json:"card_id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	cardID, err := primitive.ObjectIDFromHex(body.CardID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card_id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	a := models.Automation{ProjectID: projectID, Enabled: true, CreatedBy: userID}
+	if body.AutomationID != "" {
+		automationID, err := primitive.ObjectIDFromHex(body.AutomationID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid automation_id"})
+		}
+		if err := database.GetCollection("automations").FindOne(ctx, bson.M{"_id": automationID, "project_id": projectID}).Decode(&a); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Automation not found"})
+		}
+	}
+	if msg := applyAutomationBody(ctx, &a, body.automationBody); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID, "project_id": projectID, "archived_at": nil}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	// The trigger is taken to have just fired for the card; a field filter
+	// on card.updated cannot be checked without a previous version.
+	triggerMatches := automationTriggerMatches(a.Trigger, automationEvent{Type: a.Trigger.Type, Card: card})
+	conditionsMatch, err := automationConditionsMatch(ctx, a, cardID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid conditions: " + err.Error()})
+	}
+
+	actions := []fiber.Map{}
+	wouldRun := triggerMatches && conditionsMatch
+	for _, act := range a.Actions {
+		desc, err := applyAutomationAction(ctx, a, act, &card, true)
+		result := fiber.Map{"type": act.Type, "description": desc}
+		if err != nil {
+			result["error"] = err.Error()
+			wouldRun = false
+		}
+		actions = append(actions, result)
+	}
+
+	return c.JSON(fiber.Map{
+		"trigger_matches":  triggerMatches,
+		"conditions_match": conditionsMatch,
+		"would_run":        wouldRun,
+		"actions":          actions,
+	})
+}
+
+// eachAutomationCard calls fn for every card matching filter, a page at a
+// time so large projects are covered without loading them all at once.
+func eachAutomationCard(ctx context.Context, filter bson.M, fn func(models.Card)) {
+	var after primitive.ObjectID
+	for {
+		page := bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": after}}}}
+		cards := findCards(ctx, page, options.Find().SetSort(bson.M{"_id": 1}).SetLimit(automationCardPage))
+		for _, card := range cards {
+			fn(card)
+		}
+		if len(cards) < automationCardPage || ctx.Err() != nil {
+			return
+		}
+		after = cards[len(cards)-1].ID
+	}
+}
+
+// RunAutomations fires the time-based rules. An overdue rule sweeps the
+// cards that became overdue since its last sweep, and a scheduled rule
+// runs on every card matching its conditions once its next occurrence is
+// due. Both are claimed by advancing their marker first, so concurrent
+// runs never fire twice.
+func RunAutomations(ctx context.Context) {
+	now := time.Now()
+	col := database.GetCollection("automations")
+
+	var overdue []models.Automation
+	if cursor, err := col.Find(ctx, bson.M{"enabled": true, "trigger.type": "card.overdue"}); err == nil {
+		cursor.All(ctx, &overdue)
+		cursor.Close(ctx)
+	}
+	for _, a := range overdue {
+		if a.CheckedAt == nil {
+			col.UpdateOne(ctx, bson.M{"_id": a.ID}, bson.M{"$set": bson.M{"checked_at": now}})
+			continue
+		}
+		res, err := col.UpdateOne(ctx, bson.M{"_id": a.ID, "checked_at": a.CheckedAt}, bson.M{"$set": bson.M{"checked_at": now}})
+		if err != nil || res.ModifiedCount == 0 {
+			continue
+		}
+		// Due dates are stored as midnight UTC of the due day, so a card
+		// is overdue a day after its due date.
+		eachAutomationCard(ctx, bson.M{
+			"project_id":  a.ProjectID,
+			"archived_at": nil,
+			"due_date":    bson.M{"$gt": a.CheckedAt.Add(-24 * time.Hour), "$lte": now.Add(-24 * time.Hour)},
+		}, func(card models.Card) {
+			runAutomation(ctx, a, automationEvent{Type: "card.overdue", Card: card}, newAutomationChain())
+		})
+	}
+
+	var scheduled []models.Automation
+	if cursor, err := col.Find(ctx, bson.M{"enabled": true, "trigger.type": "schedule", "next_run_at": bson.M{"$lte": now}}); err == nil {
+		cursor.All(ctx, &scheduled)
+		cursor.Close(ctx)
+	}
+	for _, a := range scheduled {
+		rule, err := parseRRule(a.Trigger.Rule)
+		if err != nil || a.StartAt == nil {
+			col.UpdateOne(ctx, bson.M{"_id": a.ID}, bson.M{"$set": bson.M{"enabled": false}})
+			continue
+		}
+		next := nextRecurrenceRun(rule, *a.StartAt, now)
+		res, err := col.UpdateOne(ctx,
+			bson.M{"_id": a.ID, "next_run_at": a.NextRunAt},
+			bson.M{"$set": bson.M{"next_run_at": next}},
+		)
+		if err != nil || res.ModifiedCount == 0 {
+			continue
+		}
+
+		filter, err := automationConditions(ctx, a)
+		if err != nil {
+			continue
+		}
+		eachAutomationCard(ctx, bson.M{"$and": bson.A{bson.M{"project_id": a.ProjectID, "archived_at": nil}, filter}},
+			func(card models.Card) {
+				runAutomation(ctx, a, automationEvent{Type: "schedule", Card: card}, newAutomationChain())
+			})
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/fpmb/server/internal/database"
@@ -30,9 +32,78 @@ func GetBoard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
 	}
 
+	// Read before loading so a live client replaying from seq may see a
+	// change twice but never misses one.
+	seq := currentBoardSeq(ctx, projectID)
+
+	cardFilter := bson.M{}
+	if raw := c.Query("labels"); raw != "" {
+		labelIDs := []primitive.ObjectID{}
+		for _, part := range strings.Split(raw, ",") {
+			id, err := primitive.ObjectIDFromHex(strings.TrimSpace(part))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label ID"})
+			}
+			labelIDs = append(labelIDs, id)
+		}
+		if c.Query("label_match") == "all" {
+			cardFilter["label_ids"] = bson.M{"$all": labelIDs}
+		} else {
+			cardFilter["label_ids"] = bson.M{"$in": labelIDs}
+		}
+	}
+
+	groupBy := c.Query("group_by")
+	if groupBy != "" && !validGroupBy(groupBy) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "group_by must be one of lane, assignee, priority, label"})
+	}
+
+	// An explicit view is combined with q; without either, the default view
+	// applies. view=none shows the unfiltered board.
+	query := strings.TrimSpace(c.Query("q"))
+	sortParam := c.Query("sort")
+	var view *models.SavedView
+	switch viewParam := c.Query("view"); {
+	case viewParam == "none":
+	case viewParam != "":
+		viewID, err := primitive.ObjectIDFromHex(viewParam)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid view ID"})
+		}
+		view, err = readableView(ctx, userID, viewID)
+		if err != nil || (view.ProjectID != nil && *view.ProjectID != projectID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "View not found"})
+		}
+	case query == "" && c.Query("labels") == "":
+		view = defaultView(ctx, userID, projectID)
+	}
+	if view != nil {
+		query = strings.TrimSpace(view.Query + " " + query)
+		if groupBy == "" {
+			groupBy = view.GroupBy
+		}
+		if sortParam == "" {
+			sortParam = view.Sort
+		}
+	}
+
+	cardSort, err := compileBoardSort(ctx, projectID, sortParam)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	queryFilter, err := compileBoardQuery(ctx, query, newQueryScope(ctx, userID, []primitive.ObjectID{projectID}))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid query: " + err.Error()})
+	}
+	for k, v := range queryFilter {
+		cardFilter[k] = v
+	}
+	cardFilter["archived_at"] = nil
+
 	colCursor, err := database.GetCollection("board_columns").Find(ctx,
-		bson.M{"project_id": projectID},
-		options.Find().SetSort(bson.M{"position": 1}))
+		bson.M{"project_id": projectID, "archived_at": nil},
+		options.Find().SetSort(rankSort))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch columns"})
 	}
@@ -41,30 +112,74 @@ func GetBoard(c *fiber.Ctx) error {
 	var columns []models.BoardColumn
 	colCursor.All(ctx, &columns)
 
+	type BoardCard struct {
+		cardResponse
+		CommentCount    int `json:"comment_count"`
+		AttachmentCount int `json:"attachment_count"`
+	}
+
 	type ColumnWithCards struct {
 		models.BoardColumn
-		Cards []models.Card `json:"cards"`
+		OverLimit          bool        `json:"over_limit"`
+		OverLimitAssignees []string    `json:"over_limit_assignees"`
+		Cards              []BoardCard `json:"cards"`
 	}
 
-	result := []ColumnWithCards{}
-	for _, col := range columns {
+	columnCards := make([][]models.Card, len(columns))
+	cardIDs := []primitive.ObjectID{}
+	for i, col := range columns {
+		cardFilter["column_id"] = col.ID
 		cardCursor, err := database.GetCollection("cards").Find(ctx,
-			bson.M{"column_id": col.ID},
-			options.Find().SetSort(bson.M{"position": 1}))
+			cardFilter,
+			options.Find().SetSort(cardSort))
 		if err != nil {
-			result = append(result, ColumnWithCards{BoardColumn: col, Cards: []models.Card{}})
 			continue
 		}
-		var cards []models.Card
-		cardCursor.All(ctx, &cards)
+		cardCursor.All(ctx, &columnCards[i])
 		cardCursor.Close(ctx)
-		if cards == nil {
-			cards = []models.Card{}
+		for _, card := range columnCards[i] {
+			cardIDs = append(cardIDs, card.ID)
+		}
+	}
+
+	comments := commentCounts(ctx, projectID)
+	links := cardLinkSummaries(ctx, userID, cardIDs)
+
+	result := []ColumnWithCards{}
+	for i, col := range columns {
+		boardCards := []BoardCard{}
+		for _, card := range columnCards[i] {
+			if card.LabelIDs == nil {
+				card.LabelIDs = []primitive.ObjectID{}
+			}
+			cardLinks := links[card.ID]
+			if cardLinks == nil {
+				cardLinks = []cardLinkSummary{}
+			}
+			boardCards = append(boardCards, BoardCard{
+				cardResponse:    cardResponse{Card: card, Links: cardLinks},
+				CommentCount:    comments[card.ID],
+				AttachmentCount: len(card.AttachmentIDs),
+			})
 		}
-		result = append(result, ColumnWithCards{BoardColumn: col, Cards: cards})
+		over, overAssignees := columnWIPStatus(ctx, col)
+		result = append(result, ColumnWithCards{
+			BoardColumn:        col,
+			OverLimit:          over,
+			OverLimitAssignees: overAssignees,
+			Cards:              boardCards,
+		})
 	}
 
-	return c.JSON(fiber.Map{"project_id": projectID, "columns": result})
+	response := fiber.Map{"project_id": projectID, "columns": result, "query": query, "sort": sortParam, "seq": seq}
+	if view != nil {
+		response["view_id"] = view.ID
+	}
+	if groupBy != "" {
+		response["group_by"] = groupBy
+		response["lanes"] = buildLanes(ctx, projectID, groupBy, columns, columnCards)
+	}
+	return c.JSON(response)
 }
 
 func CreateColumn(c *fiber.Ctx) error {
@@ -79,11 +194,18 @@ func CreateColumn(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Title string `json:"title"`
+		Title            string `json:"title"`
+		IsDone           bool   `json:"is_done"`
+		WIPLimit         int    `json:"wip_limit"`
+		AssigneeWIPLimit int    `json:"assignee_wip_limit"`
+		WIPMode          string `json:"wip_mode"`
 	}
 	if err := c.BodyParser(&body); err != nil || body.Title == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
 	}
+	if body.WIPLimit < 0 || body.AssigneeWIPLimit < 0 || !validWIPMode(body.WIPMode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "WIP limits must be positive and wip_mode one of warn, block"})
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -93,18 +215,28 @@ func CreateColumn(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	count, _ := database.GetCollection("board_columns").CountDocuments(ctx, bson.M{"project_id": projectID})
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	now := time.Now()
 	col := &models.BoardColumn{
-		ID:        primitive.NewObjectID(),
-		ProjectID: projectID,
-		Title:     body.Title,
-		Position:  int(count),
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:               primitive.NewObjectID(),
+		ProjectID:        projectID,
+		Title:            body.Title,
+		Rank:             rankAt(ctx, "board_columns", bson.M{"project_id": projectID}, primitive.NilObjectID, -1),
+		IsDone:           body.IsDone,
+		WIPLimit:         body.WIPLimit,
+		AssigneeWIPLimit: body.AssigneeWIPLimit,
+		WIPMode:          body.WIPMode,
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 
 	database.GetCollection("board_columns").InsertOne(ctx, col)
+	recordActivity(ctx, projectID, userID, "created", "column", col.ID, col.Title, nil)
+	publishBoardEvent(ctx, projectID, userID, "column.created", col)
+	setVersionETag(c, col.Version)
 	return c.Status(fiber.StatusCreated).JSON(col)
 }
 
@@ -125,9 +257,17 @@ func UpdateColumn(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Title string `json:"title"`
+		Title            string  `json:"title"`
+		IsDone           *bool   `json:"is_done"`
+		WIPLimit         *int    `json:"wip_limit"`
+		AssigneeWIPLimit *int    `json:"assignee_wip_limit"`
+		WIPMode          *string `json:"wip_mode"`
 	}
 	c.BodyParser(&body)
+	if (body.WIPLimit != nil && *body.WIPLimit < 0) || (body.AssigneeWIPLimit != nil && *body.AssigneeWIPLimit < 0) ||
+		(body.WIPMode != nil && !validWIPMode(*body.WIPMode)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "WIP limits must be positive and wip_mode one of warn, block"})
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -137,16 +277,63 @@ func UpdateColumn(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	update := bson.M{"updated_at": time.Now()}
 	if body.Title != "" {
 		update["title"] = body.Title
 	}
+	if body.IsDone != nil {
+		update["is_done"] = *body.IsDone
+	}
+	if body.WIPLimit != nil {
+		update["wip_limit"] = *body.WIPLimit
+	}
+	if body.AssigneeWIPLimit != nil {
+		update["assignee_wip_limit"] = *body.AssigneeWIPLimit
+	}
+	if body.WIPMode != nil {
+		update["wip_mode"] = *body.WIPMode
+	}
 
 	col := database.GetCollection("board_columns")
-	col.UpdateOne(ctx, bson.M{"_id": columnID, "project_id": projectID}, bson.M{"$set": update})
+
+	var before models.BoardColumn
+	if err := col.FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID}).Decode(&before); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+
+	filter := bson.M{"_id": columnID, "project_id": projectID}
+	if err := matchVersion(c, filter, before.Version); err != nil {
+		return versionError(c, err, before.Version, before)
+	}
+
+	res, err := col.UpdateOne(ctx, filter, bson.M{"$set": update, "$inc": bumpVersion})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update column"})
+	}
 
 	var column models.BoardColumn
-	col.FindOne(ctx, bson.M{"_id": columnID}).Decode(&column)
+	if err := col.FindOne(ctx, bson.M{"_id": columnID}).Decode(&column); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+	if res.MatchedCount == 0 {
+		return versionConflict(c, column.Version, column)
+	}
+
+	changes := appendChange(nil, "title", before.Title, column.Title)
+	changes = appendChange(changes, "is_done", before.IsDone, column.IsDone)
+	changes = appendChange(changes, "wip_limit", before.WIPLimit, column.WIPLimit)
+	changes = appendChange(changes, "assignee_wip_limit", before.AssigneeWIPLimit, column.AssigneeWIPLimit)
+	changes = appendChange(changes, "wip_mode", before.WIPMode, column.WIPMode)
+	if len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "column", columnID, column.Title, changes)
+		publishBoardEvent(ctx, projectID, userID, "column.updated", column)
+	}
+
+	setVersionETag(c, column.Version)
 	return c.JSON(column)
 }
 
@@ -167,9 +354,11 @@ func ReorderColumn(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Position int `json:"position"`
+		Position *int `json:"position"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Position == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "position is required"})
 	}
-	c.BodyParser(&body)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -179,11 +368,41 @@ func ReorderColumn(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	database.GetCollection("board_columns").UpdateOne(ctx,
-		bson.M{"_id": columnID, "project_id": projectID},
-		bson.M{"$set": bson.M{"position": body.Position, "updated_at": time.Now()}},
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var before models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID}).Decode(&before); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+
+	filter := bson.M{"_id": columnID, "project_id": projectID}
+	if err := matchVersion(c, filter, before.Version); err != nil {
+		return versionError(c, err, before.Version, before)
+	}
+
+	rank := rankAt(ctx, "board_columns", bson.M{"project_id": projectID}, columnID, *body.Position)
+	res, err := database.GetCollection("board_columns").UpdateOne(ctx, filter,
+		bson.M{"$set": bson.M{"rank": rank, "updated_at": time.Now()}, "$inc": bumpVersion},
 	)
-	return c.JSON(fiber.Map{"id": columnID, "position": body.Position})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to move column"})
+	}
+	if res.MatchedCount == 0 {
+		var current models.BoardColumn
+		database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID}).Decode(&current)
+		return versionConflict(c, current.Version, current)
+	}
+
+	if before.Rank != rank {
+		recordActivity(ctx, projectID, userID, "moved", "column", columnID, before.Title,
+			[]models.ActivityChange{{Field: "position", To: *body.Position}})
+		publishBoardEvent(ctx, projectID, userID, "column.moved", fiber.Map{"id": columnID, "rank": rank})
+	}
+
+	setVersionETag(c, before.Version+1)
+	return c.JSON(fiber.Map{"id": columnID, "rank": rank, "version": before.Version + 1})
 }
 
 func DeleteColumn(c *fiber.Ctx) error {
@@ -210,8 +429,89 @@ func DeleteColumn(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	database.GetCollection("board_columns").DeleteOne(ctx, bson.M{"_id": columnID, "project_id": projectID})
-	database.GetCollection("cards").DeleteMany(ctx, bson.M{"column_id": columnID})
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var column models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID}).Decode(&column); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+
+	filter := bson.M{"_id": columnID, "project_id": projectID}
+	if err := matchVersion(c, filter, column.Version); err != nil {
+		return versionError(c, err, column.Version, column)
+	}
+
+	// With ?move_to= the column's cards, archived ones included, move to
+	// another column. Without it the column must have no live cards; its
+	// archived ones stay in the archive.
+	var moveTo *models.BoardColumn
+	if raw := c.Query("move_to"); raw != "" {
+		targetID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil || targetID == columnID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid move_to column"})
+		}
+		var target models.BoardColumn
+		if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": targetID, "project_id": projectID, "archived_at": nil}).Decode(&target); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid move_to column"})
+		}
+		moveTo = &target
+	} else if live, _ := database.GetCollection("cards").CountDocuments(ctx, bson.M{"column_id": columnID, "archived_at": nil}); live > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Column still has cards; move them with move_to or archive the column"})
+	}
+
+	res, err := database.GetCollection("board_columns").DeleteOne(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete column"})
+	}
+	if res.DeletedCount == 0 {
+		var current models.BoardColumn
+		if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID}).Decode(&current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+		}
+		return versionConflict(c, current.Version, current)
+	}
+
+	cards := findCards(ctx, bson.M{"column_id": columnID}, options.Find().SetSort(rankSort))
+	database.GetCollection("watches").DeleteMany(ctx, bson.M{"target_type": "column", "target_id": columnID})
+
+	if moveTo != nil {
+		for _, card := range cards {
+			if card.ArchivedAt == nil {
+				recordCardTransition(ctx, card, &columnID, &moveTo.ID, userID)
+			}
+		}
+		// Cards archived along with the column stay archived, but as cards
+		// of their own so they show up in the archive and can be restored.
+		for _, card := range cards {
+			database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{"$set": bson.M{
+				"column_id":  moveTo.ID,
+				"rank":       rankAt(ctx, "cards", bson.M{"column_id": moveTo.ID}, card.ID, -1),
+				"updated_at": time.Now(),
+			}, "$unset": bson.M{"column_archived": ""}, "$inc": bumpVersion})
+		}
+		database.GetCollection("recurrences").UpdateMany(ctx,
+			bson.M{"project_id": projectID, "column_id": columnID},
+			bson.M{"$set": bson.M{"column_id": moveTo.ID, "updated_at": time.Now()}},
+		)
+		recordActivity(ctx, projectID, userID, "deleted", "column", columnID, column.Title,
+			[]models.ActivityChange{{Field: "cards", From: len(cards), To: moveTo.Title}})
+		publishBoardEvent(ctx, projectID, userID, "column.deleted", fiber.Map{"id": columnID, "moved_to": moveTo.ID})
+		return c.JSON(fiber.Map{"message": "Column deleted", "moved": len(cards)})
+	}
+
+	// Cards archived along with the column become cards archived on their
+	// own; restoring one then asks for a column.
+	database.GetCollection("cards").UpdateMany(ctx, bson.M{"column_id": columnID, "column_archived": true},
+		bson.M{"$unset": bson.M{"column_archived": ""}, "$inc": bumpVersion})
+	// Recurrences feeding this column stay around, paused, until retargeted.
+	database.GetCollection("recurrences").UpdateMany(ctx,
+		bson.M{"project_id": projectID, "column_id": columnID},
+		bson.M{"$set": bson.M{"paused": true, "updated_at": time.Now()}},
+	)
+	recordActivity(ctx, projectID, userID, "deleted", "column", columnID, column.Title, nil)
+	publishBoardEvent(ctx, projectID, userID, "column.deleted", fiber.Map{"id": columnID})
 	return c.JSON(fiber.Map{"message": "Column deleted"})
 }
 
@@ -232,13 +532,19 @@ func CreateCard(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Title       string           `json:"title"`
-		Description string           `json:"description"`
-		Priority    string           `json:"priority"`
-		Color       string           `json:"color"`
-		DueDate     string           `json:"due_date"`
-		Assignees   []string         `json:"assignees"`
-		Subtasks    []models.Subtask `json:"subtasks"`
+		Title            string                 `json:"title"`
+		Description      string                 `json:"description"`
+		Priority         string                 `json:"priority"`
+		Color            string                 `json:"color"`
+		DueDate          string                 `json:"due_date"`
+		Assignees        []string               `json:"assignees"`
+		LabelIDs         []string               `json:"label_ids"`
+		LaneID           string                 `json:"lane_id"`
+		SprintID         string                 `json:"sprint_id"`
+		Subtasks         []models.Subtask       `json:"subtasks"`
+		CustomFields     map[string]interface{} `json:"custom_fields"`
+		EstimatedMinutes *int                   `json:"estimated_minutes"`
+		ActualMinutes    *int                   `json:"actual_minutes"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
@@ -255,7 +561,42 @@ func CreateCard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	count, _ := database.GetCollection("cards").CountDocuments(ctx, bson.M{"column_id": columnID})
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	labelIDs, err := resolveLabelIDs(ctx, projectID, body.LabelIDs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown label in label_ids"})
+	}
+
+	customValues, _, err := resolveCustomFields(ctx, projectID, body.CustomFields, nil)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	sprintID, err := resolveSprintID(ctx, projectID, body.SprintID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sprint_id must be a planned or active sprint of this project"})
+	}
+
+	var column models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID, "archived_at": nil}).Decode(&column); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+
+	var laneID *primitive.ObjectID
+	if body.LaneID != "" {
+		id, err := primitive.ObjectIDFromHex(body.LaneID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lane_id"})
+		}
+		if n, _ := database.GetCollection("swimlanes").CountDocuments(ctx, bson.M{"_id": id, "project_id": projectID}); n == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lane_id"})
+		}
+		laneID = &id
+	}
+
 	now := time.Now()
 
 	if body.Assignees == nil {
@@ -279,23 +620,47 @@ func CreateCard(c *fiber.Ctx) error {
 	}
 
 	card := &models.Card{
-		ID:          primitive.NewObjectID(),
-		ColumnID:    columnID,
-		ProjectID:   projectID,
-		Title:       body.Title,
-		Description: body.Description,
-		Priority:    body.Priority,
-		Color:       body.Color,
-		DueDate:     dueDate,
-		Assignees:   body.Assignees,
-		Subtasks:    body.Subtasks,
-		Position:    int(count),
-		CreatedBy:   userID,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-	}
-
-	database.GetCollection("cards").InsertOne(ctx, card)
+		ID:               primitive.NewObjectID(),
+		ColumnID:         columnID,
+		LaneID:           laneID,
+		SprintID:         sprintID,
+		ProjectID:        projectID,
+		Title:            body.Title,
+		Description:      body.Description,
+		Priority:         body.Priority,
+		Color:            body.Color,
+		DueDate:          dueDate,
+		Assignees:        body.Assignees,
+		LabelIDs:         labelIDs,
+		EstimatedMinutes: body.EstimatedMinutes,
+		ActualMinutes:    body.ActualMinutes,
+		Subtasks:         body.Subtasks,
+		SubtaskSeq:       numberSubtasks(body.Subtasks),
+		CustomFields:     customValues,
+		Rank:             rankAt(ctx, "cards", bson.M{"column_id": columnID}, primitive.NilObjectID, -1),
+		CreatedBy:        userID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	violations := checkWIP(ctx, column, *card)
+	if len(violations) > 0 && wipBlocks(column) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "WIP limit reached", "violations": violations})
+	}
+
+	card.Number, card.Key = nextCardKey(ctx, projectID)
+	if _, err := database.GetCollection("cards").InsertOne(ctx, card); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create card"})
+	}
+	recordCardTransition(ctx, *card, nil, &columnID, userID)
+	if len(violations) > 0 {
+		notifyWIPExceeded(ctx, column, *card, userID, violations)
+	}
+	recordActivity(ctx, projectID, userID, "created", "card", card.ID, card.Title,
+		[]models.ActivityChange{{Field: "column", To: columnTitle(ctx, columnID)}})
+	autoWatchCard(ctx, *card, userID)
+	publishBoardEvent(ctx, projectID, userID, "card.created", card)
+	fireAutomations(automationEvent{Type: "card.created", Card: *card})
 
 	for _, email := range card.Assignees {
 		var assignee models.User
@@ -310,7 +675,8 @@ func CreateCard(c *fiber.Ctx) error {
 			card.ProjectID, card.ID)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(card)
+	setVersionETag(c, card.Version)
+	return c.Status(fiber.StatusCreated).JSON(cardResponse{Card: *card, Links: []cardLinkSummary{}, WIPWarnings: violations})
 }
 
 func UpdateCard(c *fiber.Ctx) error {
@@ -337,14 +703,33 @@ func UpdateCard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, existing.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if existing.ArchivedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is archived; restore it first"})
+	}
+
+	filter := bson.M{"_id": cardID}
+	if err := matchVersion(c, filter, existing.Version); err != nil {
+		return versionError(c, err, existing.Version, existing)
+	}
+
 	var body struct {
-		Title       *string          `json:"title"`
-		Description *string          `json:"description"`
-		Priority    *string          `json:"priority"`
-		Color       *string          `json:"color"`
-		DueDate     *string          `json:"due_date"`
-		Assignees   []string         `json:"assignees"`
-		Subtasks    []models.Subtask `json:"subtasks"`
+		Title            *string                `json:"title"`
+		Description      *string                `json:"description"`
+		Priority         *string                `json:"priority"`
+		Color            *string                `json:"color"`
+		DueDate          *string                `json:"due_date"`
+		Assignees        []string               `json:"assignees"`
+		LabelIDs         []string               `json:"label_ids"`
+		LaneID           *string                `json:"lane_id"`
+		SprintID         *string                `json:"sprint_id"`
+		Subtasks         []subtaskInput         `json:"subtasks"`
+		CustomFields     map[string]interface{} `json:"custom_fields"`
+		EstimatedMinutes *int                   `json:"estimated_minutes"`
+		ActualMinutes    *int                   `json:"actual_minutes"`
 	}
 	c.BodyParser(&body)
 
@@ -371,17 +756,103 @@ func UpdateCard(c *fiber.Ctx) error {
 	if body.Assignees != nil {
 		update["assignees"] = body.Assignees
 	}
+	if body.LabelIDs != nil {
+		labelIDs, err := resolveLabelIDs(ctx, existing.ProjectID, body.LabelIDs)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown label in label_ids"})
+		}
+		update["label_ids"] = labelIDs
+	}
+	if body.LaneID != nil {
+		laneUpdate, _, err := laneMoveUpdate(ctx, existing, "lane", "", *body.LaneID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lane_id"})
+		}
+		update["lane_id"] = laneUpdate["lane_id"]
+	}
+	if body.SprintID != nil {
+		sprintID, err := resolveSprintID(ctx, existing.ProjectID, *body.SprintID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sprint_id must be a planned or active sprint of this project"})
+		}
+		update["sprint_id"] = sprintID
+	}
 	if body.Subtasks != nil {
-		update["subtasks"] = body.Subtasks
+		subtasks, err := mergeSubtasks(ctx, existing, body.Subtasks)
+		if errors.Is(err, errSubtaskDueDate) || errors.Is(err, errSubtaskAssignee) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update subtasks"})
+		}
+		update["subtasks"] = subtasks
+	}
+	if body.EstimatedMinutes != nil {
+		update["estimated_minutes"] = *body.EstimatedMinutes
+	}
+	if body.ActualMinutes != nil && (existing.ActualMinutes == nil || *existing.ActualMinutes != *body.ActualMinutes) {
+		if cardHasTimeEntries(ctx, cardID) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "actual_minutes is tracked by time entries"})
+		}
+		update["actual_minutes"] = *body.ActualMinutes
+	}
+
+	ops := bson.M{"$set": update, "$inc": bumpVersion}
+	if body.CustomFields != nil {
+		set, unset, err := resolveCustomFields(ctx, existing.ProjectID, body.CustomFields, existing.CustomFields)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		for id, value := range set {
+			update["custom_fields."+id] = value
+		}
+		if len(unset) > 0 {
+			cleared := bson.M{}
+			for _, id := range unset {
+				cleared["custom_fields."+id] = ""
+			}
+			ops["$unset"] = cleared
+		}
 	}
 
 	col := database.GetCollection("cards")
-	col.UpdateOne(ctx, bson.M{"_id": cardID}, bson.M{"$set": update})
+	res, err := col.UpdateOne(ctx, filter, ops)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update card"})
+	}
 
 	var card models.Card
-	col.FindOne(ctx, bson.M{"_id": cardID}).Decode(&card)
+	if err := col.FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+	if res.MatchedCount == 0 {
+		return versionConflict(c, card.Version, card)
+	}
+
+	changes := cardChanges(existing, card)
+	if body.LabelIDs != nil {
+		changes = appendChange(changes, "labels", labelNames(ctx, existing.LabelIDs), labelNames(ctx, card.LabelIDs))
+	}
+	if body.LaneID != nil {
+		changes = appendChange(changes, "lane", laneTitle(ctx, existing.LaneID), laneTitle(ctx, card.LaneID))
+	}
+	if body.SprintID != nil {
+		changes = appendChange(changes, "sprint", sprintName(ctx, existing.SprintID), sprintName(ctx, card.SprintID))
+	}
+	if body.CustomFields != nil {
+		changes = append(changes, customFieldChanges(ctx, existing, card)...)
+	}
+	if len(changes) > 0 {
+		recordActivity(ctx, card.ProjectID, userID, "updated", "card", card.ID, card.Title, changes)
+	}
+	notifyWatchers(ctx, card, userID, watchEvents(ctx, existing, card))
+	publishBoardEvent(ctx, card.ProjectID, userID, "card.updated", card)
+	if len(changedCardFields(existing, card)) > 0 {
+		fireAutomations(automationEvent{Type: "card.updated", Card: card, Before: &existing})
+	}
 
 	if body.Assignees != nil {
+		autoWatchCard(ctx, card)
 		existingSet := make(map[string]bool)
 		for _, e := range existing.Assignees {
 			existingSet[e] = true
@@ -403,7 +874,8 @@ func UpdateCard(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(card)
+	setVersionETag(c, card.Version)
+	return c.JSON(withLinks(ctx, userID, card))
 }
 
 func MoveCard(c *fiber.Ctx) error {
@@ -418,8 +890,13 @@ func MoveCard(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		ColumnID string `json:"column_id"`
-		Position int    `json:"position"`
+		ColumnID string  `json:"column_id"`
+		Position *int    `json:"position"`
+		Force    bool    `json:"force"`
+		LaneID   *string `json:"lane_id"`
+		GroupBy  string  `json:"group_by"`
+		FromLane string  `json:"from_lane"`
+		ToLane   *string `json:"to_lane"`
 	}
 	if err := c.BodyParser(&body); err != nil || body.ColumnID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "column_id is required"})
@@ -443,16 +920,126 @@ func MoveCard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	col := database.GetCollection("cards")
-	col.UpdateOne(ctx, bson.M{"_id": cardID}, bson.M{"$set": bson.M{
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if card.ArchivedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is archived; restore it first"})
+	}
+
+	filter := bson.M{"_id": cardID}
+	if err := matchVersion(c, filter, card.Version); err != nil {
+		return versionError(c, err, card.Version, card)
+	}
+
+	var target models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": newColumnID, "project_id": card.ProjectID, "archived_at": nil}).Decode(&target); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+
+	if card.ColumnID != newColumnID && !body.Force && isDoneColumn(target) && !columnIsDone(ctx, card.ColumnID) {
+		if blockers := unresolvedBlockers(ctx, userID, cardID); len(blockers) > 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":      "Card is blocked by unfinished cards",
+				"blocked_by": blockers,
+			})
+		}
+	}
+
+	// A lane move is either an explicit lane_id or, for dynamic grouping,
+	// group_by plus the lane keys the card is dragged from and to.
+	laneUpdate, rankFilter := bson.M{}, bson.M{"column_id": newColumnID, "archived_at": nil}
+	if body.LaneID != nil {
+		body.GroupBy, body.ToLane = "lane", body.LaneID
+	}
+	if body.ToLane != nil {
+		if !validGroupBy(body.GroupBy) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "group_by must be one of lane, assignee, priority, label"})
+		}
+		var cell bson.M
+		laneUpdate, cell, err = laneMoveUpdate(ctx, card, body.GroupBy, body.FromLane, *body.ToLane)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid target lane"})
+		}
+		// Position counts within the target lane's cell of the column.
+		for k, v := range cell {
+			rankFilter[k] = v
+		}
+	}
+
+	moved := card
+	if assignees, ok := laneUpdate["assignees"].([]string); ok {
+		moved.Assignees = assignees
+	}
+
+	var violations []wipViolation
+	if card.ColumnID != newColumnID || laneUpdate["assignees"] != nil {
+		violations = checkWIP(ctx, target, moved)
+		if len(violations) > 0 && wipBlocks(target) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "WIP limit reached", "violations": violations})
+		}
+	}
+
+	index := -1
+	if body.Position != nil {
+		index = *body.Position
+	}
+
+	update := bson.M{
 		"column_id":  newColumnID,
-		"position":   body.Position,
+		"rank":       rankAt(ctx, "cards", rankFilter, cardID, index),
 		"updated_at": time.Now(),
-	}})
+	}
+	for k, v := range laneUpdate {
+		update[k] = v
+	}
+
+	col := database.GetCollection("cards")
+	res, err := col.UpdateOne(ctx, filter, bson.M{"$set": update, "$inc": bumpVersion})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to move card"})
+	}
 
 	var updated models.Card
-	col.FindOne(ctx, bson.M{"_id": cardID}).Decode(&updated)
-	return c.JSON(updated)
+	if err := col.FindOne(ctx, bson.M{"_id": cardID}).Decode(&updated); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+	if res.MatchedCount == 0 {
+		return versionConflict(c, updated.Version, updated)
+	}
+
+	if len(laneUpdate) > 0 {
+		changes := cardChanges(card, updated)
+		changes = appendChange(changes, "labels", labelNames(ctx, card.LabelIDs), labelNames(ctx, updated.LabelIDs))
+		changes = appendChange(changes, "lane", laneTitle(ctx, card.LaneID), laneTitle(ctx, updated.LaneID))
+		if len(changes) > 0 {
+			recordActivity(ctx, card.ProjectID, userID, "updated", "card", cardID, card.Title, changes)
+		}
+		autoWatchCard(ctx, updated)
+	}
+
+	if card.ColumnID != newColumnID {
+		recordCardTransition(ctx, updated, &card.ColumnID, &newColumnID, userID)
+		recordActivity(ctx, card.ProjectID, userID, "moved", "card", cardID, card.Title,
+			appendChange(nil, "column", columnTitle(ctx, card.ColumnID), columnTitle(ctx, newColumnID)))
+		notifyWatchers(ctx, updated, userID, watchEvents(ctx, card, updated), card.ColumnID)
+	}
+	if len(violations) > 0 {
+		notifyWIPExceeded(ctx, target, updated, userID, violations)
+	}
+	publishBoardEvent(ctx, card.ProjectID, userID, "card.moved", updated)
+	if card.ColumnID != newColumnID {
+		fireAutomations(automationEvent{Type: "card.moved", Card: updated, Before: &card})
+	}
+	if len(changedCardFields(card, updated)) > 0 {
+		fireAutomations(automationEvent{Type: "card.updated", Card: updated, Before: &card})
+	}
+
+	resp := withLinks(ctx, userID, updated)
+	resp.WIPWarnings = violations
+	setVersionETag(c, updated.Version)
+	return c.JSON(resp)
 }
 
 func DeleteCard(c *fiber.Ctx) error {
@@ -474,11 +1061,65 @@ func DeleteCard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
 	}
 
+	// Deleting a card archives it; ?permanent=true deletes it for good
+	// and is reserved for project admins.
+	permanent := c.QueryBool("permanent")
+	required := RoleEditor
+	if permanent {
+		required = RoleAdmin
+	}
 	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
-	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+	if err != nil || !hasPermission(roleFlags, required) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	database.GetCollection("cards").DeleteOne(ctx, bson.M{"_id": cardID})
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	filter := bson.M{"_id": cardID}
+	if err := matchVersion(c, filter, card.Version); err != nil {
+		return versionError(c, err, card.Version, card)
+	}
+
+	if !permanent {
+		if card.ArchivedAt != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is already archived"})
+		}
+		if err := archiveCard(ctx, card, userID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to archive card"})
+		}
+		publishBoardEvent(ctx, card.ProjectID, userID, "card.archived", fiber.Map{"id": card.ID, "column_id": card.ColumnID})
+		return c.JSON(fiber.Map{"message": "Card archived"})
+	}
+
+	res, err := database.GetCollection("cards").DeleteOne(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete card"})
+	}
+	if res.DeletedCount == 0 {
+		var current models.Card
+		if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+		}
+		return versionConflict(c, current.Version, current)
+	}
+	deleteCardData(ctx, []primitive.ObjectID{cardID})
+	if card.ArchivedAt == nil {
+		recordCardTransition(ctx, card, &card.ColumnID, nil, userID)
+	}
+	recordActivity(ctx, card.ProjectID, userID, "deleted", "card", cardID, card.Title, nil)
+	publishBoardEvent(ctx, card.ProjectID, userID, "card.deleted", fiber.Map{"id": cardID, "column_id": card.ColumnID})
 	return c.JSON(fiber.Map{"message": "Card deleted"})
 }
+
+// deleteCardData removes everything that hangs off the given cards. Callers
+// delete the card documents themselves.
+func deleteCardData(ctx context.Context, cardIDs []primitive.ObjectID) {
+	if len(cardIDs) == 0 {
+		return
+	}
+	database.GetCollection("card_comments").DeleteMany(ctx, bson.M{"card_id": bson.M{"$in": cardIDs}})
+	database.GetCollection("watches").DeleteMany(ctx, bson.M{"target_type": "card", "target_id": bson.M{"$in": cardIDs}})
+	deleteCardLinks(ctx, cardIDs)
+}
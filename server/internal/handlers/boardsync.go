@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/gofiber/websocket/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Board events are kept this long for reconnecting clients; anyone further
+// behind is told to reload the board.
+const boardEventRetention = 24 * time.Hour
+
+const (
+	// boardSendBuffer is how many messages may wait for a slow client
+	// before it is disconnected.
+	boardSendBuffer   = 256
+	boardWriteTimeout = 10 * time.Second
+)
+
+type boardEvent struct {
+	ID        primitive.ObjectID `bson:"_id"        json:"-"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"-"`
+	Seq       int64              `bson:"seq"        json:"seq"`
+	Event     string             `bson:"event"      json:"event"`
+	ActorID   primitive.ObjectID `bson:"actor_id"   json:"actor_id"`
+	Payload   string             `bson:"payload"    json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type boardClient struct {
+	conn      *websocket.Conn
+	userID    string
+	name      string
+	cardID    string
+	editing   bool
+	out       chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newBoardClient(conn *websocket.Conn, userID, name string) *boardClient {
+	c := &boardClient{
+		conn:   conn,
+		userID: userID,
+		name:   name,
+		out:    make(chan []byte, boardSendBuffer),
+		done:   make(chan struct{}),
+	}
+	go c.writeLoop()
+	return c
+}
+
+// send queues msg for the client's writer without blocking. A client whose
+// queue is full has stalled and is disconnected; it reloads the board when
+// it reconnects.
+func (c *boardClient) send(msg []byte) {
+	select {
+	case <-c.done:
+	case c.out <- msg:
+	default:
+		c.close()
+	}
+}
+
+func (c *boardClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+func (c *boardClient) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.out:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(boardWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+type boardRoom struct {
+	clients map[*websocket.Conn]*boardClient
+	mu      sync.RWMutex
+}
+
+var boardRooms = struct {
+	m  map[string]*boardRoom
+	mu sync.RWMutex
+}{m: make(map[string]*boardRoom)}
+
+// boardPublishLocks serialises publishing per project so clients receive
+// events in sequence order.
+var boardPublishLocks sync.Map
+
+func boardPublishLock(projectID primitive.ObjectID) *sync.Mutex {
+	mu, _ := boardPublishLocks.LoadOrStore(projectID.Hex(), &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// joinBoardRoom adds client to the project's room, creating it if needed.
+// Joining and leaving hold boardRooms.mu throughout, so a client never
+// joins a room that is being removed.
+func joinBoardRoom(projectID string, client *boardClient) *boardRoom {
+	boardRooms.mu.Lock()
+	defer boardRooms.mu.Unlock()
+	room, ok := boardRooms.m[projectID]
+	if !ok {
+		room = &boardRoom{clients: make(map[*websocket.Conn]*boardClient)}
+		boardRooms.m[projectID] = room
+	}
+	room.mu.Lock()
+	room.clients[client.conn] = client
+	room.mu.Unlock()
+	return room
+}
+
+// leaveBoardRoom removes client and drops the room once it is empty.
+func leaveBoardRoom(projectID string, room *boardRoom, client *boardClient) bool {
+	boardRooms.mu.Lock()
+	defer boardRooms.mu.Unlock()
+	room.mu.Lock()
+	delete(room.clients, client.conn)
+	empty := len(room.clients) == 0
+	room.mu.Unlock()
+	if empty && boardRooms.m[projectID] == room {
+		delete(boardRooms.m, projectID)
+	}
+	return empty
+}
+
+func findBoardRoom(projectID string) *boardRoom {
+	boardRooms.mu.RLock()
+	defer boardRooms.mu.RUnlock()
+	return boardRooms.m[projectID]
+}
+
+func (r *boardRoom) broadcast(msg []byte) {
+	r.mu.RLock()
+	clients := make([]*boardClient, 0, len(r.clients))
+	for _, c := range r.clients {
+		clients = append(clients, c)
+	}
+	r.mu.RUnlock()
+
+	for _, c := range clients {
+		c.send(msg)
+	}
+}
+
+func (r *boardRoom) presence() []map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]map[string]interface{}, 0, len(r.clients))
+	for _, c := range r.clients {
+		entry := map[string]interface{}{"user_id": c.userID, "name": c.name, "editing": c.editing}
+		if c.cardID != "" {
+			entry["card_id"] = c.cardID
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+func (r *boardRoom) broadcastPresence() {
+	msg, _ := json.Marshal(map[string]interface{}{"type": "presence", "users": r.presence()})
+	r.broadcast(msg)
+}
+
+func EnsureBoardEventIndexes(ctx context.Context) {
+	_, err := database.GetCollection("board_events").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "project_id", Value: 1}, {Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(boardEventRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		log.Printf("EnsureBoardEventIndexes: %v", err)
+	}
+}
+
+func currentBoardSeq(ctx context.Context, projectID primitive.ObjectID) int64 {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	database.GetCollection("board_sequences").FindOne(ctx, bson.M{"_id": projectID}).Decode(&counter)
+	return counter.Seq
+}
+
+// publishBoardEvent records a board change under the project's next
+// sequence number and pushes it to everyone viewing the board. The publish
+// lock only covers numbering, storing and queueing the event; queueing
+// never blocks, and each client's writer does the socket write.
+func publishBoardEvent(ctx context.Context, projectID, actorID primitive.ObjectID, event string, data interface{}) {
+	// Payloads are stored as the JSON clients receive so a replay is
+	// byte-for-byte the same as the live event.
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("publishBoardEvent: %v", err)
+		return
+	}
+
+	mu := boardPublishLock(projectID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err = database.GetCollection("board_sequences").FindOneAndUpdate(ctx,
+		bson.M{"_id": projectID},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		log.Printf("publishBoardEvent: %v", err)
+		return
+	}
+
+	e := boardEvent{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		Seq:       counter.Seq,
+		Event:     event,
+		ActorID:   actorID,
+		Payload:   string(payload),
+		CreatedAt: time.Now(),
+	}
+	database.GetCollection("board_events").InsertOne(ctx, e)
+
+	if room := findBoardRoom(projectID.Hex()); room != nil {
+		room.broadcast(boardEventMessage(e))
+	}
+}
+
+func boardEventMessage(e boardEvent) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":       "event",
+		"seq":        e.Seq,
+		"event":      e.Event,
+		"actor_id":   e.ActorID,
+		"data":       json.RawMessage(e.Payload),
+		"created_at": e.CreatedAt,
+	})
+	return msg
+}
+
+// replayBoardEvents sends a reconnecting client every event after since,
+// or asks it to reload when some of them have already expired.
+func replayBoardEvents(ctx context.Context, client *boardClient, projectID primitive.ObjectID, since int64) {
+	current := currentBoardSeq(ctx, projectID)
+	if since >= current {
+		return
+	}
+
+	cursor, err := database.GetCollection("board_events").Find(ctx,
+		bson.M{"project_id": projectID, "seq": bson.M{"$gt": since}},
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var events []boardEvent
+	cursor.All(ctx, &events)
+	// A replay that would not fit the send queue is replaced by a reload.
+	if len(events) == 0 || events[0].Seq != since+1 || len(events) > boardSendBuffer/2 {
+		msg, _ := json.Marshal(map[string]interface{}{"type": "resync", "seq": current})
+		client.send(msg)
+		return
+	}
+
+	for _, e := range events {
+		client.send(boardEventMessage(e))
+	}
+}
+
+// BoardWS streams card and column changes for a project's board. Clients
+// pass the seq from their last board load (or event) as ?since= to receive
+// what they missed, and send {"type":"presence","card_id":"...","editing":true}
+// to show which card they are looking at.
+func BoardWS(c *websocket.Conn) {
+	projectHex := c.Params("id")
+	tokenStr := c.Query("token", "")
+	userName := c.Query("name", "Anonymous")
+
+	userID, _, ok := parseWSToken(tokenStr)
+	if !ok {
+		_ = c.WriteJSON(map[string]string{"type": "error", "payload": "unauthorized"})
+		_ = c.Close()
+		return
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(projectHex)
+	userOID, uerr := primitive.ObjectIDFromHex(userID)
+	if err != nil || uerr != nil {
+		_ = c.WriteJSON(map[string]string{"type": "error", "payload": "invalid project"})
+		_ = c.Close()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if _, err := getProjectRole(ctx, projectID, userOID); err != nil {
+		cancel()
+		_ = c.WriteJSON(map[string]string{"type": "error", "payload": "access denied"})
+		_ = c.Close()
+		return
+	}
+
+	client := newBoardClient(c, userID, userName)
+
+	// Hold the publish lock so no event slips between the replay and
+	// joining the room.
+	mu := boardPublishLock(projectID)
+	mu.Lock()
+	room := joinBoardRoom(projectHex, client)
+
+	if raw := c.Query("since", ""); raw != "" {
+		if since, err := parseIntFromString(raw); err == nil {
+			replayBoardEvents(ctx, client, projectID, since)
+		}
+	}
+	hello, _ := json.Marshal(map[string]interface{}{"type": "hello", "seq": currentBoardSeq(ctx, projectID)})
+	client.send(hello)
+	mu.Unlock()
+	cancel()
+
+	room.broadcastPresence()
+
+	defer func() {
+		client.close()
+		if !leaveBoardRoom(projectHex, room, client) {
+			room.broadcastPresence()
+		}
+	}()
+
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				log.Printf("WS error project board=%s user=%s: %v", projectHex, userID, err)
+			}
+			break
+		}
+
+		var incoming struct {
+			Type    string `json:"type"`
+			CardID  string `json:"card_id"`
+			Editing bool   `json:"editing"`
+		}
+		if json.Unmarshal(msg, &incoming) != nil {
+			continue
+		}
+
+		switch incoming.Type {
+		case "presence":
+			room.mu.Lock()
+			client.cardID = incoming.CardID
+			client.editing = incoming.Editing && incoming.CardID != ""
+			room.mu.Unlock()
+			room.broadcastPresence()
+		case "ping":
+			client.send([]byte(`{"type":"pong"}`))
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxBulkCards = 500
+
+var (
+	transactionsOnce      sync.Once
+	transactionsSupported bool
+)
+
+// supportsTransactions reports whether the server is a replica set or
+// sharded cluster. Standalone servers reject multi-document transactions.
+func supportsTransactions(ctx context.Context) bool {
+	transactionsOnce.Do(func() {
+		var hello struct {
+			SetName string `bson:"setName"`
+			Msg     string `bson:"msg"`
+		}
+		if err := database.DB.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err == nil {
+			transactionsSupported = hello.SetName != "" || hello.Msg == "isdbgrid"
+		}
+	})
+	return transactionsSupported
+}
+
+// runInTransaction runs fn inside a transaction when the server supports
+// one, and directly otherwise. It reports which of the two happened.
+func runInTransaction(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	if !supportsTransactions(ctx) {
+		return false, fn(ctx)
+	}
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return false, fn(ctx)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return true, err
+}
+
+type bulkResult struct {
+	CardID primitive.ObjectID `json:"card_id"`
+	Status string             `json:"status"`
+	Error  string             `json:"error,omitempty"`
+
+	// automation is fired once the batch has committed.
+	automation *automationEvent
+}
+
+// mergeStrings applies a set, add or remove edit to a list.
+func mergeStrings(current, values []string, mode string) []string {
+	switch mode {
+	case "add":
+		out := append([]string{}, current...)
+		for _, v := range values {
+			if !containsString(out, v) {
+				out = append(out, v)
+			}
+		}
+		return out
+	case "remove":
+		out := []string{}
+		for _, v := range current {
+			if !containsString(values, v) {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	return values
+}
+
+func mergeObjectIDs(current, values []primitive.ObjectID, mode string) []primitive.ObjectID {
+	toHex := func(ids []primitive.ObjectID) []string {
+		out := []string{}
+		for _, id := range ids {
+			out = append(out, id.Hex())
+		}
+		return out
+	}
+	out := []primitive.ObjectID{}
+	for _, hex := range mergeStrings(toHex(current), toHex(values), mode) {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		out = append(out, id)
+	}
+	return out
+}
+
+// BulkCards applies one operation to many cards of a project, picked by
+// card_ids, a board query, or both. Permissions are checked once; each card
+// then gets its own result. On servers that support transactions a database
+// error rolls the whole batch back.
+func BulkCards(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		Op        string   `json:"op"`
+		CardIDs   []string `json:"card_ids"`
+		Query     string   `json:"query"`
+		ColumnID  string   `json:"column_id"`
+		Priority  string   `json:"priority"`
+		Assignees []string `json:"assignees"`
+		LabelIDs  []string `json:"label_ids"`
+		DueDate   *string  `json:"due_date"`
+		Mode      string   `json:"mode"`
+		Force     bool     `json:"force"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if body.CardIDs == nil && body.Query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Give card_ids or a query"})
+	}
+	if len(body.CardIDs) > maxBulkCards {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many cards; the limit is 500"})
+	}
+	if body.Mode == "" {
+		body.Mode = "set"
+	}
+	if body.Mode != "set" && body.Mode != "add" && body.Mode != "remove" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mode must be one of set, add, remove"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// delete removes cards for good, like DeleteCard with ?permanent=true;
+	// op=archive is the editor-level equivalent of a plain delete.
+	required := RoleEditor
+	if body.Op == "delete" {
+		required = RoleAdmin
+	}
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, required) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var target models.BoardColumn
+	var labelIDs []primitive.ObjectID
+	var dueDate *time.Time
+	switch body.Op {
+	case "move":
+		columnID, err := primitive.ObjectIDFromHex(body.ColumnID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid column_id"})
+		}
+		if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID, "archived_at": nil}).Decode(&target); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+		}
+	case "priority":
+		if body.Priority == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "priority is required"})
+		}
+	case "assignees":
+		if body.Assignees == nil {
+			body.Assignees = []string{}
+		}
+	case "labels":
+		if labelIDs, err = resolveLabelIDs(ctx, projectID, body.LabelIDs); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown label in label_ids"})
+		}
+	case "due_date":
+		if body.DueDate == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "due_date is required (empty to clear)"})
+		}
+		if *body.DueDate != "" {
+			parsed, err := time.Parse("2006-01-02", *body.DueDate)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "due_date must be YYYY-MM-DD"})
+			}
+			dueDate = &parsed
+		}
+	case "restore":
+		if body.ColumnID != "" {
+			columnID, err := primitive.ObjectIDFromHex(body.ColumnID)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid column_id"})
+			}
+			if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID, "project_id": projectID, "archived_at": nil}).Decode(&target); err != nil {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+			}
+		}
+	case "archive", "delete":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "op must be one of move, priority, assignees, labels, due_date, archive, restore, delete"})
+	}
+
+	filter := bson.M{}
+	if body.Query != "" {
+		if filter, err = compileBoardQuery(ctx, body.Query, newQueryScope(ctx, userID, []primitive.ObjectID{projectID})); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid query: " + err.Error()})
+		}
+	}
+	filter["project_id"] = projectID
+	switch body.Op {
+	case "restore":
+		filter["archived_at"] = bson.M{"$ne": nil}
+	case "delete":
+		// Archived cards can be deleted too.
+	default:
+		filter["archived_at"] = nil
+	}
+
+	results := []bulkResult{}
+	if body.CardIDs != nil {
+		ids := []primitive.ObjectID{}
+		for _, raw := range body.CardIDs {
+			id, err := primitive.ObjectIDFromHex(raw)
+			if err != nil {
+				results = append(results, bulkResult{Status: "failed", Error: "Invalid card ID"})
+				continue
+			}
+			ids = append(ids, id)
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	cards := findCards(ctx, filter, options.Find().SetSort(rankSort).SetLimit(maxBulkCards+1))
+	if len(cards) > maxBulkCards {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many cards; the limit is 500"})
+	}
+	if body.CardIDs != nil {
+		found := map[string]bool{}
+		for _, card := range cards {
+			found[card.ID.Hex()] = true
+		}
+		for _, raw := range body.CardIDs {
+			if id, err := primitive.ObjectIDFromHex(raw); err == nil && !found[raw] {
+				results = append(results, bulkResult{CardID: id, Status: "failed", Error: "Card not found in this project"})
+			}
+		}
+	}
+
+	errAbort := errors.New("bulk operation aborted")
+	var cardResults []bulkResult
+	transactional, err := runInTransaction(ctx, func(ctx context.Context) error {
+		cardResults = cardResults[:0]
+		for _, card := range cards {
+			res := applyBulkOp(ctx, userID, card, body.Op, target, body.Priority, body.Assignees, labelIDs, dueDate, body.Mode, body.Force)
+			if res.Status == "error" {
+				if supportsTransactions(ctx) {
+					return errAbort
+				}
+				res.Status = "failed"
+			}
+			cardResults = append(cardResults, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Bulk operation failed; no cards were changed"})
+	}
+	results = append(results, cardResults...)
+	publishBulkEvents(ctx, projectID, userID, body.Op, cardResults)
+	for _, r := range cardResults {
+		if r.automation != nil {
+			fireAutomations(*r.automation)
+		}
+	}
+
+	counts := map[string]int{"ok": 0, "skipped": 0, "failed": 0}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	return c.JSON(fiber.Map{
+		"op":            body.Op,
+		"transactional": transactional,
+		"succeeded":     counts["ok"],
+		"skipped":       counts["skipped"],
+		"failed":        counts["failed"],
+		"results":       results,
+	})
+}
+
+var bulkEvents = map[string]string{
+	"archive": "card.archived",
+	"restore": "card.restored",
+	"delete":  "card.deleted",
+	"move":    "card.moved",
+}
+
+// publishBulkEvents tells live boards about the cards a bulk operation
+// changed. It runs after the transaction commits so an aborted batch is
+// never broadcast.
+func publishBulkEvents(ctx context.Context, projectID, userID primitive.ObjectID, op string, results []bulkResult) {
+	ids := []primitive.ObjectID{}
+	for _, r := range results {
+		if r.Status == "ok" {
+			ids = append(ids, r.CardID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	event, ok := bulkEvents[op]
+	if !ok {
+		event = "card.updated"
+	}
+	if op == "delete" {
+		for _, id := range ids {
+			publishBoardEvent(ctx, projectID, userID, event, fiber.Map{"id": id})
+		}
+		return
+	}
+	for _, card := range findCards(ctx, bson.M{"_id": bson.M{"$in": ids}}) {
+		if op == "archive" {
+			publishBoardEvent(ctx, projectID, userID, event, fiber.Map{"id": card.ID, "column_id": card.ColumnID})
+			continue
+		}
+		publishBoardEvent(ctx, projectID, userID, event, card)
+	}
+}
+
+// applyBulkOp changes one card. A rule that stops this card (WIP limit,
+// blockers) fails it alone; "error" means the database write failed.
+func applyBulkOp(ctx context.Context, userID primitive.ObjectID, card models.Card, op string, target models.BoardColumn, priority string, assignees []string, labelIDs []primitive.ObjectID, dueDate *time.Time, mode string, force bool) bulkResult {
+	res := bulkResult{CardID: card.ID, Status: "ok"}
+	col := database.GetCollection("cards")
+
+	switch op {
+	case "archive":
+		if err := archiveCard(ctx, card, userID); err != nil {
+			return bulkResult{CardID: card.ID, Status: "error", Error: "Failed to archive card"}
+		}
+		return res
+	case "restore":
+		column, ok := target, !target.ID.IsZero()
+		if !ok {
+			column, ok = restoreTarget(ctx, card, "")
+		}
+		if !ok {
+			return bulkResult{CardID: card.ID, Status: "failed", Error: "The card's column is archived or gone; give column_id"}
+		}
+		if violations := checkWIP(ctx, column, card); len(violations) > 0 && wipBlocks(column) && !force {
+			return bulkResult{CardID: card.ID, Status: "failed", Error: "WIP limit reached"}
+		}
+		if _, err := restoreCard(ctx, card, column, userID); err != nil {
+			return bulkResult{CardID: card.ID, Status: "error", Error: "Failed to restore card"}
+		}
+		return res
+	}
+
+	if op == "delete" {
+		if _, err := col.DeleteOne(ctx, bson.M{"_id": card.ID}); err != nil {
+			return bulkResult{CardID: card.ID, Status: "error", Error: "Failed to delete card"}
+		}
+		deleteCardData(ctx, []primitive.ObjectID{card.ID})
+		if card.ArchivedAt == nil {
+			recordCardTransition(ctx, card, &card.ColumnID, nil, userID)
+		}
+		recordActivity(ctx, card.ProjectID, userID, "deleted", "card", card.ID, card.Title, nil)
+		return res
+	}
+
+	update := bson.M{}
+	switch op {
+	case "move":
+		if card.ColumnID == target.ID {
+			return bulkResult{CardID: card.ID, Status: "skipped"}
+		}
+		if !force && isDoneColumn(target) && !columnIsDone(ctx, card.ColumnID) {
+			if blockers := unresolvedBlockers(ctx, userID, card.ID); len(blockers) > 0 {
+				return bulkResult{CardID: card.ID, Status: "failed", Error: "Card is blocked by unfinished cards"}
+			}
+		}
+		if violations := checkWIP(ctx, target, card); len(violations) > 0 && wipBlocks(target) {
+			return bulkResult{CardID: card.ID, Status: "failed", Error: "WIP limit reached"}
+		}
+		update["column_id"] = target.ID
+		update["rank"] = rankAt(ctx, "cards", bson.M{"column_id": target.ID}, card.ID, -1)
+	case "priority":
+		update["priority"] = priority
+	case "assignees":
+		update["assignees"] = mergeStrings(card.Assignees, assignees, mode)
+	case "labels":
+		update["label_ids"] = mergeObjectIDs(card.LabelIDs, labelIDs, mode)
+	case "due_date":
+		update["due_date"] = dueDate
+	}
+	update["updated_at"] = time.Now()
+
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{"$set": update, "$inc": bumpVersion}); err != nil {
+		return bulkResult{CardID: card.ID, Status: "error", Error: "Failed to update card"}
+	}
+
+	var updated models.Card
+	col.FindOne(ctx, bson.M{"_id": card.ID}).Decode(&updated)
+
+	if op == "move" {
+		recordCardTransition(ctx, updated, &card.ColumnID, &target.ID, userID)
+		recordActivity(ctx, card.ProjectID, userID, "moved", "card", card.ID, card.Title,
+			appendChange(nil, "column", columnTitle(ctx, card.ColumnID), target.Title))
+		notifyWatchers(ctx, updated, userID, watchEvents(ctx, card, updated), card.ColumnID)
+		res.automation = &automationEvent{Type: "card.moved", Card: updated, Before: &card}
+		return res
+	}
+
+	changes := cardChanges(card, updated)
+	if op == "labels" {
+		changes = appendChange(changes, "labels", labelNames(ctx, card.LabelIDs), labelNames(ctx, updated.LabelIDs))
+	}
+	if len(changes) == 0 {
+		res.Status = "skipped"
+		return res
+	}
+	recordActivity(ctx, card.ProjectID, userID, "updated", "card", card.ID, card.Title, changes)
+	notifyWatchers(ctx, updated, userID, watchEvents(ctx, card, updated))
+	if len(changedCardFields(card, updated)) > 0 {
+		res.automation = &automationEvent{Type: "card.updated", Card: updated, Before: &card}
+	}
+
+	if op == "assignees" {
+		autoWatchCard(ctx, updated)
+		for _, email := range updated.Assignees {
+			if containsString(card.Assignees, email) {
+				continue
+			}
+			var assignee models.User
+			if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&assignee); err != nil || assignee.ID == userID {
+				continue
+			}
+			createNotification(ctx, assignee.ID, "assign",
+				"You have been assigned to the task \""+updated.Title+"\"",
+				updated.ProjectID, updated.ID)
+		}
+	}
+	return res
+}
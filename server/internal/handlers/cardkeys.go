@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Every project has a short key (WEB) and numbers its cards from a counter
+// on the project document, giving cards keys like WEB-142. A renamed
+// project keeps its old keys in former_keys so earlier references still
+// resolve, and no other project may take them.
+
+var (
+	projectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+	cardKeyExact      = regexp.MustCompile(`^([A-Z][A-Z0-9]{1,9})-([1-9][0-9]{0,8})$`)
+	cardKeyPattern    = regexp.MustCompile(`\b([A-Z][A-Z0-9]{1,9})-([1-9][0-9]{0,8})\b`)
+)
+
+// maxCardRefs bounds how many distinct keys are resolved in one text.
+const maxCardRefs = 100
+
+type cardRef struct {
+	Key       string             `json:"key"`
+	CardID    primitive.ObjectID `json:"card_id"`
+	ProjectID primitive.ObjectID `json:"project_id"`
+	Title     string             `json:"title"`
+	Archived  bool               `json:"archived,omitempty"`
+}
+
+func cardKey(projectKey string, number int) string {
+	return projectKey + "-" + strconv.Itoa(number)
+}
+
+func normalizeProjectKey(raw string) (string, bool) {
+	key := strings.ToUpper(strings.TrimSpace(raw))
+	return key, projectKeyPattern.MatchString(key)
+}
+
+func parseCardKey(raw string) (string, int, bool) {
+	m := cardKeyExact.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(raw)))
+	if m == nil {
+		return "", 0, false
+	}
+	n, _ := strconv.Atoi(m[2])
+	return m[1], n, true
+}
+
+// deriveProjectKey suggests a key from a project name: the initials of a
+// multi-word name, otherwise its first three letters.
+func deriveProjectKey(name string) string {
+	words := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+
+	key := ""
+	if len(words) > 1 {
+		for _, w := range words {
+			key += w[:1]
+			if len(key) == 4 {
+				break
+			}
+		}
+	} else if len(words) == 1 {
+		key = words[0]
+		if len(key) > 3 {
+			key = key[:3]
+		}
+	}
+	if key != "" && (key[0] < 'A' || key[0] > 'Z') {
+		key = "P" + key
+	}
+	if len(key) < 2 {
+		key = "PRJ"
+	}
+	return key
+}
+
+func projectKeyTaken(ctx context.Context, key string, except primitive.ObjectID) bool {
+	n, _ := database.GetCollection("projects").CountDocuments(ctx, bson.M{
+		"_id": bson.M{"$ne": except},
+		"$or": bson.A{bson.M{"key": key}, bson.M{"former_keys": key}},
+	})
+	return n > 0
+}
+
+// availableProjectKey returns base, or base with the lowest number appended
+// that no project uses.
+func availableProjectKey(ctx context.Context, base string) string {
+	if !projectKeyTaken(ctx, base, primitive.NilObjectID) {
+		return base
+	}
+	for i := 2; ; i++ {
+		suffix := strconv.Itoa(i)
+		prefix := base
+		if len(prefix)+len(suffix) > 10 {
+			prefix = prefix[:10-len(suffix)]
+		}
+		if key := prefix + suffix; !projectKeyTaken(ctx, key, primitive.NilObjectID) {
+			return key
+		}
+	}
+}
+
+// chooseProjectKey validates a requested key, or derives one from the
+// project name when none is given. It returns a client-facing error message.
+func chooseProjectKey(ctx context.Context, raw, name string) (string, string) {
+	if strings.TrimSpace(raw) == "" {
+		return availableProjectKey(ctx, deriveProjectKey(name)), ""
+	}
+	key, ok := normalizeProjectKey(raw)
+	if !ok {
+		return "", "Key must be 2 to 10 letters or digits, starting with a letter"
+	}
+	if projectKeyTaken(ctx, key, primitive.NilObjectID) {
+		return "", "Project key already in use"
+	}
+	return key, ""
+}
+
+// ensureProjectKey gives a project created before keys existed one derived
+// from its name, and returns the project's key.
+func ensureProjectKey(ctx context.Context, project models.Project) string {
+	col := database.GetCollection("projects")
+	for attempt := 0; attempt < 3 && project.Key == ""; attempt++ {
+		key := availableProjectKey(ctx, deriveProjectKey(project.Name))
+		_, err := col.UpdateOne(ctx, bson.M{"_id": project.ID, "key": nil}, bson.M{"$set": bson.M{"key": key}})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return ""
+		}
+		col.FindOne(ctx, bson.M{"_id": project.ID}).Decode(&project)
+	}
+	return project.Key
+}
+
+// nextCardKey takes the next number from the project's counter. The
+// increment is atomic, so concurrent creates never share a number.
+func nextCardKey(ctx context.Context, projectID primitive.ObjectID) (int, string) {
+	var project models.Project
+	err := database.GetCollection("projects").FindOneAndUpdate(ctx,
+		bson.M{"_id": projectID},
+		bson.M{"$inc": bson.M{"card_seq": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&project)
+	if err != nil {
+		return 0, ""
+	}
+	key := ensureProjectKey(ctx, project)
+	if key == "" {
+		return 0, ""
+	}
+	return project.CardSeq, cardKey(key, project.CardSeq)
+}
+
+// rekeyCards rewrites the keys of a project's cards after its key changed.
+func rekeyCards(ctx context.Context, projectID primitive.ObjectID, key string) {
+	database.GetCollection("cards").UpdateMany(ctx,
+		bson.M{"project_id": projectID, "number": bson.M{"$gt": 0}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"key":     bson.M{"$concat": bson.A{key, "-", bson.M{"$toString": "$number"}}},
+			"version": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+		}}}},
+	)
+}
+
+// cardKeyBatch is how many card keys BackfillCardKeys writes per request.
+const cardKeyBatch = 1000
+
+// EnsureCardKeys creates the key indexes. Existing projects and cards get
+// their keys from BackfillCardKeys.
+func EnsureCardKeys(ctx context.Context) {
+	hasKey := bson.M{"key": bson.M{"$type": "string"}}
+	_, err := database.GetCollection("projects").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(hasKey),
+	})
+	if err != nil {
+		log.Printf("EnsureCardKeys: %v", err)
+	}
+	_, err = database.GetCollection("cards").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(hasKey),
+		},
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "number", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("EnsureCardKeys: %v", err)
+	}
+}
+
+// BackfillCardKeys gives projects created before keys existed a key and
+// numbers their cards, oldest first. It only touches what is still
+// missing, so an interrupted run picks up where it stopped.
+func BackfillCardKeys(ctx context.Context) {
+	var keyless []models.Project
+	if cursor, err := database.GetCollection("projects").Find(ctx, bson.M{"key": nil}); err == nil {
+		cursor.All(ctx, &keyless)
+		cursor.Close(ctx)
+	}
+	for _, project := range keyless {
+		ensureProjectKey(ctx, project)
+	}
+
+	projectIDs, err := database.GetCollection("cards").Distinct(ctx, "project_id", bson.M{"number": bson.M{"$exists": false}})
+	if err != nil {
+		log.Printf("BackfillCardKeys: %v", err)
+		return
+	}
+	for _, v := range projectIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if projectID, ok := v.(primitive.ObjectID); ok {
+			backfillProjectCardKeys(ctx, projectID)
+		}
+	}
+}
+
+// backfillProjectCardKeys reserves one block of numbers for all of the
+// project's unnumbered cards with a single increment and assigns them in
+// bulk, in creation order.
+func backfillProjectCardKeys(ctx context.Context, projectID primitive.ObjectID) {
+	cards := database.GetCollection("cards")
+	backlog := findCards(ctx, bson.M{"project_id": projectID, "number": bson.M{"$exists": false}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).SetProjection(bson.M{"_id": 1}))
+	if len(backlog) == 0 {
+		return
+	}
+
+	var project models.Project
+	err := database.GetCollection("projects").FindOneAndUpdate(ctx,
+		bson.M{"_id": projectID},
+		bson.M{"$inc": bson.M{"card_seq": len(backlog)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&project)
+	if err != nil {
+		return
+	}
+	key := ensureProjectKey(ctx, project)
+	if key == "" {
+		return
+	}
+
+	first := project.CardSeq - len(backlog) + 1
+	batch := make([]mongo.WriteModel, 0, cardKeyBatch)
+	for i, card := range backlog {
+		number := first + i
+		batch = append(batch, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": card.ID, "number": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"number": number, "key": cardKey(key, number)}}))
+		if len(batch) == cardKeyBatch || i == len(backlog)-1 {
+			if _, err := cards.BulkWrite(ctx, batch); err != nil {
+				log.Printf("BackfillCardKeys: project %s: %v", projectID.Hex(), err)
+				return
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+// findCardByKey looks a card up by its key, falling back to the project's
+// former keys.
+func findCardByKey(ctx context.Context, prefix string, number int) (models.Card, error) {
+	var card models.Card
+	cards := database.GetCollection("cards")
+	if err := cards.FindOne(ctx, bson.M{"key": cardKey(prefix, number)}).Decode(&card); err == nil {
+		return card, nil
+	}
+	var project models.Project
+	if err := database.GetCollection("projects").FindOne(ctx, bson.M{"former_keys": prefix}).Decode(&project); err != nil {
+		return card, err
+	}
+	err := cards.FindOne(ctx, bson.M{"project_id": project.ID, "number": number}).Decode(&card)
+	return card, err
+}
+
+// resolveCardRefs finds the cards that card keys in texts refer to, among
+// projectIDs. The result is keyed by the key as written.
+func resolveCardRefs(ctx context.Context, projectIDs []primitive.ObjectID, texts ...string) map[string]cardRef {
+	refs := map[string]cardRef{}
+	byPrefix := map[string][]int{}
+	keys := []string{}
+	for _, text := range texts {
+		for _, m := range cardKeyPattern.FindAllStringSubmatch(text, -1) {
+			if len(keys) >= maxCardRefs || containsString(keys, m[0]) {
+				continue
+			}
+			n, _ := strconv.Atoi(m[2])
+			keys = append(keys, m[0])
+			byPrefix[m[1]] = append(byPrefix[m[1]], n)
+		}
+	}
+	if len(keys) == 0 || len(projectIDs) == 0 {
+		return refs
+	}
+
+	add := func(key string, card models.Card) {
+		refs[key] = cardRef{Key: key, CardID: card.ID, ProjectID: card.ProjectID, Title: card.Title, Archived: card.ArchivedAt != nil}
+	}
+	for _, card := range findCards(ctx, bson.M{"key": bson.M{"$in": keys}, "project_id": bson.M{"$in": projectIDs}}) {
+		add(card.Key, card)
+	}
+	if len(refs) == len(keys) {
+		return refs
+	}
+
+	// Keys written before a project was renamed.
+	prefixes := []string{}
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	var renamed []models.Project
+	cursor, err := database.GetCollection("projects").Find(ctx, bson.M{
+		"_id":         bson.M{"$in": projectIDs},
+		"former_keys": bson.M{"$in": prefixes},
+	})
+	if err != nil {
+		return refs
+	}
+	cursor.All(ctx, &renamed)
+	cursor.Close(ctx)
+
+	for _, project := range renamed {
+		for _, prefix := range project.FormerKeys {
+			numbers := byPrefix[prefix]
+			if len(numbers) == 0 {
+				continue
+			}
+			for _, card := range findCards(ctx, bson.M{"project_id": project.ID, "number": bson.M{"$in": numbers}}) {
+				add(cardKey(prefix, card.Number), card)
+			}
+		}
+	}
+	return refs
+}
+
+// cardRefsIn picks the refs mentioned in text, in order of appearance.
+func cardRefsIn(refs map[string]cardRef, text string) []cardRef {
+	var found []cardRef
+	seen := map[string]bool{}
+	for _, key := range cardKeyPattern.FindAllString(text, -1) {
+		ref, ok := refs[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		found = append(found, ref)
+	}
+	return found
+}
+
+// teamProjectIDs returns the projects of a team, which every team member
+// can see; chat and docs resolve card keys among them.
+func teamProjectIDs(ctx context.Context, teamID primitive.ObjectID) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	distinct, err := database.GetCollection("projects").Distinct(ctx, "_id", bson.M{"team_id": teamID})
+	if err != nil {
+		return ids
+	}
+	for _, v := range distinct {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func GetCardByKey(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	prefix, number, ok := parseCardKey(c.Params("key"))
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card key"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, err := findCardByKey(ctx, prefix, number)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch card"})
+	}
+
+	if _, err := getProjectRole(ctx, card.ProjectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	setVersionETag(c, card.Version)
+	return c.JSON(withLinks(ctx, userID, card))
+}
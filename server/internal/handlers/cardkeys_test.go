@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCardKey(t *testing.T) {
+	tests := []struct {
+		raw        string
+		wantPrefix string
+		wantNumber int
+		wantOK     bool
+	}{
+		{"WEB-142", "WEB", 142, true},
+		{" web-7 ", "WEB", 7, true},
+		{"A1-1", "A1", 1, true},
+		{"ABCDEFGHIJ-999999999", "ABCDEFGHIJ", 999999999, true},
+		{"WEB-0", "", 0, false},
+		{"WEB-012", "", 0, false},
+		{"W-1", "", 0, false},
+		{"1AB-1", "", 0, false},
+		{"ABCDEFGHIJK-1", "", 0, false},
+		{"WEB-1234567890", "", 0, false},
+		{"WEB 1", "", 0, false},
+		{"WEB-1x", "", 0, false},
+		{"", "", 0, false},
+	}
+	for _, tt := range tests {
+		prefix, number, ok := parseCardKey(tt.raw)
+		if prefix != tt.wantPrefix || number != tt.wantNumber || ok != tt.wantOK {
+			t.Errorf("parseCardKey(%q) = %q, %d, %v; want %q, %d, %v",
+				tt.raw, prefix, number, ok, tt.wantPrefix, tt.wantNumber, tt.wantOK)
+		}
+	}
+}
+
+func TestDeriveProjectKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Website", "WEB"},
+		{"Web app", "WA"},
+		{"customer-facing mobile app redesign", "CFMA"},
+		{"Q3 Launch", "QL"},
+		{"2026 roadmap", "P2R"},
+		{"2026", "P202"},
+		{"Go", "GO"},
+		{"X", "PRJ"},
+		{"", "PRJ"},
+		{"!!!", "PRJ"},
+	}
+	for _, tt := range tests {
+		if got := deriveProjectKey(tt.name); got != tt.want {
+			t.Errorf("deriveProjectKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeProjectKey(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{" web ", "WEB", true},
+		{"ops2", "OPS2", true},
+		{"w", "W", false},
+		{"2OPS", "2OPS", false},
+		{"WEB-1", "WEB-1", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeProjectKey(tt.raw)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("normalizeProjectKey(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCardKeyPattern(t *testing.T) {
+	text := "Fixed in WEB-12 and api-3, see OPS-4, WEB-12 again; not X-1 or WEB-012 or MYWEB-5x"
+	got := cardKeyPattern.FindAllString(text, -1)
+	want := []string{"WEB-12", "OPS-4", "WEB-12"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cardKeyPattern found %v, want %v", got, want)
+	}
+}
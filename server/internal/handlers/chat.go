@@ -69,6 +69,13 @@ func (r *chatRoom) onlineUsers() []map[string]string {
 	return list
 }
 
+// chatMessageResponse is a message with the cards its content mentions by
+// key.
+type chatMessageResponse struct {
+	models.ChatMessage
+	CardRefs []cardRef `json:"card_refs,omitempty"`
+}
+
 func ListChatMessages(c *fiber.Ctx) error {
 	teamID := c.Params("teamId")
 	teamOID, err := primitive.ObjectIDFromHex(teamID)
@@ -116,7 +123,17 @@ func ListChatMessages(c *fiber.Ctx) error {
 		messages[i], messages[j] = messages[j], messages[i]
 	}
 
-	return c.JSON(messages)
+	contents := make([]string, len(messages))
+	for i, m := range messages {
+		contents[i] = m.Content
+	}
+	refs := resolveCardRefs(ctx, teamProjectIDs(ctx, teamOID), contents...)
+
+	result := make([]chatMessageResponse, len(messages))
+	for i, m := range messages {
+		result[i] = chatMessageResponse{ChatMessage: m, CardRefs: cardRefsIn(refs, m.Content)}
+	}
+	return c.JSON(result)
 }
 
 func parseIntFromString(s string) (int64, error) {
@@ -225,11 +242,12 @@ func TeamChatWS(c *websocket.Conn) {
 
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			_, _ = database.GetCollection("chat_messages").InsertOne(ctx, chatMsg)
+			refs := resolveCardRefs(ctx, teamProjectIDs(ctx, teamOID), content)
 			cancel()
 
 			outMsg, _ := json.Marshal(map[string]interface{}{
 				"type":    "message",
-				"message": chatMsg,
+				"message": chatMessageResponse{ChatMessage: chatMsg, CardRefs: cardRefsIn(refs, content)},
 			})
 			room.broadcastAll(outMsg)
 		} else if incoming.Type == "edit" {
@@ -248,6 +266,10 @@ func TeamChatWS(c *websocket.Conn) {
 				bson.M{"_id": msgID, "user_id": userOID, "team_id": teamOID, "deleted": bson.M{"$ne": true}},
 				bson.M{"$set": bson.M{"content": content, "edited_at": now}},
 			)
+			var refs map[string]cardRef
+			if err == nil && res.ModifiedCount > 0 {
+				refs = resolveCardRefs(ctx, teamProjectIDs(ctx, teamOID), content)
+			}
 			cancel()
 
 			if err == nil && res.ModifiedCount > 0 {
@@ -256,6 +278,7 @@ func TeamChatWS(c *websocket.Conn) {
 					"message_id": msgID.Hex(),
 					"content":    content,
 					"edited_at":  now,
+					"card_refs":  cardRefsIn(refs, content),
 				})
 				room.broadcastAll(outMsg)
 			}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// resolveMentions returns the users referenced as @email in content who can
+// access the project. Mentions of unknown users or outsiders are dropped.
+func resolveMentions(ctx context.Context, projectID primitive.ObjectID, content string) []models.User {
+	seen := map[string]bool{}
+	users := []models.User{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		email := strings.ToLower(strings.TrimRight(m[1], "."))
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		var user models.User
+		if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+			continue
+		}
+		if _, err := getProjectRole(ctx, projectID, user.ID); err != nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func mentionIDs(users []models.User) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+// notifyMentions tells each mentioned user, except the author and those in
+// skip, about a comment. Callers run it once the comment is saved.
+func notifyMentions(ctx context.Context, card models.Card, author primitive.ObjectID, users []models.User, skip []primitive.ObjectID) {
+	var authorName string
+	var u models.User
+	if err := database.GetCollection("users").FindOne(ctx, bson.M{"_id": author}).Decode(&u); err == nil {
+		authorName = u.Name
+	}
+
+	already := map[primitive.ObjectID]bool{}
+	for _, id := range skip {
+		already[id] = true
+	}
+
+	for _, user := range users {
+		if user.ID == author || already[user.ID] {
+			continue
+		}
+		createNotification(ctx, user.ID, "mention",
+			authorName+" mentioned you on \""+card.Title+"\"",
+			card.ProjectID, card.ID)
+	}
+}
+
+func ListCardComments(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleViewer) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	cursor, err := database.GetCollection("card_comments").Find(ctx,
+		bson.M{"card_id": card.ID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch comments"})
+	}
+	defer cursor.Close(ctx)
+
+	var comments []models.CardComment
+	cursor.All(ctx, &comments)
+
+	type CommentResponse struct {
+		models.CardComment
+		UserName  string `json:"user_name"`
+		AvatarURL string `json:"avatar_url,omitempty"`
+	}
+
+	users := map[primitive.ObjectID]models.User{}
+	result := []CommentResponse{}
+	for _, cm := range comments {
+		user, ok := users[cm.UserID]
+		if !ok {
+			database.GetCollection("users").FindOne(ctx, bson.M{"_id": cm.UserID}).Decode(&user)
+			users[cm.UserID] = user
+		}
+		result = append(result, CommentResponse{CardComment: cm, UserName: user.Name, AvatarURL: user.AvatarURL})
+	}
+
+	return c.JSON(result)
+}
+
+func CreateCardComment(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Content) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "content is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	mentioned := resolveMentions(ctx, card.ProjectID, body.Content)
+	comment := &models.CardComment{
+		ID:        primitive.NewObjectID(),
+		CardID:    card.ID,
+		ProjectID: card.ProjectID,
+		UserID:    userID,
+		Content:   body.Content,
+		Mentions:  mentionIDs(mentioned),
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := database.GetCollection("card_comments").InsertOne(ctx, comment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create comment"})
+	}
+	notifyMentions(ctx, card, userID, mentioned, nil)
+	recordActivity(ctx, card.ProjectID, userID, "commented", "card", card.ID, card.Title, nil)
+	autoWatchCard(ctx, card, userID)
+
+	return c.Status(fiber.StatusCreated).JSON(comment)
+}
+
+func UpdateCardComment(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Content) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "content is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	col := database.GetCollection("card_comments")
+	var comment models.CardComment
+	if err := col.FindOne(ctx, bson.M{"_id": commentID, "card_id": card.ID}).Decode(&comment); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+	if comment.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only edit your own comments"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	now := time.Now()
+	mentioned := resolveMentions(ctx, card.ProjectID, body.Content)
+	mentions := mentionIDs(mentioned)
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": commentID}, bson.M{"$set": bson.M{
+		"content":   body.Content,
+		"mentions":  mentions,
+		"edited_at": now,
+	}}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update comment"})
+	}
+	notifyMentions(ctx, card, userID, mentioned, comment.Mentions)
+
+	comment.Content = body.Content
+	comment.Mentions = mentions
+	comment.EditedAt = &now
+	return c.JSON(comment)
+}
+
+func DeleteCardComment(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Params("commentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	col := database.GetCollection("card_comments")
+	var comment models.CardComment
+	if err := col.FindOne(ctx, bson.M{"_id": commentID, "card_id": card.ID}).Decode(&comment); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+	// Editors may delete their own comments; other people's need an admin.
+	if comment.UserID != userID && !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col.DeleteOne(ctx, bson.M{"_id": commentID})
+	return c.JSON(fiber.Map{"message": "Comment deleted"})
+}
+
+// commentCounts returns the number of comments per card for a project.
+func commentCounts(ctx context.Context, projectID primitive.ObjectID) map[primitive.ObjectID]int {
+	counts := map[primitive.ObjectID]int{}
+	cursor, err := database.GetCollection("card_comments").Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"project_id": projectID}},
+		bson.M{"$group": bson.M{"_id": "$card_id", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return counts
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	cursor.All(ctx, &rows)
+	for _, r := range rows {
+		counts[r.ID] = r.Count
+	}
+	return counts
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var customFieldTypes = map[string]bool{
+	"text":         true,
+	"number":       true,
+	"date":         true,
+	"select":       true,
+	"multi_select": true,
+	"user":         true,
+	"checkbox":     true,
+	"url":          true,
+}
+
+var (
+	customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+	nonKeyChars           = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// customFieldKey derives the query key of a field from its name, e.g.
+// "Story Points" becomes story_points.
+func customFieldKey(name string) string {
+	key := strings.Trim(nonKeyChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		key = "f_" + key
+	}
+	if len(key) > 40 {
+		key = strings.TrimRight(key[:40], "_")
+	}
+	return key
+}
+
+func customFieldPath(f models.CustomField) string {
+	return "custom_fields." + f.ID.Hex()
+}
+
+func projectCustomFields(ctx context.Context, projectID primitive.ObjectID) []models.CustomField {
+	fields := []models.CustomField{}
+	cursor, err := database.GetCollection("custom_fields").Find(ctx, bson.M{"project_id": projectID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return fields
+	}
+	defer cursor.Close(ctx)
+	cursor.All(ctx, &fields)
+	return fields
+}
+
+func customFieldKeyTaken(ctx context.Context, projectID primitive.ObjectID, key string, exclude primitive.ObjectID) bool {
+	filter := bson.M{"project_id": projectID, "key": key}
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+	count, _ := database.GetCollection("custom_fields").CountDocuments(ctx, filter)
+	return count > 0
+}
+
+// normalizeCustomValue validates a JSON value against the field's type and
+// returns the form stored on the card. A nil result means "no value".
+func normalizeCustomValue(ctx context.Context, projectID primitive.ObjectID, f models.CustomField, v interface{}) (interface{}, error) {
+	if f.Type != "user" || v == nil {
+		return normalizePlainValue(f, v)
+	}
+
+	email, ok := v.(string)
+	if !ok {
+		return nil, invalidCustomValue(f)
+	}
+	if email == "" {
+		return nil, nil
+	}
+	var user models.User
+	if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+		return nil, fmt.Errorf("%s: unknown user %q", f.Name, email)
+	}
+	if _, err := getProjectRole(ctx, projectID, user.ID); err != nil {
+		return nil, fmt.Errorf("%s: %s is not a member of this project", f.Name, email)
+	}
+	return email, nil
+}
+
+func invalidCustomValue(f models.CustomField) error {
+	return fmt.Errorf("%s: expected %s", f.Name, strings.ReplaceAll(f.Type, "_", " "))
+}
+
+// normalizePlainValue is normalizeCustomValue for every type that needs no
+// lookups, i.e. all but user.
+func normalizePlainValue(f models.CustomField, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	invalid := invalidCustomValue(f)
+
+	switch f.Type {
+	case "text":
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		return s, nil
+
+	case "number":
+		n, ok := v.(float64)
+		if !ok {
+			return nil, invalid
+		}
+		return n, nil
+
+	case "date":
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if s == "" {
+			return nil, nil
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, fmt.Errorf("%s: expected a date as YYYY-MM-DD", f.Name)
+		}
+		return s, nil
+
+	case "select":
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if s == "" {
+			return nil, nil
+		}
+		for _, o := range f.Options {
+			if o == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: %q is not an option", f.Name, s)
+
+	case "multi_select":
+		raw, ok := v.([]interface{})
+		if !ok {
+			return nil, invalid
+		}
+		values := []string{}
+		seen := map[string]bool{}
+		for _, item := range raw {
+			s, ok := item.(string)
+			if !ok {
+				return nil, invalid
+			}
+			found := false
+			for _, o := range f.Options {
+				found = found || o == s
+			}
+			if !found {
+				return nil, fmt.Errorf("%s: %q is not an option", f.Name, s)
+			}
+			if !seen[s] {
+				seen[s] = true
+				values = append(values, s)
+			}
+		}
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values, nil
+
+	case "checkbox":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, invalid
+		}
+		return b, nil
+
+	case "url":
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		u, err := url.ParseRequestURI(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s: expected an http(s) URL", f.Name)
+		}
+		return s, nil
+	}
+	return nil, invalid
+}
+
+// resolveCustomFields validates submitted values, keyed by field ID or key,
+// against the project's definitions. It returns the values to set and the
+// field IDs to clear. Required fields must end up with a value: current
+// holds the card's existing values (nil when creating a card).
+func resolveCustomFields(ctx context.Context, projectID primitive.ObjectID, raw map[string]interface{}, current map[string]interface{}) (map[string]interface{}, []string, error) {
+	fields := projectCustomFields(ctx, projectID)
+	byRef := map[string]models.CustomField{}
+	for _, f := range fields {
+		byRef[f.ID.Hex()] = f
+		byRef[f.Key] = f
+	}
+
+	set := map[string]interface{}{}
+	unset := []string{}
+	for ref, v := range raw {
+		f, ok := byRef[ref]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown custom field %q", ref)
+		}
+		value, err := normalizeCustomValue(ctx, projectID, f, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if value == nil {
+			unset = append(unset, f.ID.Hex())
+		} else {
+			set[f.ID.Hex()] = value
+		}
+	}
+
+	for _, f := range fields {
+		if !f.Required {
+			continue
+		}
+		id := f.ID.Hex()
+		_, setting := set[id]
+		clearing := false
+		for _, u := range unset {
+			clearing = clearing || u == id
+		}
+		if _, has := current[id]; !setting && (clearing || !has) {
+			return nil, nil, fmt.Errorf("%s is required", f.Name)
+		}
+	}
+	return set, unset, nil
+}
+
+// customFieldChanges lists changed custom values for the activity log, keyed
+// as cf.<key> like in board queries.
+func customFieldChanges(ctx context.Context, before, after models.Card) []models.ActivityChange {
+	changes := []models.ActivityChange{}
+	for _, f := range projectCustomFields(ctx, after.ProjectID) {
+		id := f.ID.Hex()
+		from, to := before.CustomFields[id], after.CustomFields[id]
+		if fmt.Sprint(from) != fmt.Sprint(to) {
+			changes = append(changes, models.ActivityChange{Field: "cf." + f.Key, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+var errInvalidOptions = errors.New("select fields need at least one option and options must be unique")
+
+func cleanOptions(fieldType string, values []string) ([]string, error) {
+	if fieldType != "select" && fieldType != "multi_select" {
+		return nil, nil
+	}
+	cleaned := []string{}
+	seen := map[string]bool{}
+	for _, o := range values {
+		o = strings.TrimSpace(o)
+		if o == "" || seen[o] {
+			return nil, errInvalidOptions
+		}
+		seen[o] = true
+		cleaned = append(cleaned, o)
+	}
+	if len(cleaned) == 0 {
+		return nil, errInvalidOptions
+	}
+	return cleaned, nil
+}
+
+func ListCustomFields(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	return c.JSON(projectCustomFields(ctx, projectID))
+}
+
+func CreateCustomField(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		Name     string   `json:"name"`
+		Key      string   `json:"key"`
+		Type     string   `json:"type"`
+		Options  []string `json:"options"`
+		Required bool     `json:"required"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if !customFieldTypes[body.Type] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be one of text, number, date, select, multi_select, user, checkbox, url"})
+	}
+	if body.Key == "" {
+		body.Key = customFieldKey(body.Name)
+	}
+	if !customFieldKeyPattern.MatchString(body.Key) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "key must be lowercase letters, digits and underscores"})
+	}
+	fieldOptions, err := cleanOptions(body.Type, body.Options)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if customFieldKeyTaken(ctx, projectID, body.Key, primitive.NilObjectID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A field with this key already exists"})
+	}
+
+	now := time.Now()
+	field := &models.CustomField{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		Name:      body.Name,
+		Key:       body.Key,
+		Type:      body.Type,
+		Options:   fieldOptions,
+		Required:  body.Required,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if _, err := database.GetCollection("custom_fields").InsertOne(ctx, field); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create field"})
+	}
+	recordActivity(ctx, projectID, userID, "created", "custom_field", field.ID, field.Name, nil)
+
+	return c.Status(fiber.StatusCreated).JSON(field)
+}
+
+func UpdateCustomField(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	fieldID, err := primitive.ObjectIDFromHex(c.Params("fieldId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid field ID"})
+	}
+
+	var body struct {
+		Name     *string  `json:"name"`
+		Key      *string  `json:"key"`
+		Type     *string  `json:"type"`
+		Options  []string `json:"options"`
+		Required *bool    `json:"required"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("custom_fields")
+	var field models.CustomField
+	if err := col.FindOne(ctx, bson.M{"_id": fieldID, "project_id": projectID}).Decode(&field); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Field not found"})
+	}
+	before := field
+
+	if body.Type != nil && *body.Type != field.Type {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A field's type cannot be changed"})
+	}
+	if body.Name != nil {
+		if field.Name = strings.TrimSpace(*body.Name); field.Name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name cannot be empty"})
+		}
+	}
+	if body.Key != nil {
+		if !customFieldKeyPattern.MatchString(*body.Key) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "key must be lowercase letters, digits and underscores"})
+		}
+		if customFieldKeyTaken(ctx, projectID, *body.Key, fieldID) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A field with this key already exists"})
+		}
+		field.Key = *body.Key
+	}
+	if body.Options != nil {
+		fieldOptions, err := cleanOptions(field.Type, body.Options)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		field.Options = fieldOptions
+	}
+	if body.Required != nil {
+		field.Required = *body.Required
+	}
+	field.UpdatedAt = time.Now()
+
+	col.ReplaceOne(ctx, bson.M{"_id": fieldID}, field)
+
+	// Values whose option was removed are dropped from the cards.
+	path := customFieldPath(field)
+	cards := database.GetCollection("cards")
+	switch field.Type {
+	case "select":
+		cards.UpdateMany(ctx,
+			bson.M{"project_id": projectID, path: bson.M{"$exists": true, "$nin": field.Options}},
+			bson.M{"$unset": bson.M{path: ""}, "$inc": bumpVersion})
+	case "multi_select":
+		cards.UpdateMany(ctx,
+			bson.M{"project_id": projectID, path: bson.M{"$elemMatch": bson.M{"$nin": field.Options}}},
+			bson.M{"$pull": bson.M{path: bson.M{"$nin": field.Options}}, "$inc": bumpVersion})
+		cards.UpdateMany(ctx,
+			bson.M{"project_id": projectID, path: bson.M{"$size": 0}},
+			bson.M{"$unset": bson.M{path: ""}})
+	}
+
+	changes := appendChange(nil, "name", before.Name, field.Name)
+	changes = appendChange(changes, "key", before.Key, field.Key)
+	changes = appendChange(changes, "options", before.Options, field.Options)
+	changes = appendChange(changes, "required", before.Required, field.Required)
+	if len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "custom_field", field.ID, field.Name, changes)
+	}
+
+	return c.JSON(field)
+}
+
+func DeleteCustomField(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	fieldID, err := primitive.ObjectIDFromHex(c.Params("fieldId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid field ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var field models.CustomField
+	if err := database.GetCollection("custom_fields").FindOne(ctx, bson.M{"_id": fieldID, "project_id": projectID}).Decode(&field); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Field not found"})
+	}
+
+	database.GetCollection("custom_fields").DeleteOne(ctx, bson.M{"_id": fieldID})
+	path := customFieldPath(field)
+	database.GetCollection("cards").UpdateMany(ctx,
+		bson.M{"project_id": projectID, path: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{path: ""}, "$inc": bumpVersion},
+	)
+	recordActivity(ctx, projectID, userID, "deleted", "custom_field", field.ID, field.Name, nil)
+
+	return c.JSON(fiber.Map{"message": "Field deleted"})
+}
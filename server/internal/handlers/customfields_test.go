@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fpmb/server/internal/models"
+)
+
+func TestNormalizePlainValue(t *testing.T) {
+	field := func(typ string, options ...string) models.CustomField {
+		return models.CustomField{Name: "Field", Type: typ, Options: options}
+	}
+	list := func(items ...interface{}) []interface{} { return items }
+
+	tests := []struct {
+		name    string
+		field   models.CustomField
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "nil is no value", field: field("text"), value: nil, want: nil},
+		{name: "text is trimmed", field: field("text"), value: "  hello ", want: "hello"},
+		{name: "blank text", field: field("text"), value: "   ", want: nil},
+		{name: "text wrong type", field: field("text"), value: 3.0, wantErr: true},
+		{name: "number", field: field("number"), value: 2.5, want: 2.5},
+		{name: "number as string", field: field("number"), value: "2.5", wantErr: true},
+		{name: "date", field: field("date"), value: "2026-03-01", want: "2026-03-01"},
+		{name: "empty date", field: field("date"), value: "", want: nil},
+		{name: "date with time", field: field("date"), value: "2026-03-01T10:00:00Z", wantErr: true},
+		{name: "select option", field: field("select", "low", "high"), value: "high", want: "high"},
+		{name: "empty select", field: field("select", "low"), value: "", want: nil},
+		{name: "select unknown option", field: field("select", "low"), value: "mid", wantErr: true},
+		{name: "multi select dedupes", field: field("multi_select", "a", "b"), value: list("b", "a", "b"), want: []string{"b", "a"}},
+		{name: "empty multi select", field: field("multi_select", "a"), value: list(), want: nil},
+		{name: "multi select unknown option", field: field("multi_select", "a"), value: list("a", "z"), wantErr: true},
+		{name: "multi select non-string item", field: field("multi_select", "a"), value: list("a", 1.0), wantErr: true},
+		{name: "multi select not a list", field: field("multi_select", "a"), value: "a", wantErr: true},
+		{name: "checkbox", field: field("checkbox"), value: false, want: false},
+		{name: "checkbox wrong type", field: field("checkbox"), value: "true", wantErr: true},
+		{name: "url", field: field("url"), value: " https://example.com/a ", want: "https://example.com/a"},
+		{name: "empty url", field: field("url"), value: "", want: nil},
+		{name: "url without scheme", field: field("url"), value: "example.com", wantErr: true},
+		{name: "javascript url", field: field("url"), value: "javascript:alert(1)", wantErr: true},
+		{name: "unknown type", field: field("colour"), value: "red", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizePlainValue(tt.field, tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got %#v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -16,6 +16,17 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// docResponse is a doc with the cards its content mentions by key.
+type docResponse struct {
+	models.Doc
+	CardRefs []cardRef `json:"card_refs,omitempty"`
+}
+
+func withCardRefs(ctx context.Context, doc models.Doc) docResponse {
+	refs := resolveCardRefs(ctx, teamProjectIDs(ctx, doc.TeamID), doc.Content)
+	return docResponse{Doc: doc, CardRefs: cardRefsIn(refs, doc.Content)}
+}
+
 func ListDocs(c *fiber.Ctx) error {
 	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
 	if err != nil {
@@ -100,7 +111,8 @@ func CreateDoc(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(doc)
+	setVersionETag(c, doc.Version)
+	return c.Status(fiber.StatusCreated).JSON(withCardRefs(ctx, *doc))
 }
 
 func GetDoc(c *fiber.Ctx) error {
@@ -126,7 +138,8 @@ func GetDoc(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
 	}
 
-	return c.JSON(doc)
+	setVersionETag(c, doc.Version)
+	return c.JSON(withCardRefs(ctx, doc))
 }
 
 func UpdateDoc(c *fiber.Ctx) error {
@@ -153,6 +166,11 @@ func UpdateDoc(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	filter := bson.M{"_id": docID}
+	if err := matchVersion(c, filter, existing.Version); err != nil {
+		return versionError(c, err, existing.Version, existing)
+	}
+
 	var body struct {
 		Title   string `json:"title"`
 		Content string `json:"content"`
@@ -168,10 +186,18 @@ func UpdateDoc(c *fiber.Ctx) error {
 	}
 
 	col := database.GetCollection("docs")
-	col.UpdateOne(ctx, bson.M{"_id": docID}, bson.M{"$set": update})
+	res, err := col.UpdateOne(ctx, filter, bson.M{"$set": update, "$inc": bumpVersion})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update doc"})
+	}
 
 	var doc models.Doc
-	col.FindOne(ctx, bson.M{"_id": docID}).Decode(&doc)
+	if err := col.FindOne(ctx, bson.M{"_id": docID}).Decode(&doc); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Doc not found"})
+	}
+	if res.MatchedCount == 0 {
+		return versionConflict(c, doc.Version, doc)
+	}
 
 	docDir := filepath.Join("../data/teams", existing.TeamID.Hex(), "docs")
 	if err := os.MkdirAll(docDir, 0755); err != nil {
@@ -183,7 +209,8 @@ func UpdateDoc(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(doc)
+	setVersionETag(c, doc.Version)
+	return c.JSON(withCardRefs(ctx, doc))
 }
 
 func DeleteDoc(c *fiber.Ctx) error {
@@ -210,7 +237,22 @@ func DeleteDoc(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	database.GetCollection("docs").DeleteOne(ctx, bson.M{"_id": docID})
+	filter := bson.M{"_id": docID}
+	if err := matchVersion(c, filter, doc.Version); err != nil {
+		return versionError(c, err, doc.Version, doc)
+	}
+
+	res, err := database.GetCollection("docs").DeleteOne(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete doc"})
+	}
+	if res.DeletedCount == 0 {
+		var current models.Doc
+		if err := database.GetCollection("docs").FindOne(ctx, bson.M{"_id": docID}).Decode(&current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Doc not found"})
+		}
+		return versionConflict(c, current.Version, current)
+	}
 
 	mdPath := filepath.Join("../data/teams", doc.TeamID.Hex(), "docs", docID.Hex()+".md")
 	if err := os.Remove(mdPath); err != nil && !os.IsNotExist(err) {
@@ -167,6 +167,10 @@ func CreateProjectEvent(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	now := time.Now()
 	event := &models.Event{
 		ID:          primitive.NewObjectID(),
@@ -183,6 +187,8 @@ func CreateProjectEvent(c *fiber.Ctx) error {
 	}
 
 	database.GetCollection("events").InsertOne(ctx, event)
+	recordActivity(ctx, projectID, userID, "created", "event", event.ID, event.Title,
+		[]models.ActivityChange{{Field: "date", To: event.Date}})
 	return c.Status(fiber.StatusCreated).JSON(event)
 }
 
@@ -216,6 +222,12 @@ func UpdateEvent(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if event.Scope == "project" {
+		if err := ensureProjectWritable(ctx, event.ScopeID); err != nil {
+			return projectWriteError(c, err)
+		}
+	}
+
 	var body struct {
 		Title       string `json:"title"`
 		Date        string `json:"date"`
@@ -247,6 +259,20 @@ func UpdateEvent(c *fiber.Ctx) error {
 
 	var updated models.Event
 	col.FindOne(ctx, bson.M{"_id": eventID}).Decode(&updated)
+
+	if event.Scope == "project" {
+		changes := appendChange(nil, "title", event.Title, updated.Title)
+		changes = appendChange(changes, "date", event.Date, updated.Date)
+		changes = appendChange(changes, "time", event.Time, updated.Time)
+		changes = appendChange(changes, "color", event.Color, updated.Color)
+		if event.Description != updated.Description {
+			changes = append(changes, models.ActivityChange{Field: "description"})
+		}
+		if len(changes) > 0 {
+			recordActivity(ctx, event.ScopeID, userID, "updated", "event", eventID, updated.Title, changes)
+		}
+	}
+
 	return c.JSON(updated)
 }
 
@@ -280,6 +306,15 @@ func DeleteEvent(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if event.Scope == "project" {
+		if err := ensureProjectWritable(ctx, event.ScopeID); err != nil {
+			return projectWriteError(c, err)
+		}
+	}
+
 	database.GetCollection("events").DeleteOne(ctx, bson.M{"_id": eventID})
+	if event.Scope == "project" {
+		recordActivity(ctx, event.ScopeID, userID, "deleted", "event", eventID, event.Title, nil)
+	}
 	return c.JSON(fiber.Map{"message": "Event deleted"})
 }
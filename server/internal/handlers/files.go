@@ -27,6 +27,20 @@ func storageBase(ctx context.Context, projectID primitive.ObjectID) (string, err
 	return filepath.Join("../data/teams", project.TeamID.Hex()), nil
 }
 
+// uniqueFilePath returns a path in dir for name that does not exist yet,
+// appending " (2)", " (3)", ... before the extension on collisions.
+func uniqueFilePath(dir, name string) string {
+	ext := filepath.Ext(name)
+	stem := name[:len(name)-len(ext)]
+	destPath := filepath.Join(dir, name)
+	for n := 2; ; n++ {
+		if _, statErr := os.Stat(destPath); statErr != nil {
+			return destPath
+		}
+		destPath = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+	}
+}
+
 func ListFiles(c *fiber.Ctx) error {
 	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
 	if err != nil {
@@ -99,6 +113,10 @@ func CreateFolder(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	now := time.Now()
 	file := &models.File{
 		ID:        primitive.NewObjectID(),
@@ -117,6 +135,7 @@ func CreateFolder(c *fiber.Ctx) error {
 	}
 
 	database.GetCollection("files").InsertOne(ctx, file)
+	recordActivity(ctx, projectID, userID, "created", "folder", file.ID, file.Name, nil)
 	return c.Status(fiber.StatusCreated).JSON(file)
 }
 
@@ -139,6 +158,10 @@ func UploadFile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	fh, err := c.FormFile("file")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No file provided"})
@@ -155,18 +178,7 @@ func UploadFile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create storage directory"})
 	}
 
-	filename := fh.Filename
-	ext := filepath.Ext(filename)
-	stem := filename[:len(filename)-len(ext)]
-	destPath := filepath.Join(base, filename)
-	for n := 2; ; n++ {
-		if _, statErr := os.Stat(destPath); statErr != nil {
-			break
-		}
-		filename = fmt.Sprintf("%s (%d)%s", stem, n, ext)
-		destPath = filepath.Join(base, filename)
-	}
-
+	destPath := uniqueFilePath(base, fh.Filename)
 	if err := c.SaveFile(fh, destPath); err != nil {
 		log.Printf("UploadFile SaveFile error: %v (destPath=%s)", err, destPath)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save file"})
@@ -195,6 +207,7 @@ func UploadFile(c *fiber.Ctx) error {
 	}
 
 	database.GetCollection("files").InsertOne(ctx, file)
+	recordActivity(ctx, projectID, userID, "uploaded", "file", file.ID, file.Name, nil)
 	return c.Status(fiber.StatusCreated).JSON(file)
 }
 
@@ -578,13 +591,33 @@ func DeleteFile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if file.ProjectID != primitive.NilObjectID {
+		if err := ensureProjectWritable(ctx, file.ProjectID); err != nil {
+			return projectWriteError(c, err)
+		}
+	}
+
 	database.GetCollection("files").DeleteOne(ctx, bson.M{"_id": fileID})
 
+	removed := []primitive.ObjectID{fileID}
 	if file.Type == "folder" {
+		if cursor, err := database.GetCollection("files").Find(ctx, bson.M{"parent_id": fileID}); err == nil {
+			var children []models.File
+			cursor.All(ctx, &children)
+			cursor.Close(ctx)
+			for _, child := range children {
+				removed = append(removed, child.ID)
+			}
+		}
 		database.GetCollection("files").DeleteMany(ctx, bson.M{"parent_id": fileID})
 	} else if file.StorageURL != "" {
 		os.Remove(filepath.Join("../data", file.StorageURL))
 	}
 
+	if file.ProjectID != primitive.NilObjectID {
+		detachFiles(ctx, file.ProjectID, removed)
+		recordActivity(ctx, file.ProjectID, userID, "deleted", file.Type, fileID, file.Name, nil)
+	}
+
 	return c.JSON(fiber.Map{"message": "Deleted"})
 }
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errInvalidLabel = errors.New("invalid label")
+
+// resolveLabelIDs parses label IDs and checks that each one is defined on the
+// project. Duplicates are dropped while preserving order.
+func resolveLabelIDs(ctx context.Context, projectID primitive.ObjectID, ids []string) ([]primitive.ObjectID, error) {
+	result := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, raw := range ids {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return nil, errInvalidLabel
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	count, err := database.GetCollection("labels").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": result}, "project_id": projectID})
+	if err != nil || int(count) != len(result) {
+		return nil, errInvalidLabel
+	}
+	return result, nil
+}
+
+func labelNames(ctx context.Context, ids []primitive.ObjectID) []string {
+	names := []string{}
+	if len(ids) == 0 {
+		return names
+	}
+	cursor, err := database.GetCollection("labels").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return names
+	}
+	defer cursor.Close(ctx)
+
+	var labels []models.Label
+	cursor.All(ctx, &labels)
+	byID := map[primitive.ObjectID]string{}
+	for _, l := range labels {
+		byID[l.ID] = l.Name
+	}
+	for _, id := range ids {
+		if name, ok := byID[id]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func labelNameTaken(ctx context.Context, projectID primitive.ObjectID, name string, exclude primitive.ObjectID) bool {
+	filter := bson.M{
+		"project_id": projectID,
+		"name":       bson.M{"$regex": "^" + regexp.QuoteMeta(name) + "$", "$options": "i"},
+	}
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+	count, _ := database.GetCollection("labels").CountDocuments(ctx, filter)
+	return count > 0
+}
+
+func ListLabels(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	cursor, err := database.GetCollection("labels").Find(ctx,
+		bson.M{"project_id": projectID},
+		options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch labels"})
+	}
+	defer cursor.Close(ctx)
+
+	var labels []models.Label
+	cursor.All(ctx, &labels)
+	if labels == nil {
+		labels = []models.Label{}
+	}
+	return c.JSON(labels)
+}
+
+func CreateLabel(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		Name        string `json:"name"`
+		Color       string `json:"color"`
+		Description string `json:"description"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Color == "" {
+		body.Color = "neutral"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if labelNameTaken(ctx, projectID, body.Name, primitive.NilObjectID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A label with this name already exists"})
+	}
+
+	now := time.Now()
+	label := &models.Label{
+		ID:          primitive.NewObjectID(),
+		ProjectID:   projectID,
+		Name:        body.Name,
+		Color:       body.Color,
+		Description: body.Description,
+		CreatedBy:   userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if _, err := database.GetCollection("labels").InsertOne(ctx, label); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create label"})
+	}
+	recordActivity(ctx, projectID, userID, "created", "label", label.ID, label.Name, nil)
+
+	return c.Status(fiber.StatusCreated).JSON(label)
+}
+
+func UpdateLabel(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	labelID, err := primitive.ObjectIDFromHex(c.Params("labelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label ID"})
+	}
+
+	var body struct {
+		Name        *string `json:"name"`
+		Color       *string `json:"color"`
+		Description *string `json:"description"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("labels")
+	var before models.Label
+	if err := col.FindOne(ctx, bson.M{"_id": labelID, "project_id": projectID}).Decode(&before); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Label not found"})
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name cannot be empty"})
+		}
+		if labelNameTaken(ctx, projectID, name, labelID) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A label with this name already exists"})
+		}
+		update["name"] = name
+	}
+	if body.Color != nil {
+		update["color"] = *body.Color
+	}
+	if body.Description != nil {
+		update["description"] = *body.Description
+	}
+
+	col.UpdateOne(ctx, bson.M{"_id": labelID}, bson.M{"$set": update})
+
+	var label models.Label
+	col.FindOne(ctx, bson.M{"_id": labelID}).Decode(&label)
+
+	changes := appendChange(nil, "name", before.Name, label.Name)
+	changes = appendChange(changes, "color", before.Color, label.Color)
+	if len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "label", labelID, label.Name, changes)
+	}
+
+	return c.JSON(label)
+}
+
+func DeleteLabel(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	labelID, err := primitive.ObjectIDFromHex(c.Params("labelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var label models.Label
+	if err := database.GetCollection("labels").FindOne(ctx, bson.M{"_id": labelID, "project_id": projectID}).Decode(&label); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Label not found"})
+	}
+
+	database.GetCollection("labels").DeleteOne(ctx, bson.M{"_id": labelID})
+	res, _ := database.GetCollection("cards").UpdateMany(ctx,
+		bson.M{"project_id": projectID, "label_ids": labelID},
+		bson.M{"$pull": bson.M{"label_ids": labelID}, "$inc": bumpVersion},
+	)
+	database.GetCollection("recurrences").UpdateMany(ctx,
+		bson.M{"project_id": projectID, "template.label_ids": labelID},
+		bson.M{"$pull": bson.M{"template.label_ids": labelID}},
+	)
+
+	var removed int64
+	if res != nil {
+		removed = res.ModifiedCount
+	}
+	recordActivity(ctx, projectID, userID, "deleted", "label", labelID, label.Name,
+		[]models.ActivityChange{{Field: "cards", From: removed}})
+
+	return c.JSON(fiber.Map{"message": "Label deleted", "cards_updated": removed})
+}
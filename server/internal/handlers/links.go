@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Links are stored once, from the source card's point of view. The inverse
+// names are what the target card sees and are also accepted on create.
+var linkInverse = map[string]string{
+	"blocks":     "blocked_by",
+	"relates_to": "relates_to",
+	"duplicates": "duplicated_by",
+}
+
+type cardLinkSummary struct {
+	ID         primitive.ObjectID `json:"id"`
+	Type       string             `json:"type"`
+	CardID     primitive.ObjectID `json:"card_id"`
+	ProjectID  primitive.ObjectID `json:"project_id"`
+	Title      string             `json:"title,omitempty"`
+	Done       bool               `json:"done"`
+	Archived   bool               `json:"archived,omitempty"`
+	Restricted bool               `json:"restricted,omitempty"`
+}
+
+type cardResponse struct {
+	models.Card
+	Links       []cardLinkSummary `json:"links"`
+	WIPWarnings []wipViolation    `json:"wip_warnings,omitempty"`
+}
+
+func withLinks(ctx context.Context, userID primitive.ObjectID, card models.Card) cardResponse {
+	links := cardLinkSummaries(ctx, userID, []primitive.ObjectID{card.ID})[card.ID]
+	if links == nil {
+		links = []cardLinkSummary{}
+	}
+	return cardResponse{Card: card, Links: links}
+}
+
+// cardLinkSummaries returns the links of each given card as seen from that
+// card. Cards in projects the user cannot read are reported without a title.
+func cardLinkSummaries(ctx context.Context, userID primitive.ObjectID, cardIDs []primitive.ObjectID) map[primitive.ObjectID][]cardLinkSummary {
+	result := map[primitive.ObjectID][]cardLinkSummary{}
+	if len(cardIDs) == 0 {
+		return result
+	}
+
+	cursor, err := database.GetCollection("card_links").Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"source_card_id": bson.M{"$in": cardIDs}},
+		bson.M{"target_card_id": bson.M{"$in": cardIDs}},
+	}})
+	if err != nil {
+		return result
+	}
+	var links []models.CardLink
+	cursor.All(ctx, &links)
+	cursor.Close(ctx)
+	if len(links) == 0 {
+		return result
+	}
+
+	wanted := map[primitive.ObjectID]bool{}
+	for _, id := range cardIDs {
+		wanted[id] = true
+	}
+	otherIDs := []primitive.ObjectID{}
+	for _, l := range links {
+		otherIDs = append(otherIDs, l.SourceCardID, l.TargetCardID)
+	}
+
+	cards := map[primitive.ObjectID]models.Card{}
+	columnIDs := []primitive.ObjectID{}
+	if cur, err := database.GetCollection("cards").Find(ctx, bson.M{"_id": bson.M{"$in": otherIDs}}); err == nil {
+		var found []models.Card
+		cur.All(ctx, &found)
+		cur.Close(ctx)
+		for _, card := range found {
+			cards[card.ID] = card
+			columnIDs = append(columnIDs, card.ColumnID)
+		}
+	}
+
+	doneColumns := map[primitive.ObjectID]bool{}
+	if cur, err := database.GetCollection("board_columns").Find(ctx, bson.M{"_id": bson.M{"$in": columnIDs}}); err == nil {
+		var cols []models.BoardColumn
+		cur.All(ctx, &cols)
+		cur.Close(ctx)
+		for _, col := range cols {
+			doneColumns[col.ID] = isDoneColumn(col)
+		}
+	}
+
+	access := map[primitive.ObjectID]bool{}
+	canRead := func(projectID primitive.ObjectID) bool {
+		ok, seen := access[projectID]
+		if !seen {
+			_, err := getProjectRole(ctx, projectID, userID)
+			ok = err == nil
+			access[projectID] = ok
+		}
+		return ok
+	}
+
+	summarize := func(link models.CardLink, otherID primitive.ObjectID, linkType string) (cardLinkSummary, bool) {
+		other, ok := cards[otherID]
+		if !ok {
+			return cardLinkSummary{}, false
+		}
+		summary := cardLinkSummary{
+			ID:        link.ID,
+			Type:      linkType,
+			CardID:    other.ID,
+			ProjectID: other.ProjectID,
+			Done:      doneColumns[other.ColumnID],
+			Archived:  other.ArchivedAt != nil,
+		}
+		if canRead(other.ProjectID) {
+			summary.Title = other.Title
+		} else {
+			summary.Restricted = true
+		}
+		return summary, true
+	}
+
+	for _, l := range links {
+		if wanted[l.SourceCardID] {
+			if s, ok := summarize(l, l.TargetCardID, l.Type); ok {
+				result[l.SourceCardID] = append(result[l.SourceCardID], s)
+			}
+		}
+		if wanted[l.TargetCardID] {
+			if s, ok := summarize(l, l.SourceCardID, linkInverse[l.Type]); ok {
+				result[l.TargetCardID] = append(result[l.TargetCardID], s)
+			}
+		}
+	}
+	return result
+}
+
+// blocksReachable reports whether card `to` can be reached from card `from`
+// by following "blocks" links, i.e. whether `from` transitively blocks `to`.
+func blocksReachable(ctx context.Context, from, to primitive.ObjectID) bool {
+	visited := map[primitive.ObjectID]bool{from: true}
+	frontier := []primitive.ObjectID{from}
+	for len(frontier) > 0 {
+		cursor, err := database.GetCollection("card_links").Find(ctx, bson.M{
+			"type":           "blocks",
+			"source_card_id": bson.M{"$in": frontier},
+		})
+		if err != nil {
+			return false
+		}
+		var links []models.CardLink
+		cursor.All(ctx, &links)
+		cursor.Close(ctx)
+
+		frontier = nil
+		for _, l := range links {
+			if l.TargetCardID == to {
+				return true
+			}
+			if !visited[l.TargetCardID] {
+				visited[l.TargetCardID] = true
+				frontier = append(frontier, l.TargetCardID)
+			}
+		}
+	}
+	return false
+}
+
+// unresolvedBlockers returns the summaries of cards blocking the given card
+// that are not yet in a done column. Archived blockers count as resolved.
+func unresolvedBlockers(ctx context.Context, userID primitive.ObjectID, cardID primitive.ObjectID) []cardLinkSummary {
+	blockers := []cardLinkSummary{}
+	for _, s := range cardLinkSummaries(ctx, userID, []primitive.ObjectID{cardID})[cardID] {
+		if s.Type == "blocked_by" && !s.Done && !s.Archived {
+			blockers = append(blockers, s)
+		}
+	}
+	return blockers
+}
+
+func deleteCardLinks(ctx context.Context, cardIDs []primitive.ObjectID) {
+	database.GetCollection("card_links").DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"source_card_id": bson.M{"$in": cardIDs}},
+		bson.M{"target_card_id": bson.M{"$in": cardIDs}},
+	}})
+}
+
+func ListCardLinks(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	if _, err := getProjectRole(ctx, card.ProjectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	return c.JSON(withLinks(ctx, userID, card).Links)
+}
+
+func CreateCardLink(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		TargetCardID string `json:"target_card_id"`
+		Type         string `json:"type"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.TargetCardID == "" || body.Type == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "target_card_id and type are required"})
+	}
+
+	targetID, err := primitive.ObjectIDFromHex(body.TargetCardID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid target_card_id"})
+	}
+	if targetID == cardID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A card cannot be linked to itself"})
+	}
+
+	// Inverse types are stored with source and target swapped.
+	linkType := strings.ReplaceAll(strings.ToLower(body.Type), "-", "_")
+	sourceID, destID := cardID, targetID
+	if _, ok := linkInverse[linkType]; !ok {
+		found := false
+		for stored, inverse := range linkInverse {
+			if inverse == linkType {
+				linkType, sourceID, destID = stored, targetID, cardID
+				found = true
+				break
+			}
+		}
+		if !found {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be one of blocks, blocked_by, relates_to, duplicates, duplicated_by"})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var target models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": targetID}).Decode(&target); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Target card not found"})
+	}
+	if _, err := getProjectRole(ctx, target.ProjectID, userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Target card not found"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("card_links")
+	pair := bson.A{
+		bson.M{"source_card_id": sourceID, "target_card_id": destID},
+		bson.M{"source_card_id": destID, "target_card_id": sourceID},
+	}
+	if n, _ := col.CountDocuments(ctx, bson.M{"type": linkType, "$or": pair}); n > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "These cards are already linked"})
+	}
+	if linkType == "blocks" && blocksReachable(ctx, destID, sourceID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "This link would create a blocking cycle"})
+	}
+
+	source, dest := card, target
+	if sourceID != cardID {
+		source, dest = target, card
+	}
+
+	link := &models.CardLink{
+		ID:              primitive.NewObjectID(),
+		Type:            linkType,
+		SourceCardID:    sourceID,
+		TargetCardID:    destID,
+		SourceProjectID: source.ProjectID,
+		TargetProjectID: dest.ProjectID,
+		CreatedBy:       userID,
+		CreatedAt:       time.Now(),
+	}
+	if _, err := col.InsertOne(ctx, link); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create link"})
+	}
+
+	recordActivity(ctx, source.ProjectID, userID, "linked", "card", source.ID, source.Title,
+		[]models.ActivityChange{{Field: linkType, To: dest.Title}})
+
+	return c.Status(fiber.StatusCreated).JSON(withLinks(ctx, userID, card))
+}
+
+func DeleteCardLink(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(c.Params("linkId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid link ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var link models.CardLink
+	if err := database.GetCollection("card_links").FindOne(ctx, bson.M{
+		"_id": linkID,
+		"$or": bson.A{bson.M{"source_card_id": cardID}, bson.M{"target_card_id": cardID}},
+	}).Decode(&link); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Link not found"})
+	}
+
+	database.GetCollection("card_links").DeleteOne(ctx, bson.M{"_id": linkID})
+	recordActivity(ctx, card.ProjectID, userID, "unlinked", "card", card.ID, card.Title,
+		[]models.ActivityChange{{Field: link.Type}})
+
+	return c.JSON(fiber.Map{"message": "Link removed"})
+}
@@ -2,6 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/fpmb/server/internal/database"
@@ -9,6 +14,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -34,6 +40,46 @@ func getProjectRole(ctx context.Context, projectID, userID primitive.ObjectID) (
 	return getTeamRole(ctx, project.TeamID, userID)
 }
 
+// isProjectMemberEmail reports whether the user with the given email can
+// access the project, directly or through its team.
+func isProjectMemberEmail(ctx context.Context, projectID primitive.ObjectID, email string) bool {
+	var user models.User
+	if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+		return false
+	}
+	_, err := getProjectRole(ctx, projectID, user.ID)
+	return err == nil
+}
+
+// IsProjectMember reports whether the user can access the project, for
+// background jobs that notify people about its cards.
+func IsProjectMember(ctx context.Context, projectID, userID primitive.ObjectID) bool {
+	_, err := getProjectRole(ctx, projectID, userID)
+	return err == nil
+}
+
+var errProjectArchived = errors.New("project is archived")
+
+// ensureProjectWritable is the single guard every mutating handler calls
+// before touching project content. Archived projects are read-only.
+func ensureProjectWritable(ctx context.Context, projectID primitive.ObjectID) error {
+	var project models.Project
+	if err := database.GetCollection("projects").FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		return err
+	}
+	if project.IsArchived {
+		return errProjectArchived
+	}
+	return nil
+}
+
+func projectWriteError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errProjectArchived) {
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{"error": "Project is archived and read-only"})
+	}
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+}
+
 func ListProjects(c *fiber.Ctx) error {
 	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
 	if err != nil {
@@ -56,6 +102,7 @@ func ListProjects(c *fiber.Ctx) error {
 		UpdatedAt   time.Time          `json:"updated_at"`
 	}
 
+	includeArchived := c.Query("include_archived") == "true"
 	result := []ProjectResponse{}
 
 	cursor, err := database.GetCollection("team_members").Find(ctx, bson.M{"user_id": userID})
@@ -71,7 +118,11 @@ func ListProjects(c *fiber.Ctx) error {
 		var team models.Team
 		database.GetCollection("teams").FindOne(ctx, bson.M{"_id": m.TeamID}).Decode(&team)
 
-		projCursor, err := database.GetCollection("projects").Find(ctx, bson.M{"team_id": m.TeamID})
+		projFilter := bson.M{"team_id": m.TeamID}
+		if !includeArchived {
+			projFilter["is_archived"] = bson.M{"$ne": true}
+		}
+		projCursor, err := database.GetCollection("projects").Find(ctx, projFilter)
 		if err != nil {
 			continue
 		}
@@ -119,6 +170,9 @@ func ListProjects(c *fiber.Ctx) error {
 			}).Decode(&p); err != nil {
 				continue
 			}
+			if p.IsArchived && !includeArchived {
+				continue
+			}
 			result = append(result, ProjectResponse{
 				ID:          p.ID,
 				Name:        p.Name,
@@ -147,6 +201,7 @@ func CreatePersonalProject(c *fiber.Ctx) error {
 		Name        string `json:"name"`
 		Description string `json:"description"`
 		IsPublic    bool   `json:"is_public"`
+		Key         string `json:"key"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
@@ -158,6 +213,11 @@ func CreatePersonalProject(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	key, msg := chooseProjectKey(ctx, body.Key, body.Name)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
 	now := time.Now()
 	project := &models.Project{
 		ID:          primitive.NewObjectID(),
@@ -166,12 +226,16 @@ func CreatePersonalProject(c *fiber.Ctx) error {
 		Description: body.Description,
 		IsPublic:    body.IsPublic,
 		IsArchived:  false,
+		Key:         key,
 		CreatedBy:   userID,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 
 	if _, err := database.GetCollection("projects").InsertOne(ctx, project); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Project key already in use"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create project"})
 	}
 
@@ -185,12 +249,14 @@ func CreatePersonalProject(c *fiber.Ctx) error {
 	database.GetCollection("project_members").InsertOne(ctx, member)
 
 	defaultColumns := []string{"To Do", "In Progress", "Done"}
+	ranks := spreadRanks(len(defaultColumns))
 	for i, title := range defaultColumns {
 		col := &models.BoardColumn{
 			ID:        primitive.NewObjectID(),
 			ProjectID: project.ID,
 			Title:     title,
-			Position:  i,
+			Rank:      ranks[i],
+			IsDone:    title == "Done",
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
@@ -219,7 +285,12 @@ func ListTeamProjects(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
 	}
 
-	cursor, err := database.GetCollection("projects").Find(ctx, bson.M{"team_id": teamID},
+	filter := bson.M{"team_id": teamID}
+	if c.Query("include_archived") != "true" {
+		filter["is_archived"] = bson.M{"$ne": true}
+	}
+
+	cursor, err := database.GetCollection("projects").Find(ctx, filter,
 		options.Find().SetSort(bson.M{"updated_at": -1}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch projects"})
@@ -265,6 +336,7 @@ func CreateProject(c *fiber.Ctx) error {
 		Name        string `json:"name"`
 		Description string `json:"description"`
 		IsPublic    bool   `json:"is_public"`
+		Key         string `json:"key"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
@@ -281,6 +353,11 @@ func CreateProject(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	key, msg := chooseProjectKey(ctx, body.Key, body.Name)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
 	now := time.Now()
 	project := &models.Project{
 		ID:          primitive.NewObjectID(),
@@ -289,22 +366,28 @@ func CreateProject(c *fiber.Ctx) error {
 		Description: body.Description,
 		IsPublic:    body.IsPublic,
 		IsArchived:  false,
+		Key:         key,
 		CreatedBy:   userID,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 
 	if _, err := database.GetCollection("projects").InsertOne(ctx, project); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Project key already in use"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create project"})
 	}
 
 	defaultColumns := []string{"To Do", "In Progress", "Done"}
+	ranks := spreadRanks(len(defaultColumns))
 	for i, title := range defaultColumns {
 		col := &models.BoardColumn{
 			ID:        primitive.NewObjectID(),
 			ProjectID: project.ID,
 			Title:     title,
-			Position:  i,
+			Rank:      ranks[i],
+			IsDone:    title == "Done",
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
@@ -377,12 +460,41 @@ func UpdateProject(c *fiber.Ctx) error {
 		Description string `json:"description"`
 		IsPublic    *bool  `json:"is_public"`
 		Visibility  string `json:"visibility"`
+		Key         string `json:"key"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
+	col := database.GetCollection("projects")
+	var current models.Project
+	if err := col.FindOne(ctx, bson.M{"_id": projectID}).Decode(&current); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+	}
+
 	update := bson.M{"updated_at": time.Now()}
+	rekey := ""
+	if body.Key != "" {
+		key, ok := normalizeProjectKey(body.Key)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Key must be 2 to 10 letters or digits, starting with a letter"})
+		}
+		if key != current.Key {
+			if projectKeyTaken(ctx, key, projectID) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Project key already in use"})
+			}
+			// The old key stays reserved so existing references keep working.
+			former := []string{}
+			for _, k := range append(current.FormerKeys, current.Key) {
+				if k != "" && k != key && !containsString(former, k) {
+					former = append(former, k)
+				}
+			}
+			update["key"] = key
+			update["former_keys"] = former
+			rekey = key
+		}
+	}
 	if body.Name != "" {
 		update["name"] = body.Name
 	}
@@ -397,8 +509,17 @@ func UpdateProject(c *fiber.Ctx) error {
 		update["is_public"] = body.Visibility == "public"
 	}
 
-	col := database.GetCollection("projects")
-	col.UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{"$set": update})
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{"$set": update}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Project key already in use"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update project"})
+	}
+	if rekey != "" {
+		rekeyCards(ctx, projectID, rekey)
+		recordActivity(ctx, projectID, userID, "updated", "project", projectID, current.Name,
+			appendChange(nil, "key", current.Key, rekey))
+	}
 
 	var project models.Project
 	col.FindOne(ctx, bson.M{"_id": projectID}).Decode(&project)
@@ -406,6 +527,14 @@ func UpdateProject(c *fiber.Ctx) error {
 }
 
 func ArchiveProject(c *fiber.Ctx) error {
+	return setProjectArchived(c, true)
+}
+
+func UnarchiveProject(c *fiber.Ctx) error {
+	return setProjectArchived(c, false)
+}
+
+func setProjectArchived(c *fiber.Ctx, archived bool) error {
 	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
@@ -424,17 +553,18 @@ func ArchiveProject(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	var project models.Project
-	if err := database.GetCollection("projects").FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
-	}
-
-	database.GetCollection("projects").UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{"$set": bson.M{
-		"is_archived": !project.IsArchived,
+	res, err := database.GetCollection("projects").UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{"$set": bson.M{
+		"is_archived": archived,
 		"updated_at":  time.Now(),
 	}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update project"})
+	}
+	if res.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+	}
 
-	return c.JSON(fiber.Map{"is_archived": !project.IsArchived})
+	return c.JSON(fiber.Map{"is_archived": archived})
 }
 
 func DeleteProject(c *fiber.Ctx) error {
@@ -459,6 +589,23 @@ func DeleteProject(c *fiber.Ctx) error {
 	database.GetCollection("projects").DeleteOne(ctx, bson.M{"_id": projectID})
 	database.GetCollection("board_columns").DeleteMany(ctx, bson.M{"project_id": projectID})
 	database.GetCollection("cards").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("card_comments").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("labels").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("swimlanes").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("recurrences").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("automations").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("automation_runs").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("saved_views").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("custom_fields").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("sprints").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("time_entries").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("watches").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("board_events").DeleteMany(ctx, bson.M{"project_id": projectID})
+	database.GetCollection("board_sequences").DeleteOne(ctx, bson.M{"_id": projectID})
+	database.GetCollection("card_links").DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"source_project_id": projectID},
+		bson.M{"target_project_id": projectID},
+	}})
 	database.GetCollection("project_members").DeleteMany(ctx, bson.M{"project_id": projectID})
 	database.GetCollection("events").DeleteMany(ctx, bson.M{"scope_id": projectID, "scope": "project"})
 	database.GetCollection("files").DeleteMany(ctx, bson.M{"project_id": projectID})
@@ -565,6 +712,8 @@ func AddProjectMember(c *fiber.Ctx) error {
 		AddedAt:   time.Now(),
 	}
 	database.GetCollection("project_members").InsertOne(ctx, member)
+	recordActivity(ctx, projectID, requesterID, "added", "member", targetUserID, userName(ctx, targetUserID),
+		[]models.ActivityChange{{Field: "role", To: roleName(flags)}})
 	return c.Status(fiber.StatusCreated).JSON(member)
 }
 
@@ -597,10 +746,19 @@ func UpdateProjectMemberRole(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	var before models.ProjectMember
+	database.GetCollection("project_members").FindOne(ctx, bson.M{"project_id": projectID, "user_id": targetUserID}).Decode(&before)
+
 	database.GetCollection("project_members").UpdateOne(ctx,
 		bson.M{"project_id": projectID, "user_id": targetUserID},
 		bson.M{"$set": bson.M{"role_flags": body.RoleFlags}},
 	)
+
+	if before.RoleFlags != body.RoleFlags {
+		recordActivity(ctx, projectID, requesterID, "updated", "member", targetUserID, userName(ctx, targetUserID),
+			appendChange(nil, "role", roleName(before.RoleFlags), roleName(body.RoleFlags)))
+	}
+
 	return c.JSON(fiber.Map{"user_id": targetUserID, "role_flags": body.RoleFlags, "role_name": roleName(body.RoleFlags)})
 }
 
@@ -628,6 +786,262 @@ func RemoveProjectMember(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
-	database.GetCollection("project_members").DeleteOne(ctx, bson.M{"project_id": projectID, "user_id": targetUserID})
+	res, _ := database.GetCollection("project_members").DeleteOne(ctx, bson.M{"project_id": projectID, "user_id": targetUserID})
+	if res != nil && res.DeletedCount > 0 {
+		recordActivity(ctx, projectID, requesterID, "removed", "member", targetUserID, userName(ctx, targetUserID), nil)
+	}
 	return c.JSON(fiber.Map{"message": "Member removed"})
 }
+
+// planProjectFileMoves decides where every stored file of a project will live
+// under the target team's storage directory. The plan is persisted before any
+// file is touched so an interrupted transfer can be resumed deterministically.
+func planProjectFileMoves(ctx context.Context, projectID, targetTeamID primitive.ObjectID) ([]models.ProjectTransferMove, error) {
+	cursor, err := database.GetCollection("files").Find(ctx, bson.M{
+		"project_id":  projectID,
+		"type":        "file",
+		"storage_url": bson.M{"$exists": true, "$ne": ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []models.File
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join("../data/teams", targetTeamID.Hex())
+	taken := map[string]bool{}
+	moves := []models.ProjectTransferMove{}
+	for _, f := range files {
+		filename := filepath.Base(f.StorageURL)
+		ext := filepath.Ext(filename)
+		stem := filename[:len(filename)-len(ext)]
+		destPath := filepath.Join(base, filename)
+		for n := 2; ; n++ {
+			if _, statErr := os.Stat(destPath); statErr != nil && !taken[destPath] {
+				break
+			}
+			destPath = filepath.Join(base, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+		}
+		taken[destPath] = true
+
+		moves = append(moves, models.ProjectTransferMove{
+			FileID: f.ID,
+			From:   f.StorageURL,
+			To:     destPath[len("../data/"):],
+		})
+	}
+	return moves, nil
+}
+
+// reconcileProjectMembers drops project-level overrides that the target team
+// membership already covers. Members outside the team, and members whose
+// project role exceeds their team role, keep their explicit entry.
+func reconcileProjectMembers(ctx context.Context, projectID, teamID primitive.ObjectID) error {
+	cursor, err := database.GetCollection("project_members").Find(ctx, bson.M{"project_id": projectID})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var members []models.ProjectMember
+	if err := cursor.All(ctx, &members); err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		teamRole, err := getTeamRole(ctx, teamID, m.UserID)
+		if err != nil {
+			continue
+		}
+		if m.RoleFlags <= teamRole {
+			if _, err := database.GetCollection("project_members").DeleteOne(ctx, bson.M{"_id": m.ID}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runProjectTransfer executes the remaining steps of a pending transfer. Every
+// step is idempotent, so calling it again after a failure picks up where the
+// previous attempt stopped.
+func runProjectTransfer(ctx context.Context, t *models.ProjectTransfer) error {
+	transfers := database.GetCollection("project_transfers")
+
+	for i, m := range t.Moves {
+		if m.Done {
+			continue
+		}
+
+		src := filepath.Join("../data", m.From)
+		dst := filepath.Join("../data", m.To)
+		if _, err := os.Stat(src); err == nil {
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(src, dst); err != nil {
+				return err
+			}
+		} else if _, err := os.Stat(dst); err != nil {
+			log.Printf("runProjectTransfer: file %s missing on disk (from=%s to=%s)", m.FileID.Hex(), src, dst)
+		}
+
+		now := time.Now()
+		if _, err := database.GetCollection("files").UpdateOne(ctx, bson.M{"_id": m.FileID}, bson.M{"$set": bson.M{
+			"storage_url": m.To,
+			"updated_at":  now,
+		}}); err != nil {
+			return err
+		}
+		if _, err := transfers.UpdateOne(ctx, bson.M{"_id": t.ID}, bson.M{"$set": bson.M{
+			fmt.Sprintf("moves.%d.done", i): true,
+			"updated_at":                    now,
+		}}); err != nil {
+			return err
+		}
+		t.Moves[i].Done = true
+	}
+
+	if err := reconcileProjectMembers(ctx, t.ProjectID, t.ToTeamID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if _, err := database.GetCollection("projects").UpdateOne(ctx, bson.M{"_id": t.ProjectID}, bson.M{"$set": bson.M{
+		"team_id":    t.ToTeamID,
+		"updated_at": now,
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := transfers.UpdateOne(ctx, bson.M{"_id": t.ID}, bson.M{"$set": bson.M{
+		"status":       "completed",
+		"updated_at":   now,
+		"completed_at": now,
+	}}); err != nil {
+		return err
+	}
+	t.Status = "completed"
+	t.UpdatedAt = now
+	t.CompletedAt = &now
+	return nil
+}
+
+func TransferProject(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		TeamID string `json:"team_id"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.TeamID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "team_id is required"})
+	}
+
+	targetTeamID, err := primitive.ObjectIDFromHex(body.TeamID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid team_id"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	teamRole, err := getTeamRole(ctx, targetTeamID, userID)
+	if err != nil || !hasPermission(teamRole, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You must be an owner or admin of the target team"})
+	}
+
+	var project models.Project
+	if err := database.GetCollection("projects").FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+	}
+
+	transfers := database.GetCollection("project_transfers")
+
+	var transfer models.ProjectTransfer
+	err = transfers.FindOne(ctx, bson.M{"project_id": projectID, "status": "pending"}).Decode(&transfer)
+	if err == mongo.ErrNoDocuments {
+		if project.TeamID == targetTeamID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Project already belongs to this team"})
+		}
+
+		moves, err := planProjectFileMoves(ctx, projectID, targetTeamID)
+		if err != nil {
+			log.Printf("TransferProject planProjectFileMoves error: %v (projectID=%s)", err, projectID.Hex())
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to plan file relocation"})
+		}
+
+		now := time.Now()
+		transfer = models.ProjectTransfer{
+			ID:          primitive.NewObjectID(),
+			ProjectID:   projectID,
+			FromTeamID:  project.TeamID,
+			ToTeamID:    targetTeamID,
+			Status:      "pending",
+			Moves:       moves,
+			RequestedBy: userID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if _, err := transfers.InsertOne(ctx, &transfer); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start transfer"})
+		}
+	} else if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch transfer state"})
+	} else if transfer.ToTeamID != targetTeamID {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Another transfer is already in progress for this project", "transfer": transfer})
+	}
+
+	if err := runProjectTransfer(ctx, &transfer); err != nil {
+		log.Printf("TransferProject runProjectTransfer error: %v (transferID=%s)", err, transfer.ID.Hex())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Transfer interrupted, retry to resume", "transfer": transfer})
+	}
+
+	return c.JSON(transfer)
+}
+
+func GetProjectTransfer(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var transfer models.ProjectTransfer
+	if err := database.GetCollection("project_transfers").FindOne(ctx,
+		bson.M{"project_id": projectID},
+		options.FindOne().SetSort(bson.M{"created_at": -1}),
+	).Decode(&transfer); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No transfer found"})
+	}
+
+	return c.JSON(transfer)
+}
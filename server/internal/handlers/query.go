@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// queryTerm is one `key:value` clause of a board query. Bare words and
+// quoted phrases are text terms; a leading `-` negates the clause.
+type queryTerm struct {
+	Negate bool
+	Key    string
+	Value  string
+}
+
+var queryKeys = map[string]bool{
+	"text":     true,
+	"assignee": true,
+	"priority": true,
+	"due":      true,
+	"label":    true,
+	"column":   true,
+	"lane":     true,
+	"project":  true,
+	"sprint":   true,
+	"is":       true,
+}
+
+var relativeDuePattern = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// parseBoardQuery splits a query such as
+// `assignee:me priority:High due:<7d -column:Done "login page"` into terms.
+func parseBoardQuery(raw string) ([]queryTerm, error) {
+	terms := []queryTerm{}
+	var cur strings.Builder
+	term := queryTerm{}
+	started, inQuote, quoted := false, false, false
+
+	flush := func() error {
+		if started {
+			term.Value = cur.String()
+			if term.Key == "" {
+				term.Key = "text"
+			}
+			if !queryKeys[term.Key] && !strings.HasPrefix(term.Key, "cf.") {
+				return fmt.Errorf("unknown field %q", term.Key)
+			}
+			if term.Value == "" {
+				return fmt.Errorf("%s: needs a value", term.Key)
+			}
+			terms = append(terms, term)
+		}
+		cur.Reset()
+		term = queryTerm{}
+		started, quoted = false, false
+		return nil
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			started, quoted = true, true
+		case unicode.IsSpace(r) && !inQuote:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case r == '-' && !started:
+			term.Negate = true
+			started = true
+		case r == ':' && !inQuote && !quoted && term.Key == "" && cur.Len() > 0:
+			term.Key = strings.ToLower(cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// queryScope carries what a compiled query needs to resolve names and
+// relative values: the caller and the projects the query may touch.
+type queryScope struct {
+	User       models.User
+	ProjectIDs []primitive.ObjectID
+	Now        time.Time
+}
+
+func newQueryScope(ctx context.Context, userID primitive.ObjectID, projectIDs []primitive.ObjectID) queryScope {
+	var user models.User
+	database.GetCollection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	return queryScope{User: user, ProjectIDs: projectIDs, Now: time.Now()}
+}
+
+// compileBoardQuery turns a board query into a Mongo filter over cards. An
+// empty query compiles to an empty filter.
+func compileBoardQuery(ctx context.Context, raw string, scope queryScope) (bson.M, error) {
+	terms, err := parseBoardQuery(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	conds := bson.A{}
+	for _, t := range terms {
+		cond, err := compileQueryTerm(ctx, t, scope)
+		if err != nil {
+			return nil, err
+		}
+		if t.Negate {
+			cond = bson.M{"$nor": bson.A{cond}}
+		}
+		conds = append(conds, cond)
+	}
+	if len(conds) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": conds}, nil
+}
+
+func splitQueryValues(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func compileQueryTerm(ctx context.Context, t queryTerm, scope queryScope) (bson.M, error) {
+	if key, ok := strings.CutPrefix(t.Key, "cf."); ok {
+		return compileCustomFieldTerm(ctx, key, t.Value, scope)
+	}
+
+	values := splitQueryValues(t.Value)
+	noneOf := func(field string) bson.M {
+		return bson.M{field: bson.M{"$in": bson.A{nil, bson.A{}}}}
+	}
+
+	switch t.Key {
+	case "text":
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(t.Value), Options: "i"}
+		return bson.M{"$or": bson.A{
+			bson.M{"title": pattern},
+			bson.M{"description": pattern},
+			bson.M{"subtasks.text": pattern},
+		}}, nil
+
+	case "assignee":
+		if len(values) == 1 && strings.EqualFold(values[0], "none") {
+			return noneOf("assignees"), nil
+		}
+		emails := bson.A{}
+		for _, v := range values {
+			if strings.EqualFold(v, "me") {
+				v = scope.User.Email
+			}
+			emails = append(emails, v)
+		}
+		return bson.M{"assignees": bson.M{"$in": emails}}, nil
+
+	case "priority":
+		priorities := bson.A{}
+		for _, v := range values {
+			found := false
+			for _, p := range priorityOrder {
+				if strings.EqualFold(p, v) {
+					priorities = append(priorities, p)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("priority: unknown value %q", v)
+			}
+		}
+		return bson.M{"priority": bson.M{"$in": priorities}}, nil
+
+	case "due":
+		return compileDueTerm(t.Value, scope.Now)
+
+	case "label":
+		if len(values) == 1 && strings.EqualFold(values[0], "none") {
+			return noneOf("label_ids"), nil
+		}
+		ids := namedIDs(ctx, "labels", "name", scope.ProjectIDs, values)
+		return bson.M{"label_ids": bson.M{"$in": ids}}, nil
+
+	case "column":
+		ids := namedIDs(ctx, "board_columns", "title", scope.ProjectIDs, values)
+		return bson.M{"column_id": bson.M{"$in": ids}}, nil
+
+	case "lane":
+		if len(values) == 1 && strings.EqualFold(values[0], "none") {
+			return bson.M{"lane_id": nil}, nil
+		}
+		ids := namedIDs(ctx, "swimlanes", "title", scope.ProjectIDs, values)
+		return bson.M{"lane_id": bson.M{"$in": ids}}, nil
+
+	case "project":
+		ids := namedIDs(ctx, "projects", "name", scope.ProjectIDs, values)
+		return bson.M{"project_id": bson.M{"$in": ids}}, nil
+
+	case "sprint":
+		switch strings.ToLower(t.Value) {
+		case "none", "backlog":
+			return bson.M{"sprint_id": nil}, nil
+		case "active", "current":
+			active, _ := database.GetCollection("sprints").Distinct(ctx, "_id",
+				bson.M{"project_id": bson.M{"$in": scope.ProjectIDs}, "state": "active"})
+			return bson.M{"sprint_id": bson.M{"$in": append(bson.A{}, active...)}}, nil
+		}
+		ids := namedIDs(ctx, "sprints", "name", scope.ProjectIDs, values)
+		return bson.M{"sprint_id": bson.M{"$in": ids}}, nil
+
+	case "is":
+		switch strings.ToLower(t.Value) {
+		case "done":
+			return bson.M{"column_id": bson.M{"$in": doneColumnIDs(ctx, scope.ProjectIDs)}}, nil
+		case "open":
+			return bson.M{"column_id": bson.M{"$nin": doneColumnIDs(ctx, scope.ProjectIDs)}}, nil
+		case "recurring":
+			return bson.M{"recurrence_id": bson.M{"$ne": nil}}, nil
+		}
+		return nil, fmt.Errorf("is: expected done, open or recurring")
+	}
+	return nil, fmt.Errorf("unknown field %q", t.Key)
+}
+
+// compileDueTerm understands none, overdue, today, a date (YYYY-MM-DD) and
+// comparisons against relative (7d, 2w) or absolute dates: <7d, >2026-01-31.
+func compileDueTerm(value string, now time.Time) (bson.M, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch strings.ToLower(value) {
+	case "none":
+		return bson.M{"due_date": nil}, nil
+	case "overdue":
+		return bson.M{"due_date": bson.M{"$lt": now}}, nil
+	case "today":
+		return bson.M{"due_date": bson.M{"$gte": today, "$lt": today.AddDate(0, 0, 1)}}, nil
+	}
+
+	op := ""
+	if strings.HasPrefix(value, "<") || strings.HasPrefix(value, ">") {
+		op, value = value[:1], value[1:]
+	}
+
+	var at time.Time
+	if m := relativeDuePattern.FindStringSubmatch(strings.ToLower(value)); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if m[2] == "w" {
+			n *= 7
+		}
+		at = now.AddDate(0, 0, n)
+	} else if d, err := time.Parse("2006-01-02", value); err == nil {
+		at = d
+		if op == "" {
+			return bson.M{"due_date": bson.M{"$gte": d, "$lt": d.AddDate(0, 0, 1)}}, nil
+		}
+	} else {
+		return nil, fmt.Errorf("due: expected none, overdue, today, a date or <Nd / >Nd")
+	}
+
+	switch op {
+	case "<":
+		return bson.M{"due_date": bson.M{"$lt": at}}, nil
+	case ">":
+		return bson.M{"due_date": bson.M{"$gt": at}}, nil
+	}
+	return bson.M{"due_date": bson.M{"$gte": today, "$lt": at}}, nil
+}
+
+var comparisonPattern = regexp.MustCompile(`^(<=|>=|<|>)?(.+)$`)
+
+var comparisonOps = map[string]string{"": "$eq", "<": "$lt", "<=": "$lte", ">": "$gt", ">=": "$gte"}
+
+// compileCustomFieldTerm filters on a custom field by its key. Numbers and
+// dates accept comparisons (cf.points:>=3, cf.launch:<2026-06-01), selects
+// and users a comma-separated list, text and URLs a substring, checkboxes
+// true or false; none matches cards without a value.
+func compileCustomFieldTerm(ctx context.Context, key, value string, scope queryScope) (bson.M, error) {
+	cursor, err := database.GetCollection("custom_fields").Find(ctx, bson.M{"project_id": bson.M{"$in": scope.ProjectIDs}, "key": key})
+	if err != nil {
+		return nil, err
+	}
+	var fields []models.CustomField
+	cursor.All(ctx, &fields)
+	cursor.Close(ctx)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("unknown custom field %q", key)
+	}
+
+	alternatives := bson.A{}
+	for _, f := range fields {
+		path := customFieldPath(f)
+		if strings.EqualFold(value, "none") {
+			alternatives = append(alternatives, bson.M{path: nil})
+			continue
+		}
+
+		switch f.Type {
+		case "number", "date":
+			m := comparisonPattern.FindStringSubmatch(value)
+			var operand interface{} = m[2]
+			if f.Type == "number" {
+				n, err := strconv.ParseFloat(m[2], 64)
+				if err != nil {
+					return nil, fmt.Errorf("cf.%s: expected a number", key)
+				}
+				operand = n
+			} else if rel := relativeDuePattern.FindStringSubmatch(strings.ToLower(m[2])); rel != nil {
+				n, _ := strconv.Atoi(rel[1])
+				if rel[2] == "w" {
+					n *= 7
+				}
+				operand = scope.Now.AddDate(0, 0, n).Format("2006-01-02")
+			} else if _, err := time.Parse("2006-01-02", m[2]); err != nil {
+				return nil, fmt.Errorf("cf.%s: expected a date as YYYY-MM-DD or Nd", key)
+			}
+			alternatives = append(alternatives, bson.M{path: bson.M{comparisonOps[m[1]]: operand}})
+
+		case "select", "multi_select", "user":
+			values := bson.A{}
+			for _, v := range splitQueryValues(value) {
+				if f.Type == "user" && strings.EqualFold(v, "me") {
+					v = scope.User.Email
+				}
+				for _, o := range f.Options {
+					if strings.EqualFold(o, v) {
+						v = o
+					}
+				}
+				values = append(values, v)
+			}
+			alternatives = append(alternatives, bson.M{path: bson.M{"$in": values}})
+
+		case "checkbox":
+			switch strings.ToLower(value) {
+			case "true", "yes":
+				alternatives = append(alternatives, bson.M{path: true})
+			case "false", "no":
+				alternatives = append(alternatives, bson.M{path: bson.M{"$ne": true}})
+			default:
+				return nil, fmt.Errorf("cf.%s: expected true or false", key)
+			}
+
+		default:
+			alternatives = append(alternatives, bson.M{path: primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}})
+		}
+	}
+	return bson.M{"$or": alternatives}, nil
+}
+
+var sortableCardFields = map[string]bool{
+	"title":      true,
+	"due_date":   true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// compileBoardSort turns sort=[-]field into a card sort, where field is a
+// card field or cf.<key>. Ties, and an empty sort, fall back to rank.
+func compileBoardSort(ctx context.Context, projectID primitive.ObjectID, raw string) (bson.D, error) {
+	if raw == "" || raw == "rank" {
+		return rankSort, nil
+	}
+	dir := 1
+	if strings.HasPrefix(raw, "-") {
+		dir, raw = -1, raw[1:]
+	}
+
+	path := raw
+	if key, ok := strings.CutPrefix(raw, "cf."); ok {
+		var field models.CustomField
+		if err := database.GetCollection("custom_fields").FindOne(ctx, bson.M{"project_id": projectID, "key": key}).Decode(&field); err != nil {
+			return nil, fmt.Errorf("unknown custom field %q", key)
+		}
+		path = customFieldPath(field)
+	} else if !sortableCardFields[raw] {
+		return nil, fmt.Errorf("sort must be rank, title, due_date, created_at, updated_at or cf.<key>")
+	}
+
+	return append(bson.D{{Key: path, Value: dir}}, rankSort...), nil
+}
+
+// namedIDs resolves case-insensitive names within the given projects. Names
+// that match nothing simply contribute no IDs, so the clause matches no card.
+func namedIDs(ctx context.Context, collection, field string, projectIDs []primitive.ObjectID, names []string) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	if len(names) == 0 {
+		return ids
+	}
+	alternatives := bson.A{}
+	for _, name := range names {
+		alternatives = append(alternatives, bson.M{field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}})
+	}
+	scopeField := "project_id"
+	if collection == "projects" {
+		scopeField = "_id"
+	}
+	cursor, err := database.GetCollection(collection).Find(ctx, bson.M{
+		scopeField: bson.M{"$in": projectIDs},
+		"$or":      alternatives,
+	})
+	if err != nil {
+		return ids
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	cursor.All(ctx, &docs)
+	for _, d := range docs {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func doneColumnIDs(ctx context.Context, projectIDs []primitive.ObjectID) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	cursor, err := database.GetCollection("board_columns").Find(ctx, bson.M{"project_id": bson.M{"$in": projectIDs}})
+	if err != nil {
+		return ids
+	}
+	defer cursor.Close(ctx)
+
+	var columns []models.BoardColumn
+	cursor.All(ctx, &columns)
+	for _, col := range columns {
+		if isDoneColumn(col) {
+			ids = append(ids, col.ID)
+		}
+	}
+	return ids
+}
+
+// accessibleProjectIDs lists every project the user can read: projects of
+// their teams plus those they were added to directly.
+func accessibleProjectIDs(ctx context.Context, userID primitive.ObjectID, includeArchived bool) []primitive.ObjectID {
+	teamIDs, _ := database.GetCollection("team_members").Distinct(ctx, "team_id", bson.M{"user_id": userID})
+	memberOf, _ := database.GetCollection("project_members").Distinct(ctx, "project_id", bson.M{"user_id": userID})
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"team_id": bson.M{"$in": append(bson.A{}, teamIDs...)}},
+		bson.M{"_id": bson.M{"$in": append(bson.A{}, memberOf...)}},
+	}}
+	if !includeArchived {
+		filter["is_archived"] = bson.M{"$ne": true}
+	}
+
+	ids := []primitive.ObjectID{}
+	distinct, err := database.GetCollection("projects").Distinct(ctx, "_id", filter)
+	if err != nil {
+		return ids
+	}
+	for _, v := range distinct {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SearchCards runs a board query across every project the caller can read.
+func SearchCards(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := strings.TrimSpace(c.Query("q"))
+	if viewParam := c.Query("view"); viewParam != "" {
+		viewID, err := primitive.ObjectIDFromHex(viewParam)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid view ID"})
+		}
+		view, err := readableView(ctx, userID, viewID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "View not found"})
+		}
+		query = strings.TrimSpace(view.Query + " " + query)
+	}
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q is required"})
+	}
+
+	limit := int64(50)
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.ParseInt(l, 10, 64); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+	offset := int64(0)
+	if o := c.Query("offset"); o != "" {
+		if n, err := strconv.ParseInt(o, 10, 64); err == nil && n > 0 {
+			offset = n
+		}
+	}
+
+	projectIDs := accessibleProjectIDs(ctx, userID, c.Query("include_archived") == "true")
+	filter, err := compileBoardQuery(ctx, query, newQueryScope(ctx, userID, projectIDs))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid query: " + err.Error()})
+	}
+	filter["project_id"] = bson.M{"$in": projectIDs}
+	filter["archived_at"] = nil
+
+	cardsCol := database.GetCollection("cards")
+	total, _ := cardsCol.CountDocuments(ctx, filter)
+	cursor, err := cardsCol.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(offset).
+		SetLimit(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to search cards"})
+	}
+	defer cursor.Close(ctx)
+
+	var cards []models.Card
+	cursor.All(ctx, &cards)
+
+	type SearchResult struct {
+		models.Card
+		ProjectName string `json:"project_name"`
+		ColumnTitle string `json:"column_title"`
+	}
+
+	projectNames := map[primitive.ObjectID]string{}
+	columnTitles := map[primitive.ObjectID]string{}
+	result := []SearchResult{}
+	for _, card := range cards {
+		if _, ok := projectNames[card.ProjectID]; !ok {
+			var project models.Project
+			database.GetCollection("projects").FindOne(ctx, bson.M{"_id": card.ProjectID}).Decode(&project)
+			projectNames[card.ProjectID] = project.Name
+		}
+		if _, ok := columnTitles[card.ColumnID]; !ok {
+			columnTitles[card.ColumnID] = columnTitle(ctx, card.ColumnID)
+		}
+		if card.LabelIDs == nil {
+			card.LabelIDs = []primitive.ObjectID{}
+		}
+		result = append(result, SearchResult{
+			Card:        card,
+			ProjectName: projectNames[card.ProjectID],
+			ColumnTitle: columnTitles[card.ColumnID],
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"cards":    result,
+		"total":    total,
+		"has_more": offset+int64(len(result)) < total,
+		"query":    query,
+	})
+}
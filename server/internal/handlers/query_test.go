@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fpmb/server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseBoardQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []queryTerm
+		wantErr bool
+	}{
+		{name: "empty", raw: "  ", want: []queryTerm{}},
+		{name: "bare words", raw: "login bug", want: []queryTerm{
+			{Key: "text", Value: "login"},
+			{Key: "text", Value: "bug"},
+		}},
+		{name: "quoted phrase", raw: `"login page"`, want: []queryTerm{{Key: "text", Value: "login page"}}},
+		{name: "keys and negation", raw: `assignee:me Priority:High due:<7d -column:Done`, want: []queryTerm{
+			{Key: "assignee", Value: "me"},
+			{Key: "priority", Value: "High"},
+			{Key: "due", Value: "<7d"},
+			{Negate: true, Key: "column", Value: "Done"},
+		}},
+		{name: "quoted value", raw: `column:"In Review"`, want: []queryTerm{{Key: "column", Value: "In Review"}}},
+		{name: "colon in quotes is text", raw: `"a:b"`, want: []queryTerm{{Key: "text", Value: "a:b"}}},
+		{name: "second colon is part of the value", raw: `text:a:b`, want: []queryTerm{{Key: "text", Value: "a:b"}}},
+		{name: "custom field", raw: `cf.points:>=3`, want: []queryTerm{{Key: "cf.points", Value: ">=3"}}},
+		{name: "hyphen inside a word", raw: `follow-up`, want: []queryTerm{{Key: "text", Value: "follow-up"}}},
+		{name: "negated text", raw: `-"wont fix"`, want: []queryTerm{{Negate: true, Key: "text", Value: "wont fix"}}},
+		{name: "unknown key", raw: `colour:red`, wantErr: true},
+		{name: "missing value", raw: `priority:`, wantErr: true},
+		{name: "lone minus", raw: `-`, wantErr: true},
+		{name: "unterminated quote", raw: `"login page`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBoardQuery(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got %+v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitQueryValues(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{}},
+		{"High", []string{"High"}},
+		{"High, Urgent,,", []string{"High", "Urgent"}},
+	}
+	for _, tt := range tests {
+		if got := splitQueryValues(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitQueryValues(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompileDueTerm(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		value   string
+		want    bson.M
+		wantErr bool
+	}{
+		{value: "none", want: bson.M{"due_date": nil}},
+		{value: "Overdue", want: bson.M{"due_date": bson.M{"$lt": now}}},
+		{value: "today", want: bson.M{"due_date": bson.M{"$gte": today, "$lt": today.AddDate(0, 0, 1)}}},
+		{value: "7d", want: bson.M{"due_date": bson.M{"$gte": today, "$lt": now.AddDate(0, 0, 7)}}},
+		{value: "<2w", want: bson.M{"due_date": bson.M{"$lt": now.AddDate(0, 0, 14)}}},
+		{value: ">3D", want: bson.M{"due_date": bson.M{"$gt": now.AddDate(0, 0, 3)}}},
+		{value: "2026-04-01", want: bson.M{"due_date": bson.M{"$gte": day(2026, 4, 1), "$lt": day(2026, 4, 2)}}},
+		{value: "<2026-04-01", want: bson.M{"due_date": bson.M{"$lt": day(2026, 4, 1)}}},
+		{value: ">2026-04-01", want: bson.M{"due_date": bson.M{"$gt": day(2026, 4, 1)}}},
+		{value: "soon", wantErr: true},
+		{value: "<", wantErr: true},
+		{value: "2026-13-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := compileDueTerm(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("compileDueTerm(%q): want error, got %v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("compileDueTerm(%q): unexpected error: %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("compileDueTerm(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// The fields below compile without touching the database.
+func TestCompileBoardQuery(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	scope := queryScope{User: models.User{Email: "me@example.com"}, Now: now}
+	text := func(s string) bson.M {
+		pattern := primitive.Regex{Pattern: s, Options: "i"}
+		return bson.M{"$or": bson.A{
+			bson.M{"title": pattern},
+			bson.M{"description": pattern},
+			bson.M{"subtasks.text": pattern},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    bson.M
+		wantErr bool
+	}{
+		{name: "empty", raw: "", want: bson.M{}},
+		{name: "text is escaped", raw: `"a.b (c)"`, want: bson.M{"$and": bson.A{text(`a\.b \(c\)`)}}},
+		{name: "assignee me", raw: "assignee:me,bob@example.com", want: bson.M{"$and": bson.A{
+			bson.M{"assignees": bson.M{"$in": bson.A{"me@example.com", "bob@example.com"}}},
+		}}},
+		{name: "assignee none", raw: "assignee:none", want: bson.M{"$and": bson.A{
+			bson.M{"assignees": bson.M{"$in": bson.A{nil, bson.A{}}}},
+		}}},
+		{name: "priority is case-insensitive", raw: "priority:high,LOW", want: bson.M{"$and": bson.A{
+			bson.M{"priority": bson.M{"$in": bson.A{"High", "Low"}}},
+		}}},
+		{name: "negated due", raw: "-due:none login", want: bson.M{"$and": bson.A{
+			bson.M{"$nor": bson.A{bson.M{"due_date": nil}}},
+			text("login"),
+		}}},
+		{name: "unknown priority", raw: "priority:soon", wantErr: true},
+		{name: "bad due", raw: "due:later", wantErr: true},
+		{name: "parse error", raw: "nope:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := compileBoardQuery(context.Background(), tt.raw, scope)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got %v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
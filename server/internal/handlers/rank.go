@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Cards and columns are ordered by a base-36 string rank compared byte-wise,
+// with _id as a tie-breaker. A new rank can always be generated between two
+// neighbours, so moving an item only rewrites that item's document.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// maxRankLength is the length past which the rebalancer respaces a list.
+const maxRankLength = 12
+
+var rankSort = bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}}
+
+// rankBetween returns a rank strictly between a and b. An empty a means the
+// start of the list and an empty b the end. Generated ranks never end in '0',
+// which guarantees there is always room before them.
+func rankBetween(a, b string) string {
+	if b != "" && a >= b {
+		// Two items share a rank (e.g. concurrent moves); place after a and
+		// leave the tie to _id until the next rebalance.
+		b = ""
+	}
+
+	if b != "" {
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankBetween(rest, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(rankDigits, a[0])
+	}
+	hi := len(rankDigits)
+	if b != "" {
+		hi = strings.IndexByte(rankDigits, b[0])
+	}
+
+	if hi-lo > 1 {
+		return string(rankDigits[(lo+hi+1)/2])
+	}
+	if b != "" && len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[lo]) + rankBetween(rest, "")
+}
+
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+// spreadRanks returns n short, evenly spaced ranks in ascending order.
+func spreadRanks(n int) []string {
+	width, space := 1, len(rankDigits)
+	for space < 2*(n+1) {
+		width++
+		space *= len(rankDigits)
+	}
+
+	ranks := make([]string, n)
+	for i := range ranks {
+		v := (i + 1) * space / (n + 1)
+		buf := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = rankDigits[v%len(rankDigits)]
+			v /= len(rankDigits)
+		}
+		ranks[i] = strings.TrimRight(string(buf), "0")
+	}
+	return ranks
+}
+
+// rankAt returns a rank that places an item at index among the documents
+// matching filter, ignoring the item itself. A negative index appends.
+func rankAt(ctx context.Context, collection string, filter bson.M, exclude primitive.ObjectID, index int) string {
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+
+	var docs []struct {
+		Rank string `bson:"rank"`
+	}
+	cursor, err := database.GetCollection(collection).Find(ctx, filter,
+		options.Find().SetSort(rankSort).SetProjection(bson.M{"rank": 1}))
+	if err == nil {
+		cursor.All(ctx, &docs)
+		cursor.Close(ctx)
+	}
+
+	if index < 0 || index > len(docs) {
+		index = len(docs)
+	}
+	prev, next := "", ""
+	if index > 0 {
+		prev = docs[index-1].Rank
+	}
+	if index < len(docs) {
+		next = docs[index].Rank
+	}
+	return rankBetween(prev, next)
+}
+
+// RebalanceRanks respaces every column, card and lane list whose ranks have
+// grown too long, collide, or are missing (documents created before ranks
+// existed, which are ordered by their legacy position).
+func RebalanceRanks(ctx context.Context) {
+	rebalanceRanks(ctx, "board_columns", "project_id", "column")
+	rebalanceRanks(ctx, "cards", "column_id", "card")
+	rebalanceRanks(ctx, "swimlanes", "project_id", "lane")
+}
+
+// rebalanceRanks rewrites each list's ranks and tells open boards about the
+// new ones with a ranks.rebalanced event. A document that moved or was
+// reranked since the list was read keeps its rank, as it was placed against
+// the list it is in now.
+func rebalanceRanks(ctx context.Context, collection, groupField, kind string) {
+	rankLen := bson.M{"$strLenBytes": bson.M{"$ifNull": bson.A{"$rank", ""}}}
+	cursor, err := database.GetCollection(collection).Aggregate(ctx, bson.A{
+		bson.M{"$group": bson.M{
+			"_id":     "$" + groupField,
+			"count":   bson.M{"$sum": 1},
+			"ranks":   bson.M{"$addToSet": "$rank"},
+			"max_len": bson.M{"$max": rankLen},
+			"missing": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{rankLen, 0}}, 1, 0}}},
+		}},
+		bson.M{"$match": bson.M{"$or": bson.A{
+			bson.M{"max_len": bson.M{"$gt": maxRankLength}},
+			bson.M{"missing": bson.M{"$gt": 0}},
+			bson.M{"$expr": bson.M{"$ne": bson.A{bson.M{"$size": "$ranks"}, "$count"}}},
+		}}},
+	})
+	if err != nil {
+		return
+	}
+	var groups []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	cursor.All(ctx, &groups)
+	cursor.Close(ctx)
+
+	col := database.GetCollection(collection)
+	for _, g := range groups {
+		docCursor, err := col.Find(ctx, bson.M{groupField: g.ID},
+			options.Find().
+				SetSort(bson.D{{Key: "rank", Value: 1}, {Key: "position", Value: 1}, {Key: "_id", Value: 1}}).
+				SetProjection(bson.M{"_id": 1, "project_id": 1, "rank": 1}))
+		if err != nil {
+			continue
+		}
+		var docs []struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			ProjectID primitive.ObjectID `bson:"project_id"`
+			Rank      *string            `bson:"rank"`
+		}
+		docCursor.All(ctx, &docs)
+		docCursor.Close(ctx)
+		if len(docs) == 0 {
+			continue
+		}
+
+		ranks := spreadRanks(len(docs))
+		writes := make([]mongo.WriteModel, 0, len(docs))
+		for i, d := range docs {
+			// A nil rank also matches documents without one.
+			var rank interface{}
+			if d.Rank != nil {
+				rank = *d.Rank
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": d.ID, groupField: g.ID, "rank": rank}).
+				SetUpdate(bson.M{
+					"$set":   bson.M{"rank": ranks[i]},
+					"$unset": bson.M{"position": ""},
+					"$inc":   bumpVersion,
+				}))
+		}
+		res, err := col.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil || res.ModifiedCount == 0 {
+			continue
+		}
+
+		// Send the ranks as they are now, skipped documents included.
+		rankCursor, err := col.Find(ctx, bson.M{groupField: g.ID}, options.Find().SetProjection(bson.M{"_id": 1, "rank": 1}))
+		if err != nil {
+			continue
+		}
+		var current []struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Rank string             `bson:"rank"`
+		}
+		rankCursor.All(ctx, &current)
+		rankCursor.Close(ctx)
+
+		newRanks := map[string]string{}
+		for _, d := range current {
+			newRanks[d.ID.Hex()] = d.Rank
+		}
+		publishBoardEvent(ctx, docs[0].ProjectID, primitive.NilObjectID, "ranks.rebalanced",
+			fiber.Map{"kind": kind, "group_id": g.ID, "ranks": newRanks})
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+	}{
+		{name: "empty list", a: "", b: ""},
+		{name: "before first", a: "", b: "i"},
+		{name: "after last", a: "i", b: ""},
+		{name: "wide gap", a: "a", b: "z"},
+		{name: "adjacent digits", a: "a", b: "b"},
+		{name: "shared prefix", a: "ab", b: "ac"},
+		{name: "prefix of b", a: "a", b: "a1"},
+		{name: "before smallest", a: "", b: "01"},
+		{name: "after largest", a: "zzz", b: ""},
+		{name: "longer a", a: "a5x", b: "b"},
+		{name: "longer b", a: "a", b: "a0001"},
+	}
+	for _, tt := range tests {
+		got := rankBetween(tt.a, tt.b)
+		if got <= tt.a || (tt.b != "" && got >= tt.b) {
+			t.Errorf("%s: rankBetween(%q, %q) = %q, not strictly between", tt.name, tt.a, tt.b, got)
+		}
+		if strings.HasSuffix(got, "0") {
+			t.Errorf("%s: rankBetween(%q, %q) = %q ends in 0", tt.name, tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestRankBetweenTie(t *testing.T) {
+	for _, pair := range [][2]string{{"m", "m"}, {"n", "m"}} {
+		if got := rankBetween(pair[0], pair[1]); got <= pair[0] {
+			t.Errorf("rankBetween(%q, %q) = %q, want after %q", pair[0], pair[1], got, pair[0])
+		}
+	}
+}
+
+func TestRankBetweenRepeatedInserts(t *testing.T) {
+	// Inserting at the same spot over and over must keep ranks ordered and
+	// grow them slowly.
+	lo, hi := "", ""
+	for i := 0; i < 200; i++ {
+		r := rankBetween(lo, hi)
+		if r <= lo || (hi != "" && r >= hi) {
+			t.Fatalf("step %d: %q not between %q and %q", i, r, lo, hi)
+		}
+		if i%2 == 0 {
+			hi = r
+		} else {
+			lo = r
+		}
+	}
+	if len(hi) > 200 {
+		t.Errorf("ranks grew to %d characters", len(hi))
+	}
+}
+
+func TestSpreadRanks(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 17, 35, 36, 100, 5000} {
+		ranks := spreadRanks(n)
+		if len(ranks) != n {
+			t.Fatalf("spreadRanks(%d) returned %d ranks", n, len(ranks))
+		}
+		for i, r := range ranks {
+			if r == "" || strings.HasSuffix(r, "0") {
+				t.Errorf("spreadRanks(%d)[%d] = %q", n, i, r)
+			}
+			if len(r) > maxRankLength {
+				t.Errorf("spreadRanks(%d)[%d] = %q is longer than %d", n, i, r, maxRankLength)
+			}
+			if i > 0 && r <= ranks[i-1] {
+				t.Errorf("spreadRanks(%d) not ascending at %d: %q <= %q", n, i, r, ranks[i-1])
+			}
+		}
+		if n > 0 {
+			// Room must remain at both ends of the list.
+			if rankBetween("", ranks[0]) >= ranks[0] || rankBetween(ranks[n-1], "") <= ranks[n-1] {
+				t.Errorf("spreadRanks(%d) leaves no room at the ends", n)
+			}
+		}
+	}
+}
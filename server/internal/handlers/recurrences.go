@@ -0,0 +1,579 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// parseRecurrenceStart accepts a date (midnight UTC) or an RFC 3339 time.
+func parseRecurrenceStart(raw string) (time.Time, bool) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// nextRecurrenceRun returns the first occurrence after now, counting the
+// start itself when it lies in the future.
+func nextRecurrenceRun(rule recurrenceRule, start, now time.Time) *time.Time {
+	after := now
+	if start.After(now) {
+		after = start.Add(-time.Second)
+	}
+	if next, ok := rule.next(start, after); ok {
+		return &next
+	}
+	return nil
+}
+
+func recurrenceResponse(r models.Recurrence) fiber.Map {
+	upcoming := []time.Time{}
+	if rule, err := parseRRule(r.Rule); err == nil && !r.Paused && r.NextRunAt != nil {
+		upcoming = append([]time.Time{*r.NextRunAt}, rule.upcoming(r.StartAt, *r.NextRunAt, 2)...)
+	}
+	return fiber.Map{"recurrence": r, "upcoming": upcoming}
+}
+
+type recurrenceBody struct {
+	ColumnID         *string  `json:"column_id"`
+	LaneID           *string  `json:"lane_id"`
+	Rule             *string  `json:"rule"`
+	StartAt          *string  `json:"start_at"`
+	DueOffsetDays    *int     `json:"due_offset_days"`
+	CardID           string   `json:"card_id"`
+	Title            *string  `json:"title"`
+	Description      *string  `json:"description"`
+	Priority         *string  `json:"priority"`
+	Color            *string  `json:"color"`
+	Assignees        []string `json:"assignees"`
+	LabelIDs         []string `json:"label_ids"`
+	Subtasks         []string `json:"subtasks"`
+	EstimatedMinutes *int     `json:"estimated_minutes"`
+}
+
+// applyRecurrenceBody copies the provided fields of body onto r, validating
+// references against the project. It returns a client-facing error message.
+func applyRecurrenceBody(ctx context.Context, r *models.Recurrence, body recurrenceBody) string {
+	if body.CardID != "" {
+		cardID, err := primitive.ObjectIDFromHex(body.CardID)
+		if err != nil {
+			return "Invalid card_id"
+		}
+		var card models.Card
+		if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID, "project_id": r.ProjectID}).Decode(&card); err != nil {
+			return "Card not found in this project"
+		}
+		subtasks := []string{}
+		for _, st := range card.Subtasks {
+			subtasks = append(subtasks, st.Text)
+		}
+		r.Template = models.RecurrenceTemplate{
+			Title:            card.Title,
+			Description:      card.Description,
+			Priority:         card.Priority,
+			Color:            card.Color,
+			Assignees:        card.Assignees,
+			LabelIDs:         card.LabelIDs,
+			Subtasks:         subtasks,
+			EstimatedMinutes: card.EstimatedMinutes,
+		}
+		if body.ColumnID == nil {
+			r.ColumnID = card.ColumnID
+		}
+		if body.LaneID == nil && card.LaneID != nil {
+			r.LaneID = card.LaneID
+		}
+	}
+
+	if body.ColumnID != nil {
+		columnID, err := primitive.ObjectIDFromHex(*body.ColumnID)
+		if err != nil {
+			return "Invalid column_id"
+		}
+		r.ColumnID = columnID
+	}
+	if n, _ := database.GetCollection("board_columns").CountDocuments(ctx, bson.M{"_id": r.ColumnID, "project_id": r.ProjectID}); n == 0 {
+		return "column_id must be a column of this project"
+	}
+
+	if body.LaneID != nil {
+		r.LaneID = nil
+		if *body.LaneID != "" {
+			laneID, err := primitive.ObjectIDFromHex(*body.LaneID)
+			if err != nil {
+				return "Invalid lane_id"
+			}
+			if n, _ := database.GetCollection("swimlanes").CountDocuments(ctx, bson.M{"_id": laneID, "project_id": r.ProjectID}); n == 0 {
+				return "Invalid lane_id"
+			}
+			r.LaneID = &laneID
+		}
+	}
+
+	if body.Rule != nil {
+		r.Rule = strings.TrimPrefix(strings.TrimSpace(*body.Rule), "RRULE:")
+	}
+	if _, err := parseRRule(r.Rule); err != nil {
+		return "Invalid rule: " + err.Error()
+	}
+	if body.StartAt != nil {
+		start, ok := parseRecurrenceStart(*body.StartAt)
+		if !ok {
+			return "start_at must be YYYY-MM-DD or RFC 3339"
+		}
+		r.StartAt = start
+	}
+	if body.DueOffsetDays != nil {
+		if *body.DueOffsetDays < 0 {
+			r.DueOffsetDays = nil
+		} else {
+			r.DueOffsetDays = body.DueOffsetDays
+		}
+	}
+
+	t := &r.Template
+	if body.Title != nil {
+		t.Title = strings.TrimSpace(*body.Title)
+	}
+	if body.Description != nil {
+		t.Description = *body.Description
+	}
+	if body.Priority != nil {
+		t.Priority = *body.Priority
+	}
+	if body.Color != nil {
+		t.Color = *body.Color
+	}
+	if body.Assignees != nil {
+		t.Assignees = body.Assignees
+	}
+	if body.LabelIDs != nil {
+		labelIDs, err := resolveLabelIDs(ctx, r.ProjectID, body.LabelIDs)
+		if err != nil {
+			return "Unknown label in label_ids"
+		}
+		t.LabelIDs = labelIDs
+	}
+	if body.Subtasks != nil {
+		t.Subtasks = body.Subtasks
+	}
+	if body.EstimatedMinutes != nil {
+		t.EstimatedMinutes = body.EstimatedMinutes
+	}
+
+	if t.Title == "" {
+		return "Title is required"
+	}
+	if t.Priority == "" {
+		t.Priority = "Medium"
+	}
+	if t.Color == "" {
+		t.Color = "neutral"
+	}
+	if t.Assignees == nil {
+		t.Assignees = []string{}
+	}
+	if t.LabelIDs == nil {
+		t.LabelIDs = []primitive.ObjectID{}
+	}
+	if t.Subtasks == nil {
+		t.Subtasks = []string{}
+	}
+	return ""
+}
+
+func ListRecurrences(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	cursor, err := database.GetCollection("recurrences").Find(ctx, bson.M{"project_id": projectID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch recurrences"})
+	}
+	defer cursor.Close(ctx)
+
+	var recurrences []models.Recurrence
+	cursor.All(ctx, &recurrences)
+
+	result := []fiber.Map{}
+	for _, r := range recurrences {
+		result = append(result, recurrenceResponse(r))
+	}
+	return c.JSON(result)
+}
+
+func CreateRecurrence(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body recurrenceBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if body.Rule == nil || (body.ColumnID == nil && body.CardID == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rule and column_id (or card_id) are required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	now := time.Now()
+	r := models.Recurrence{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		StartAt:   now.UTC(),
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if msg := applyRecurrenceBody(ctx, &r, body); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	rule, _ := parseRRule(r.Rule)
+	r.NextRunAt = nextRecurrenceRun(rule, r.StartAt, now)
+
+	if _, err := database.GetCollection("recurrences").InsertOne(ctx, r); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create recurrence"})
+	}
+	recordActivity(ctx, projectID, userID, "created", "recurrence", r.ID, r.Template.Title,
+		[]models.ActivityChange{{Field: "rule", To: r.Rule}})
+
+	return c.Status(fiber.StatusCreated).JSON(recurrenceResponse(r))
+}
+
+func UpdateRecurrence(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	recurrenceID, err := primitive.ObjectIDFromHex(c.Params("recurrenceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recurrence ID"})
+	}
+
+	var body recurrenceBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("recurrences")
+	var r models.Recurrence
+	if err := col.FindOne(ctx, bson.M{"_id": recurrenceID, "project_id": projectID}).Decode(&r); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Recurrence not found"})
+	}
+	before := r
+
+	if msg := applyRecurrenceBody(ctx, &r, body); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	now := time.Now()
+	if r.Rule != before.Rule || !r.StartAt.Equal(before.StartAt) {
+		rule, _ := parseRRule(r.Rule)
+		r.NextRunAt = nextRecurrenceRun(rule, r.StartAt, now)
+	}
+	r.UpdatedAt = now
+
+	col.ReplaceOne(ctx, bson.M{"_id": recurrenceID}, r)
+
+	changes := appendChange(nil, "rule", before.Rule, r.Rule)
+	changes = appendChange(changes, "title", before.Template.Title, r.Template.Title)
+	changes = appendChange(changes, "column", columnTitle(ctx, before.ColumnID), columnTitle(ctx, r.ColumnID))
+	if len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "recurrence", r.ID, r.Template.Title, changes)
+	}
+
+	return c.JSON(recurrenceResponse(r))
+}
+
+func PauseRecurrence(c *fiber.Ctx) error {
+	return setRecurrencePaused(c, true)
+}
+
+func ResumeRecurrence(c *fiber.Ctx) error {
+	return setRecurrencePaused(c, false)
+}
+
+func setRecurrencePaused(c *fiber.Ctx, paused bool) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	recurrenceID, err := primitive.ObjectIDFromHex(c.Params("recurrenceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recurrence ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("recurrences")
+	var r models.Recurrence
+	if err := col.FindOne(ctx, bson.M{"_id": recurrenceID, "project_id": projectID}).Decode(&r); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Recurrence not found"})
+	}
+
+	now := time.Now()
+	r.Paused = paused
+	r.UpdatedAt = now
+	update := bson.M{"paused": paused, "updated_at": now}
+	if !paused {
+		// Occurrences missed while paused are skipped, not backfilled.
+		rule, _ := parseRRule(r.Rule)
+		r.NextRunAt = nextRecurrenceRun(rule, r.StartAt, now)
+		update["next_run_at"] = r.NextRunAt
+	}
+	col.UpdateOne(ctx, bson.M{"_id": recurrenceID}, bson.M{"$set": update})
+
+	verb := "resumed"
+	if paused {
+		verb = "paused"
+	}
+	recordActivity(ctx, projectID, userID, verb, "recurrence", r.ID, r.Template.Title, nil)
+
+	return c.JSON(recurrenceResponse(r))
+}
+
+func DeleteRecurrence(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	recurrenceID, err := primitive.ObjectIDFromHex(c.Params("recurrenceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid recurrence ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var r models.Recurrence
+	if err := database.GetCollection("recurrences").FindOne(ctx, bson.M{"_id": recurrenceID, "project_id": projectID}).Decode(&r); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Recurrence not found"})
+	}
+
+	database.GetCollection("recurrences").DeleteOne(ctx, bson.M{"_id": recurrenceID})
+	recordActivity(ctx, projectID, userID, "deleted", "recurrence", r.ID, r.Template.Title, nil)
+
+	return c.JSON(fiber.Map{"message": "Recurrence deleted"})
+}
+
+// RunRecurrences materialises a card for every active recurrence that is
+// due. Each recurrence is claimed by advancing next_run_at before the card
+// is created, so concurrent runs never produce duplicates. Missed
+// occurrences (e.g. while the server was down) collapse into one card.
+func RunRecurrences(ctx context.Context) {
+	now := time.Now()
+	col := database.GetCollection("recurrences")
+
+	cursor, err := col.Find(ctx, bson.M{"paused": false, "next_run_at": bson.M{"$lte": now}})
+	if err != nil {
+		return
+	}
+	var due []models.Recurrence
+	cursor.All(ctx, &due)
+	cursor.Close(ctx)
+
+	for _, r := range due {
+		rule, err := parseRRule(r.Rule)
+		if err != nil {
+			col.UpdateOne(ctx, bson.M{"_id": r.ID}, bson.M{"$set": bson.M{"paused": true}})
+			continue
+		}
+
+		// Recurrences of an archived project stay due, unclaimed, until it
+		// is unarchived; the missed occurrences then collapse into one card.
+		if ensureProjectWritable(ctx, r.ProjectID) != nil {
+			continue
+		}
+
+		occurrence := *r.NextRunAt
+		next := nextRecurrenceRun(rule, r.StartAt, now)
+		res, err := col.UpdateOne(ctx,
+			bson.M{"_id": r.ID, "next_run_at": r.NextRunAt},
+			bson.M{"$set": bson.M{"next_run_at": next, "last_run_at": now}},
+		)
+		if err != nil || res.ModifiedCount == 0 {
+			continue
+		}
+
+		var column models.BoardColumn
+		if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": r.ColumnID, "project_id": r.ProjectID, "archived_at": nil}).Decode(&column); err != nil {
+			// The target column is gone; stop until someone picks a new one.
+			col.UpdateOne(ctx, bson.M{"_id": r.ID}, bson.M{"$set": bson.M{"paused": true}})
+			continue
+		}
+
+		card := materializeRecurrence(ctx, r, column, occurrence)
+		if card == nil {
+			continue
+		}
+		col.UpdateOne(ctx, bson.M{"_id": r.ID}, bson.M{
+			"$set": bson.M{"last_card_id": card.ID},
+			"$inc": bson.M{"generated": 1},
+		})
+	}
+}
+
+func materializeRecurrence(ctx context.Context, r models.Recurrence, column models.BoardColumn, occurrence time.Time) *models.Card {
+	t := r.Template
+
+	var dueDate *time.Time
+	if r.DueOffsetDays != nil {
+		d := time.Date(occurrence.Year(), occurrence.Month(), occurrence.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, *r.DueOffsetDays)
+		dueDate = &d
+	}
+
+	subtasks := []models.Subtask{}
+	for i, text := range t.Subtasks {
+		subtasks = append(subtasks, models.Subtask{ID: i + 1, Text: text})
+	}
+
+	now := time.Now()
+	recurrenceID := r.ID
+	card := &models.Card{
+		ID:               primitive.NewObjectID(),
+		ColumnID:         column.ID,
+		LaneID:           r.LaneID,
+		RecurrenceID:     &recurrenceID,
+		ProjectID:        r.ProjectID,
+		Title:            t.Title,
+		Description:      t.Description,
+		Priority:         t.Priority,
+		Color:            t.Color,
+		DueDate:          dueDate,
+		Assignees:        t.Assignees,
+		LabelIDs:         t.LabelIDs,
+		EstimatedMinutes: t.EstimatedMinutes,
+		Subtasks:         subtasks,
+		SubtaskSeq:       len(subtasks),
+		Rank:             rankAt(ctx, "cards", bson.M{"column_id": column.ID}, primitive.NilObjectID, -1),
+		CreatedBy:        r.CreatedBy,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	violations := checkWIP(ctx, column, *card)
+	if len(violations) > 0 && wipBlocks(column) {
+		log.Printf("RunRecurrences: skipped %s, column %s is at its WIP limit", r.ID.Hex(), column.ID.Hex())
+		return nil
+	}
+
+	card.Number, card.Key = nextCardKey(ctx, r.ProjectID)
+	if _, err := database.GetCollection("cards").InsertOne(ctx, card); err != nil {
+		log.Printf("RunRecurrences: insert failed for %s: %v", r.ID.Hex(), err)
+		return nil
+	}
+	recordCardTransition(ctx, *card, nil, &column.ID, r.CreatedBy)
+	recordActivity(ctx, r.ProjectID, r.CreatedBy, "created", "card", card.ID, card.Title,
+		[]models.ActivityChange{{Field: "column", To: column.Title}, {Field: "recurrence", To: r.Rule}})
+	if len(violations) > 0 {
+		notifyWIPExceeded(ctx, column, *card, r.CreatedBy, violations)
+	}
+	autoWatchCard(ctx, *card)
+	publishBoardEvent(ctx, r.ProjectID, r.CreatedBy, "card.created", card)
+	fireAutomations(automationEvent{Type: "card.created", Card: *card})
+
+	for _, email := range card.Assignees {
+		var assignee models.User
+		if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&assignee); err != nil {
+			continue
+		}
+		createNotification(ctx, assignee.ID, "assign",
+			"You have been assigned to the task \""+card.Title+"\"",
+			card.ProjectID, card.ID)
+	}
+	return card
+}
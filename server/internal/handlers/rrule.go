@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrenceRule is the supported subset of RFC 5545 RRULE: FREQ (DAILY,
+// WEEKLY, MONTHLY), INTERVAL, BYDAY (daily and weekly only), BYMONTHDAY
+// (monthly only, negative counts from the month end), UNTIL and COUNT.
+type recurrenceRule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+	Until      *time.Time
+	Count      int
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// maxRecurrenceScan bounds, in days, how far ahead next() looks for a match.
+const maxRecurrenceScan = 10 * 366
+
+func parseRRule(raw string) (recurrenceRule, error) {
+	rule := recurrenceRule{Interval: 1}
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "RRULE:")
+	if raw == "" {
+		return rule, errors.New("rule is required")
+	}
+
+	for _, part := range strings.Split(raw, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("malformed rule part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		switch key {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" && value != "MONTHLY" {
+				return rule, errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, errors.New("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[strings.TrimSpace(d)]
+				if !ok {
+					return rule, fmt.Errorf("unsupported BYDAY value %q", d)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -31 || n > 31 {
+				return rule, errors.New("BYMONTHDAY must be between 1 and 31 or -31 and -1")
+			}
+			rule.ByMonthDay = n
+		case "UNTIL":
+			var until time.Time
+			var err error
+			if len(value) == 8 {
+				until, err = time.Parse("20060102", value)
+				until = until.Add(24*time.Hour - time.Second)
+			} else {
+				until, err = time.Parse("20060102T150405Z", value)
+			}
+			if err != nil {
+				return rule, errors.New("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+			}
+			rule.Until = &until
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return rule, errors.New("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		default:
+			return rule, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, errors.New("FREQ is required")
+	}
+	if rule.Until != nil && rule.Count > 0 {
+		return rule, errors.New("UNTIL and COUNT cannot be combined")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == "MONTHLY" {
+		return rule, errors.New("BYDAY is only supported with DAILY or WEEKLY")
+	}
+	if rule.ByMonthDay != 0 && rule.Freq != "MONTHLY" {
+		return rule, errors.New("BYMONTHDAY is only supported with MONTHLY")
+	}
+	return rule, nil
+}
+
+func (r recurrenceRule) hasDay(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// daysAfter is the offset in whole days from base of the first time of day
+// equal to base's that lies strictly after t.
+func daysAfter(base, t time.Time) int {
+	if t.Before(base) {
+		return 0
+	}
+	return int(t.Sub(base)/(24*time.Hour)) + 1
+}
+
+// next returns the first occurrence strictly after `after`, or false when
+// the series has ended or nothing matches within maxRecurrenceScan days.
+// Occurrences fall at start's time of day, on or after start.
+func (r recurrenceRule) next(start, after time.Time) (time.Time, bool) {
+	start = start.UTC()
+	from := after
+	if start.After(from) {
+		// Start itself may be the first occurrence.
+		from = start.Add(-time.Nanosecond)
+	}
+
+	var d time.Time
+	var n int
+	ok := false
+	switch r.Freq {
+	case "DAILY":
+		d, n, ok = r.nextDaily(start, from)
+	case "WEEKLY":
+		d, n, ok = r.nextWeekly(start, from)
+	case "MONTHLY":
+		d, n, ok = r.nextMonthly(start, from)
+	}
+	if !ok || d.After(from.AddDate(0, 0, maxRecurrenceScan)) {
+		return time.Time{}, false
+	}
+	if r.Until != nil && d.After(*r.Until) {
+		return time.Time{}, false
+	}
+	if r.Count > 0 && n > r.Count {
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+// nextDaily finds the first occurrence after from and its 1-based position
+// in the series. Occurrences are every Interval days from start, limited to
+// ByDay when given; their weekdays repeat every 7 occurrences.
+func (r recurrenceRule) nextDaily(start, from time.Time) (time.Time, int, bool) {
+	step := r.Interval
+	m := (daysAfter(start, from) + step - 1) / step
+	if len(r.ByDay) == 0 {
+		return start.AddDate(0, 0, m*step), m + 1, true
+	}
+
+	matches := func(i int) bool {
+		return r.hasDay(start.AddDate(0, 0, i*step).Weekday())
+	}
+	found := false
+	for i := m; i < m+7; i++ {
+		if matches(i) {
+			m, found = i, true
+			break
+		}
+	}
+	if !found {
+		return time.Time{}, 0, false
+	}
+
+	perCycle := 0
+	for i := 0; i < 7; i++ {
+		if matches(i) {
+			perCycle++
+		}
+	}
+	n := (m + 1) / 7 * perCycle
+	for i := (m + 1) / 7 * 7; i <= m; i++ {
+		if matches(i) {
+			n++
+		}
+	}
+	return start.AddDate(0, 0, m*step), n, true
+}
+
+// nextWeekly finds the first occurrence after from and its 1-based position
+// in the series. Weeks start on Monday; every Interval-th week counted from
+// start's holds an occurrence on each ByDay weekday (start's by default).
+func (r recurrenceRule) nextWeekly(start, from time.Time) (time.Time, int, bool) {
+	offset := func(wd time.Weekday) int { return (int(wd) + 6) % 7 }
+	days := make([]int, 0, 7)
+	for i := 0; i < 7; i++ {
+		wd := time.Weekday((i + 1) % 7)
+		if (len(r.ByDay) == 0 && wd == start.Weekday()) || r.hasDay(wd) {
+			days = append(days, i)
+		}
+	}
+
+	startDay := offset(start.Weekday())
+	base := start.AddDate(0, 0, -startDay)
+	// Weekdays of the first week that come before start are not part of
+	// the series.
+	skipped := 0
+	for _, o := range days {
+		if o < startDay {
+			skipped++
+		}
+	}
+
+	f := daysAfter(base, from)
+	if f < startDay {
+		f = startDay
+	}
+	week, pos := f/7, f%7
+	if week%r.Interval != 0 {
+		week, pos = (week/r.Interval+1)*r.Interval, 0
+	}
+	idx := -1
+	for i, o := range days {
+		if o >= pos {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		week, idx = week+r.Interval, 0
+	}
+
+	n := week/r.Interval*len(days) + idx + 1 - skipped
+	return base.AddDate(0, 0, week*7+days[idx]), n, true
+}
+
+// nextMonthly finds the first occurrence after from and its 1-based
+// position in the series. Every Interval-th month from start's has one on
+// ByMonthDay (start's day by default); months without that day are
+// skipped.
+func (r recurrenceRule) nextMonthly(start, from time.Time) (time.Time, int, bool) {
+	hour, minute, sec := start.Clock()
+	monthStart := func(m int) time.Time {
+		return time.Date(start.Year(), start.Month()+time.Month(m), 1, hour, minute, sec, start.Nanosecond(), time.UTC)
+	}
+	occurrence := func(m int) (time.Time, bool) {
+		first := monthStart(m)
+		last := first.AddDate(0, 1, -1).Day()
+		day := r.ByMonthDay
+		if day == 0 {
+			day = start.Day()
+		} else if day < 0 {
+			day = last + day + 1
+		}
+		if day < 1 || day > last {
+			return time.Time{}, false
+		}
+		d := first.AddDate(0, 0, day-1)
+		return d, !d.Before(start)
+	}
+
+	m := 0
+	if from.After(start) {
+		m = (from.Year()-start.Year())*12 + int(from.Month()) - int(start.Month())
+		m = m / r.Interval * r.Interval
+	}
+
+	// Positions only matter for COUNT, and counting months one by one is
+	// bounded by COUNT itself.
+	n := 0
+	if r.Count > 0 {
+		for i := 0; i < m; i += r.Interval {
+			if _, ok := occurrence(i); ok {
+				n++
+			}
+		}
+	}
+
+	limit := from.AddDate(0, 0, maxRecurrenceScan)
+	for ; ; m += r.Interval {
+		d, ok := occurrence(m)
+		if ok {
+			n++
+			if d.After(from) {
+				return d, n, true
+			}
+		}
+		if monthStart(m).After(limit) {
+			return time.Time{}, 0, false
+		}
+	}
+}
+
+// upcoming returns up to n occurrences after `after`.
+func (r recurrenceRule) upcoming(start, after time.Time, n int) []time.Time {
+	dates := []time.Time{}
+	for len(dates) < n {
+		d, ok := r.next(start, after)
+		if !ok {
+			break
+		}
+		dates = append(dates, d)
+		after = d
+	}
+	return dates
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	until := time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)
+	tests := []struct {
+		raw     string
+		want    recurrenceRule
+		wantErr bool
+	}{
+		{raw: "FREQ=DAILY", want: recurrenceRule{Freq: "DAILY", Interval: 1}},
+		{raw: "RRULE:freq=weekly;interval=2;byday=MO,FR", want: recurrenceRule{Freq: "WEEKLY", Interval: 2, ByDay: []time.Weekday{time.Monday, time.Friday}}},
+		{raw: "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", want: recurrenceRule{Freq: "MONTHLY", Interval: 1, ByMonthDay: -1, Count: 3}},
+		{raw: "FREQ=DAILY;UNTIL=20260301", want: recurrenceRule{Freq: "DAILY", Interval: 1, Until: &until}},
+		{raw: "", wantErr: true},
+		{raw: "INTERVAL=2", wantErr: true},
+		{raw: "FREQ=YEARLY", wantErr: true},
+		{raw: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{raw: "FREQ=DAILY;BYDAY=XX", wantErr: true},
+		{raw: "FREQ=MONTHLY;BYDAY=MO", wantErr: true},
+		{raw: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{raw: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{raw: "FREQ=DAILY;COUNT=2;UNTIL=20260301", wantErr: true},
+		{raw: "FREQ=DAILY;UNTIL=tomorrow", wantErr: true},
+		{raw: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{raw: "FREQ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRRule(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRRule(%q) = %+v, want error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRRule(%q) error: %v", tt.raw, err)
+			continue
+		}
+		if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval || got.ByMonthDay != tt.want.ByMonthDay ||
+			got.Count != tt.want.Count || len(got.ByDay) != len(tt.want.ByDay) {
+			t.Errorf("parseRRule(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			continue
+		}
+		for i := range got.ByDay {
+			if got.ByDay[i] != tt.want.ByDay[i] {
+				t.Errorf("parseRRule(%q).ByDay = %v, want %v", tt.raw, got.ByDay, tt.want.ByDay)
+			}
+		}
+		if (got.Until == nil) != (tt.want.Until == nil) || (got.Until != nil && !got.Until.Equal(*tt.want.Until)) {
+			t.Errorf("parseRRule(%q).Until = %v, want %v", tt.raw, got.Until, tt.want.Until)
+		}
+	}
+}
+
+func TestRecurrenceRuleNext(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	// 2026-01-05 is a Monday.
+	monday := day(2026, 1, 5)
+	tests := []struct {
+		name   string
+		rule   string
+		start  time.Time
+		after  time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{"start is the first occurrence", "FREQ=DAILY", monday, monday.Add(-time.Hour), monday, true},
+		{"daily", "FREQ=DAILY", monday, monday, day(2026, 1, 6), true},
+		{"daily keeps start time", "FREQ=DAILY", monday.Add(9 * time.Hour), day(2026, 1, 7), day(2026, 1, 7).Add(9 * time.Hour), true},
+		{"every third day", "FREQ=DAILY;INTERVAL=3", monday, day(2026, 1, 9), day(2026, 1, 11), true},
+		{"weekdays skip the weekend", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", monday, day(2026, 1, 9), day(2026, 1, 12), true},
+		{"every other day, saturdays only", "FREQ=DAILY;INTERVAL=2;BYDAY=SA", monday, monday, day(2026, 1, 17), true},
+		{"interval that never meets BYDAY", "FREQ=DAILY;INTERVAL=7;BYDAY=TU", monday, monday, time.Time{}, false},
+		{"weekly on start weekday", "FREQ=WEEKLY", monday, monday, day(2026, 1, 12), true},
+		{"biweekly on two days", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,FR", monday, day(2026, 1, 9), day(2026, 1, 20), true},
+		{"weekly skips days before start", "FREQ=WEEKLY;BYDAY=MO,TH", day(2026, 1, 7), day(2026, 1, 1), day(2026, 1, 8), true},
+		{"weekly sunday ends the week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", monday, monday, day(2026, 1, 11), true},
+		{"monthly on start day", "FREQ=MONTHLY", day(2026, 1, 15), day(2026, 1, 15), day(2026, 2, 15), true},
+		{"monthly skips short months", "FREQ=MONTHLY;BYMONTHDAY=31", day(2026, 1, 31), day(2026, 1, 31), day(2026, 3, 31), true},
+		{"monthly last day", "FREQ=MONTHLY;BYMONTHDAY=-1", day(2026, 1, 1), day(2026, 1, 31), day(2026, 2, 28), true},
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3", day(2026, 1, 10), day(2026, 2, 1), day(2026, 4, 10), true},
+		{"count reached", "FREQ=DAILY;COUNT=3", monday, day(2026, 1, 7), time.Time{}, false},
+		{"last counted occurrence", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3", monday, day(2026, 1, 7), day(2026, 1, 12), true},
+		{"until passed", "FREQ=DAILY;UNTIL=20260110", monday, day(2026, 1, 10), time.Time{}, false},
+		{"until is inclusive", "FREQ=DAILY;UNTIL=20260110", monday, day(2026, 1, 9), day(2026, 1, 10), true},
+		{"far from start", "FREQ=DAILY;INTERVAL=5", monday, day(2030, 6, 1), day(2030, 6, 3), true},
+	}
+	for _, tt := range tests {
+		rule, err := parseRRule(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: parseRRule(%q): %v", tt.name, tt.rule, err)
+		}
+		got, ok := rule.next(tt.start, tt.after)
+		if ok != tt.wantOK || (ok && !got.Equal(tt.want)) {
+			t.Errorf("%s: next(%v, %v) = %v, %v; want %v, %v", tt.name, tt.start, tt.after, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRecurrenceRuleUpcoming(t *testing.T) {
+	rule, _ := parseRRule("FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := rule.upcoming(start, start, 10)
+	want := []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}
+	if len(got) != len(want) {
+		t.Fatalf("upcoming = %v, want %v", got, want)
+	}
+	for i, d := range got {
+		if d.Format("2006-01-02") != want[i] {
+			t.Errorf("upcoming[%d] = %s, want %s", i, d.Format("2006-01-02"), want[i])
+		}
+	}
+}
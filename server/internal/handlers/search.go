@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// searchIndexes lists the weighted fields of the single text index each
+// searchable collection carries.
+var searchIndexes = map[string]bson.D{
+	"cards":         {{Key: "title", Value: 10}, {Key: "subtasks.text", Value: 3}, {Key: "description", Value: 2}},
+	"docs":          {{Key: "title", Value: 10}, {Key: "content", Value: 2}},
+	"chat_messages": {{Key: "content", Value: 1}},
+	"files":         {{Key: "name", Value: 1}},
+	"events":        {{Key: "title", Value: 10}, {Key: "description", Value: 2}},
+	"projects":      {{Key: "name", Value: 10}, {Key: "description", Value: 2}},
+}
+
+// EnsureSearchIndexes creates the text indexes global search relies on.
+// It is safe to call on every start.
+func EnsureSearchIndexes(ctx context.Context) {
+	for collection, weights := range searchIndexes {
+		keys := bson.D{}
+		for _, w := range weights {
+			keys = append(keys, bson.E{Key: w.Key, Value: "text"})
+		}
+		_, err := database.GetCollection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    keys,
+			Options: options.Index().SetName("search_text").SetWeights(weights).SetDefaultLanguage("none"),
+		})
+		if err != nil {
+			log.Printf("EnsureSearchIndexes: %s: %v", collection, err)
+		}
+	}
+}
+
+type searchHit struct {
+	Type        string              `json:"type"`
+	ID          primitive.ObjectID  `json:"id"`
+	Title       string              `json:"title"`
+	Highlight   string              `json:"highlight"`
+	Score       float64             `json:"score"`
+	ProjectID   *primitive.ObjectID `json:"project_id,omitempty"`
+	ProjectName string              `json:"project_name,omitempty"`
+	TeamID      *primitive.ObjectID `json:"team_id,omitempty"`
+	TeamName    string              `json:"team_name,omitempty"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+type searchSource struct {
+	Type       string
+	Collection string
+	Filter     bson.M
+	Hit        func(raw bson.Raw, terms []string) searchHit
+}
+
+// searchTerms extracts the words of q used for highlighting; $text does its
+// own tokenising for matching.
+func searchTerms(q string) []string {
+	terms := []string{}
+	for _, f := range strings.FieldsFunc(q, func(r rune) bool {
+		return r == ' ' || r == '"' || r == '\t'
+	}) {
+		f = strings.TrimPrefix(f, "-")
+		if f != "" {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+const searchSnippetRunes = 160
+
+// highlight returns an HTML-escaped excerpt of text around the first term
+// match, with every match wrapped in <mark>. The excerpt is empty when
+// nothing matches.
+func highlight(text string, terms []string) string {
+	if len(terms) == 0 || text == "" {
+		return ""
+	}
+	quoted := []string{}
+	for _, t := range terms {
+		quoted = append(quoted, regexp.QuoteMeta(t))
+	}
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	loc := pattern.FindStringIndex(text)
+	if loc == nil {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if utf8.RuneCountInString(text) > searchSnippetRunes {
+		start = loc[0] - searchSnippetRunes/3
+		if start < 0 {
+			start = 0
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		end = start + searchSnippetRunes
+		if end > len(text) {
+			end = len(text)
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+	}
+	excerpt := text[start:end]
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	last := 0
+	for _, m := range pattern.FindAllStringIndex(excerpt, -1) {
+		b.WriteString(html.EscapeString(excerpt[last:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(excerpt[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(excerpt[last:]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// firstHighlight highlights the first field that contains a match. When
+// only a stemmed form matched, the start of the first non-empty field is
+// returned instead.
+func firstHighlight(terms []string, fields ...string) string {
+	for _, f := range fields {
+		if h := highlight(f, terms); h != "" {
+			return h
+		}
+	}
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		if r := []rune(f); len(r) > searchSnippetRunes {
+			return html.EscapeString(string(r[:searchSnippetRunes])) + "…"
+		}
+		return html.EscapeString(f)
+	}
+	return ""
+}
+
+func textScore(raw bson.Raw) float64 {
+	if v, err := raw.LookupErr("score"); err == nil {
+		if f, ok := v.DoubleOK(); ok {
+			return f
+		}
+	}
+	return 0
+}
+
+func searchSources(userID primitive.ObjectID, projectIDs, teamIDs []primitive.ObjectID) []searchSource {
+	idPtr := func(id primitive.ObjectID) *primitive.ObjectID {
+		if id.IsZero() {
+			return nil
+		}
+		return &id
+	}
+
+	return []searchSource{
+		{
+			Type: "cards", Collection: "cards",
+			Filter: bson.M{"project_id": bson.M{"$in": projectIDs}, "archived_at": nil},
+			Hit: func(raw bson.Raw, terms []string) searchHit {
+				var card models.Card
+				bson.Unmarshal(raw, &card)
+				fields := []string{card.Title, card.Description}
+				for _, st := range card.Subtasks {
+					fields = append(fields, st.Text)
+				}
+				return searchHit{ID: card.ID, Title: card.Title, Highlight: firstHighlight(terms, fields...),
+					ProjectID: idPtr(card.ProjectID), UpdatedAt: card.UpdatedAt}
+			},
+		},
+		{
+			Type: "docs", Collection: "docs",
+			Filter: bson.M{"team_id": bson.M{"$in": teamIDs}},
+			Hit: func(raw bson.Raw, terms []string) searchHit {
+				var doc models.Doc
+				bson.Unmarshal(raw, &doc)
+				return searchHit{ID: doc.ID, Title: doc.Title, Highlight: firstHighlight(terms, doc.Title, doc.Content),
+					TeamID: idPtr(doc.TeamID), UpdatedAt: doc.UpdatedAt}
+			},
+		},
+		{
+			Type: "messages", Collection: "chat_messages",
+			Filter: bson.M{"team_id": bson.M{"$in": teamIDs}, "deleted": bson.M{"$ne": true}},
+			Hit: func(raw bson.Raw, terms []string) searchHit {
+				var msg models.ChatMessage
+				bson.Unmarshal(raw, &msg)
+				return searchHit{ID: msg.ID, Title: msg.UserName, Highlight: firstHighlight(terms, msg.Content),
+					TeamID: idPtr(msg.TeamID), UpdatedAt: msg.CreatedAt}
+			},
+		},
+		{
+			Type: "files", Collection: "files",
+			Filter: bson.M{"$or": bson.A{
+				bson.M{"project_id": bson.M{"$in": projectIDs}},
+				bson.M{"team_id": bson.M{"$in": teamIDs}},
+				bson.M{"user_id": userID},
+			}},
+			Hit: func(raw bson.Raw, terms []string) searchHit {
+				var file models.File
+				bson.Unmarshal(raw, &file)
+				return searchHit{ID: file.ID, Title: file.Name, Highlight: firstHighlight(terms, file.Name),
+					ProjectID: idPtr(file.ProjectID), TeamID: idPtr(file.TeamID), UpdatedAt: file.UpdatedAt}
+			},
+		},
+		{
+			Type: "events", Collection: "events",
+			Filter: bson.M{"$or": bson.A{
+				bson.M{"scope": "org", "scope_id": bson.M{"$in": teamIDs}},
+				bson.M{"scope": "project", "scope_id": bson.M{"$in": projectIDs}},
+			}},
+			Hit: func(raw bson.Raw, terms []string) searchHit {
+				var event models.Event
+				bson.Unmarshal(raw, &event)
+				hit := searchHit{ID: event.ID, Title: event.Title, Highlight: firstHighlight(terms, event.Title, event.Description),
+					UpdatedAt: event.UpdatedAt}
+				if event.Scope == "project" {
+					hit.ProjectID = idPtr(event.ScopeID)
+				} else {
+					hit.TeamID = idPtr(event.ScopeID)
+				}
+				return hit
+			},
+		},
+		{
+			Type: "projects", Collection: "projects",
+			Filter: bson.M{"_id": bson.M{"$in": projectIDs}},
+			Hit: func(raw bson.Raw, terms []string) searchHit {
+				var project models.Project
+				bson.Unmarshal(raw, &project)
+				return searchHit{ID: project.ID, Title: project.Name, Highlight: firstHighlight(terms, project.Name, project.Description),
+					ProjectID: idPtr(project.ID), TeamID: idPtr(project.TeamID), UpdatedAt: project.UpdatedAt}
+			},
+		},
+	}
+}
+
+// Search runs a full-text query over everything the caller can read and
+// returns the best matches per type. limit and offset apply to each type;
+// type restricts the search to one of them.
+func Search(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q is required"})
+	}
+
+	only := c.Query("type")
+	limit := int64(5)
+	if only != "" {
+		limit = 20
+	}
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.ParseInt(l, 10, 64); err == nil && n > 0 && n <= 50 {
+			limit = n
+		}
+	}
+	offset := int64(0)
+	if o := c.Query("offset"); o != "" {
+		if n, err := strconv.ParseInt(o, 10, 64); err == nil && n > 0 {
+			offset = n
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	projectIDs := accessibleProjectIDs(ctx, userID, true)
+	teamIDs := []primitive.ObjectID{}
+	if distinct, err := database.GetCollection("team_members").Distinct(ctx, "team_id", bson.M{"user_id": userID}); err == nil {
+		for _, v := range distinct {
+			if id, ok := v.(primitive.ObjectID); ok {
+				teamIDs = append(teamIDs, id)
+			}
+		}
+	}
+
+	sources := searchSources(userID, projectIDs, teamIDs)
+	if only != "" {
+		filtered := []searchSource{}
+		for _, s := range sources {
+			if s.Type == only {
+				filtered = append(filtered, s)
+			}
+		}
+		if len(filtered) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be one of cards, docs, messages, files, events, projects"})
+		}
+		sources = filtered
+	}
+
+	type typeResults struct {
+		Results []searchHit `json:"results"`
+		Total   int64       `json:"total"`
+		HasMore bool        `json:"has_more"`
+	}
+
+	terms := searchTerms(q)
+	projectNames := map[primitive.ObjectID]string{}
+	teamNames := map[primitive.ObjectID]string{}
+	response := fiber.Map{}
+
+	for _, s := range sources {
+		filter := bson.M{"$text": bson.M{"$search": q}}
+		for k, v := range s.Filter {
+			filter[k] = v
+		}
+		col := database.GetCollection(s.Collection)
+
+		total, err := col.CountDocuments(ctx, filter)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+		}
+
+		score := bson.M{"$meta": "textScore"}
+		cursor, err := col.Find(ctx, filter, options.Find().
+			SetProjection(bson.M{"score": score}).
+			SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: -1}}).
+			SetSkip(offset).
+			SetLimit(limit))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Search failed"})
+		}
+
+		hits := []searchHit{}
+		for cursor.Next(ctx) {
+			hit := s.Hit(cursor.Current, terms)
+			hit.Type = s.Type
+			hit.Score = textScore(cursor.Current)
+			if hit.ProjectID != nil {
+				if _, ok := projectNames[*hit.ProjectID]; !ok {
+					var p models.Project
+					database.GetCollection("projects").FindOne(ctx, bson.M{"_id": *hit.ProjectID}).Decode(&p)
+					projectNames[*hit.ProjectID] = p.Name
+				}
+				hit.ProjectName = projectNames[*hit.ProjectID]
+			}
+			if hit.TeamID != nil {
+				if _, ok := teamNames[*hit.TeamID]; !ok {
+					var t models.Team
+					database.GetCollection("teams").FindOne(ctx, bson.M{"_id": *hit.TeamID}).Decode(&t)
+					teamNames[*hit.TeamID] = t.Name
+				}
+				hit.TeamName = teamNames[*hit.TeamID]
+			}
+			hits = append(hits, hit)
+		}
+		cursor.Close(ctx)
+
+		response[s.Type] = typeResults{Results: hits, Total: total, HasMore: offset+int64(len(hits)) < total}
+	}
+
+	return c.JSON(fiber.Map{"query": q, "results": response})
+}
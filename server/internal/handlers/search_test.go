@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"", []string{}},
+		{"login  page", []string{"login", "page"}},
+		{`"login page" -draft`, []string{"login", "page", "draft"}},
+		{"\tfix -", []string{"fix"}},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{name: "no terms", text: "Fix login", terms: nil, want: ""},
+		{name: "empty text", text: "", terms: []string{"login"}, want: ""},
+		{name: "no match", text: "Fix login", terms: []string{"logout"}, want: ""},
+		{name: "case-insensitive", text: "Fix Login page", terms: []string{"login"}, want: "Fix <mark>Login</mark> page"},
+		{name: "every match", text: "a login, another login", terms: []string{"login"},
+			want: "a <mark>login</mark>, another <mark>login</mark>"},
+		{name: "several terms", text: "login page", terms: []string{"page", "login"},
+			want: "<mark>login</mark> <mark>page</mark>"},
+		{name: "html is escaped", text: `<b>login</b> & "more"`, terms: []string{"login"},
+			want: "&lt;b&gt;<mark>login</mark>&lt;/b&gt; &amp; &#34;more&#34;"},
+		{name: "terms are literal", text: "a.b axb", terms: []string{"a.b"}, want: "<mark>a.b</mark> axb"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.text, tt.terms); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		leading  bool
+		trailing bool
+	}{
+		{name: "match in the middle", text: strings.Repeat("a ", 100) + "needle" + strings.Repeat(" b", 100),
+			leading: true, trailing: true},
+		{name: "match at the start", text: "needle" + strings.Repeat(" b", 200), trailing: true},
+		{name: "match at the end", text: strings.Repeat("a ", 200) + "needle", leading: true},
+		{name: "multi-byte text", text: strings.Repeat("é ", 100) + "needle" + strings.Repeat(" ü", 100),
+			leading: true, trailing: true},
+	}
+	for _, tt := range tests {
+		got := highlight(tt.text, []string{"needle"})
+		if !strings.Contains(got, "<mark>needle</mark>") {
+			t.Errorf("%s: match missing from %q", tt.name, got)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("%s: excerpt splits a rune: %q", tt.name, got)
+		}
+		if strings.HasPrefix(got, "…") != tt.leading || strings.HasSuffix(got, "…") != tt.trailing {
+			t.Errorf("%s: wrong ellipses on %q", tt.name, got)
+		}
+		plain := strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(got)
+		if n := len(plain); n > searchSnippetRunes+utf8.UTFMax {
+			t.Errorf("%s: excerpt is %d bytes", tt.name, n)
+		}
+	}
+}
+
+func TestFirstHighlight(t *testing.T) {
+	long := strings.Repeat("x", searchSnippetRunes+10)
+	tests := []struct {
+		name   string
+		terms  []string
+		fields []string
+		want   string
+	}{
+		{name: "first matching field", terms: []string{"bug"}, fields: []string{"Title", "a bug here"},
+			want: "a <mark>bug</mark> here"},
+		{name: "falls back to first non-empty field", terms: []string{"running"}, fields: []string{"", "Runs <fast>"},
+			want: "Runs &lt;fast&gt;"},
+		{name: "fallback is truncated", terms: []string{"y"}, fields: []string{long},
+			want: strings.Repeat("x", searchSnippetRunes) + "…"},
+		{name: "nothing", terms: []string{"y"}, fields: []string{"", ""}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := firstHighlight(tt.terms, tt.fields...); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
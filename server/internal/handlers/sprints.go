@@ -0,0 +1,840 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultSprintLength = 14 * 24 * time.Hour
+
+var errInvalidSprint = errors.New("invalid sprint")
+
+var trailingNumber = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// EnsureSprintIndexes enforces at most one active sprint per project, so
+// concurrent starts cannot both succeed.
+func EnsureSprintIndexes(ctx context.Context) {
+	_, err := database.GetCollection("sprints").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}},
+		Options: options.Index().SetName("one_active_sprint").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"state": "active"}),
+	})
+	if err != nil {
+		log.Printf("EnsureSprintIndexes: %v", err)
+	}
+}
+
+// resolveSprintID validates a sprint reference for a card. Cards may join
+// planned or active sprints; an empty string moves the card to the backlog.
+func resolveSprintID(ctx context.Context, projectID primitive.ObjectID, raw string) (*primitive.ObjectID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return nil, errInvalidSprint
+	}
+	n, _ := database.GetCollection("sprints").CountDocuments(ctx, bson.M{
+		"_id": id, "project_id": projectID, "state": bson.M{"$ne": "closed"},
+	})
+	if n == 0 {
+		return nil, errInvalidSprint
+	}
+	return &id, nil
+}
+
+func sprintName(ctx context.Context, sprintID *primitive.ObjectID) string {
+	if sprintID == nil {
+		return ""
+	}
+	var sprint models.Sprint
+	if err := database.GetCollection("sprints").FindOne(ctx, bson.M{"_id": *sprintID}).Decode(&sprint); err != nil {
+		return ""
+	}
+	return sprint.Name
+}
+
+func parseSprintDate(raw *string) (*time.Time, error) {
+	if raw == nil || *raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", *raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// nextSprintName bumps a trailing number ("Sprint 7" becomes "Sprint 8").
+func nextSprintName(name string) string {
+	if m := trailingNumber.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return m[1] + strconv.Itoa(n+1)
+	}
+	return name + " (next)"
+}
+
+// sprintMeasure returns how a card contributes to velocity: estimated
+// minutes (the default), a plain count, or a number custom field (cf.<key>).
+// key names the measure in a sprint's stored totals.
+func sprintMeasure(ctx context.Context, projectID primitive.ObjectID, raw string) (measure func(models.Card) float64, name, key string, err error) {
+	switch raw {
+	case "", "estimated_minutes":
+		return estimateMeasure, "estimated_minutes", "estimated_minutes", nil
+	case "count":
+		return countMeasure, "count", "count", nil
+	}
+
+	cfKey, ok := strings.CutPrefix(raw, "cf.")
+	if !ok {
+		return nil, "", "", fmt.Errorf("field must be estimated_minutes, count or cf.<key>")
+	}
+	var field models.CustomField
+	if err := database.GetCollection("custom_fields").FindOne(ctx, bson.M{"project_id": projectID, "key": cfKey, "type": "number"}).Decode(&field); err != nil {
+		return nil, "", "", fmt.Errorf("cf.%s is not a number field of this project", cfKey)
+	}
+	return customFieldMeasure(field), raw, field.ID.Hex(), nil
+}
+
+func estimateMeasure(card models.Card) float64 {
+	if card.EstimatedMinutes == nil {
+		return 0
+	}
+	return float64(*card.EstimatedMinutes)
+}
+
+func countMeasure(models.Card) float64 { return 1 }
+
+func customFieldMeasure(field models.CustomField) func(models.Card) float64 {
+	id := field.ID.Hex()
+	return func(card models.Card) float64 {
+		n, _ := card.CustomFields[id].(float64)
+		return n
+	}
+}
+
+// sprintTotals sums cards by every measure sprintMeasure offers, under the
+// measure's key. Sprints store these when they start and close so later
+// re-estimates or deletions do not rewrite their velocity.
+func sprintTotals(ctx context.Context, projectID primitive.ObjectID, cards []models.Card) map[string]float64 {
+	measures := map[string]func(models.Card) float64{
+		"estimated_minutes": estimateMeasure,
+		"count":             countMeasure,
+	}
+	for _, f := range projectCustomFields(ctx, projectID) {
+		if f.Type == "number" {
+			measures[f.ID.Hex()] = customFieldMeasure(f)
+		}
+	}
+
+	totals := map[string]float64{}
+	for key, measure := range measures {
+		totals[key] = 0
+		for _, card := range cards {
+			totals[key] += measure(card)
+		}
+	}
+	return totals
+}
+
+func doneColumnSet(ctx context.Context, projectID primitive.ObjectID) map[primitive.ObjectID]bool {
+	set := map[primitive.ObjectID]bool{}
+	for _, id := range doneColumnIDs(ctx, []primitive.ObjectID{projectID}) {
+		set[id] = true
+	}
+	return set
+}
+
+func findCards(ctx context.Context, filter bson.M, opts ...*options.FindOptions) []models.Card {
+	cards := []models.Card{}
+	cursor, err := database.GetCollection("cards").Find(ctx, filter, opts...)
+	if err != nil {
+		return cards
+	}
+	defer cursor.Close(ctx)
+	cursor.All(ctx, &cards)
+	return cards
+}
+
+type sprintProgress struct {
+	models.Sprint
+	CardCount        int `json:"card_count"`
+	DoneCount        int `json:"done_count"`
+	EstimatedMinutes int `json:"estimated_minutes"`
+	DoneMinutes      int `json:"done_minutes"`
+}
+
+func withSprintProgress(sprint models.Sprint, cards []models.Card, done map[primitive.ObjectID]bool) sprintProgress {
+	p := sprintProgress{Sprint: sprint}
+	for _, card := range cards {
+		minutes := 0
+		if card.EstimatedMinutes != nil {
+			minutes = *card.EstimatedMinutes
+		}
+		p.CardCount++
+		p.EstimatedMinutes += minutes
+		if done[card.ColumnID] {
+			p.DoneCount++
+			p.DoneMinutes += minutes
+		}
+	}
+	return p
+}
+
+func ListSprints(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	filter := bson.M{"project_id": projectID}
+	if state := c.Query("state"); state != "" {
+		filter["state"] = state
+	}
+	cursor, err := database.GetCollection("sprints").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "start_date", Value: 1}, {Key: "created_at", Value: 1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch sprints"})
+	}
+	defer cursor.Close(ctx)
+
+	var sprints []models.Sprint
+	cursor.All(ctx, &sprints)
+
+	done := doneColumnSet(ctx, projectID)
+	result := []sprintProgress{}
+	for _, s := range sprints {
+		result = append(result, withSprintProgress(s, findCards(ctx, bson.M{"sprint_id": s.ID, "archived_at": nil}), done))
+	}
+	return c.JSON(result)
+}
+
+func GetSprint(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	sprintID, err := primitive.ObjectIDFromHex(c.Params("sprintId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid sprint ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	var sprint models.Sprint
+	if err := database.GetCollection("sprints").FindOne(ctx, bson.M{"_id": sprintID, "project_id": projectID}).Decode(&sprint); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sprint not found"})
+	}
+
+	cards := findCards(ctx, bson.M{"sprint_id": sprintID, "archived_at": nil}, options.Find().SetSort(rankSort))
+	return c.JSON(fiber.Map{
+		"sprint": withSprintProgress(sprint, cards, doneColumnSet(ctx, projectID)),
+		"cards":  cards,
+	})
+}
+
+func CreateSprint(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		Name      string  `json:"name"`
+		Goal      string  `json:"goal"`
+		StartDate *string `json:"start_date"`
+		EndDate   *string `json:"end_date"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+	start, err := parseSprintDate(body.StartDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "start_date must be YYYY-MM-DD"})
+	}
+	end, err := parseSprintDate(body.EndDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must be YYYY-MM-DD"})
+	}
+	if start != nil && end != nil && end.Before(*start) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must not be before start_date"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	now := time.Now()
+	sprint := &models.Sprint{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		Name:      strings.TrimSpace(body.Name),
+		Goal:      body.Goal,
+		StartDate: start,
+		EndDate:   end,
+		State:     "planned",
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if _, err := database.GetCollection("sprints").InsertOne(ctx, sprint); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create sprint"})
+	}
+	recordActivity(ctx, projectID, userID, "created", "sprint", sprint.ID, sprint.Name, nil)
+
+	return c.Status(fiber.StatusCreated).JSON(sprint)
+}
+
+func UpdateSprint(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	sprintID, err := primitive.ObjectIDFromHex(c.Params("sprintId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid sprint ID"})
+	}
+
+	var body struct {
+		Name      *string `json:"name"`
+		Goal      *string `json:"goal"`
+		StartDate *string `json:"start_date"`
+		EndDate   *string `json:"end_date"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("sprints")
+	var sprint models.Sprint
+	if err := col.FindOne(ctx, bson.M{"_id": sprintID, "project_id": projectID}).Decode(&sprint); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sprint not found"})
+	}
+	before := sprint
+
+	if body.Name != nil {
+		if sprint.Name = strings.TrimSpace(*body.Name); sprint.Name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name cannot be empty"})
+		}
+	}
+	if body.Goal != nil {
+		sprint.Goal = *body.Goal
+	}
+	if (body.StartDate != nil || body.EndDate != nil) && sprint.State == "closed" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Dates of a closed sprint cannot change"})
+	}
+	if body.StartDate != nil {
+		if sprint.StartDate, err = parseSprintDate(body.StartDate); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "start_date must be YYYY-MM-DD"})
+		}
+	}
+	if body.EndDate != nil {
+		if sprint.EndDate, err = parseSprintDate(body.EndDate); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must be YYYY-MM-DD"})
+		}
+	}
+	if sprint.StartDate != nil && sprint.EndDate != nil && sprint.EndDate.Before(*sprint.StartDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date must not be before start_date"})
+	}
+	sprint.UpdatedAt = time.Now()
+
+	col.ReplaceOne(ctx, bson.M{"_id": sprintID}, sprint)
+
+	changes := appendChange(nil, "name", before.Name, sprint.Name)
+	changes = appendChange(changes, "goal", before.Goal, sprint.Goal)
+	changes = appendChange(changes, "start_date", formatDueDate(before.StartDate), formatDueDate(sprint.StartDate))
+	changes = appendChange(changes, "end_date", formatDueDate(before.EndDate), formatDueDate(sprint.EndDate))
+	if len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "sprint", sprint.ID, sprint.Name, changes)
+	}
+
+	return c.JSON(sprint)
+}
+
+func DeleteSprint(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	sprintID, err := primitive.ObjectIDFromHex(c.Params("sprintId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid sprint ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var sprint models.Sprint
+	if err := database.GetCollection("sprints").FindOne(ctx, bson.M{"_id": sprintID, "project_id": projectID}).Decode(&sprint); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sprint not found"})
+	}
+	if sprint.State == "active" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Complete the sprint before deleting it"})
+	}
+
+	database.GetCollection("sprints").DeleteOne(ctx, bson.M{"_id": sprintID})
+	database.GetCollection("cards").UpdateMany(ctx,
+		bson.M{"sprint_id": sprintID},
+		bson.M{"$unset": bson.M{"sprint_id": ""}, "$inc": bumpVersion},
+	)
+	recordActivity(ctx, projectID, userID, "deleted", "sprint", sprint.ID, sprint.Name, nil)
+
+	return c.JSON(fiber.Map{"message": "Sprint deleted"})
+}
+
+// StartSprint activates a planned sprint and snapshots its cards as the
+// committed scope. Only one sprint per project can be active.
+func StartSprint(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	sprintID, err := primitive.ObjectIDFromHex(c.Params("sprintId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid sprint ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("sprints")
+	var sprint models.Sprint
+	if err := col.FindOne(ctx, bson.M{"_id": sprintID, "project_id": projectID}).Decode(&sprint); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sprint not found"})
+	}
+	if sprint.State != "planned" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only planned sprints can be started"})
+	}
+	if n, _ := col.CountDocuments(ctx, bson.M{"project_id": projectID, "state": "active"}); n > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Another sprint is already active"})
+	}
+
+	now := time.Now()
+	if sprint.StartDate == nil {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		sprint.StartDate = &today
+	}
+	if sprint.EndDate == nil {
+		end := sprint.StartDate.Add(defaultSprintLength)
+		sprint.EndDate = &end
+	}
+
+	committedCards := findCards(ctx, bson.M{"sprint_id": sprintID, "archived_at": nil})
+	committed := []primitive.ObjectID{}
+	for _, card := range committedCards {
+		committed = append(committed, card.ID)
+	}
+
+	sprint.State = "active"
+	sprint.StartedAt = &now
+	sprint.CommittedCardIDs = committed
+	sprint.CommittedTotals = sprintTotals(ctx, projectID, committedCards)
+	sprint.UpdatedAt = now
+
+	res, err := col.ReplaceOne(ctx, bson.M{"_id": sprintID, "state": "planned"}, sprint)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Another sprint is already active"})
+	}
+	if err != nil || res.ModifiedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only planned sprints can be started"})
+	}
+	recordActivity(ctx, projectID, userID, "started", "sprint", sprint.ID, sprint.Name,
+		[]models.ActivityChange{{Field: "cards", To: len(committed)}})
+
+	return c.JSON(sprint)
+}
+
+// CompleteSprint closes the active sprint. Cards outside done columns roll
+// over to next_sprint_id, else the earliest planned sprint, else a new
+// sprint of the same length; to_backlog returns them to the backlog instead.
+func CompleteSprint(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	sprintID, err := primitive.ObjectIDFromHex(c.Params("sprintId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid sprint ID"})
+	}
+
+	var body struct {
+		NextSprintID string `json:"next_sprint_id"`
+		ToBacklog    bool   `json:"to_backlog"`
+	}
+	c.BodyParser(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("sprints")
+	var sprint models.Sprint
+	if err := col.FindOne(ctx, bson.M{"_id": sprintID, "project_id": projectID}).Decode(&sprint); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sprint not found"})
+	}
+	if sprint.State != "active" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only the active sprint can be completed"})
+	}
+
+	now := time.Now()
+	var next *models.Sprint
+	createNext := false
+	if !body.ToBacklog {
+		if body.NextSprintID != "" {
+			nextID, err := primitive.ObjectIDFromHex(body.NextSprintID)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid next_sprint_id"})
+			}
+			next = &models.Sprint{}
+			if err := col.FindOne(ctx, bson.M{"_id": nextID, "project_id": projectID, "state": "planned"}).Decode(next); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "next_sprint_id must be a planned sprint of this project"})
+			}
+		} else {
+			next = &models.Sprint{}
+			err := col.FindOne(ctx, bson.M{"project_id": projectID, "state": "planned"},
+				options.FindOne().SetSort(bson.D{{Key: "start_date", Value: 1}, {Key: "created_at", Value: 1}})).Decode(next)
+			if err != nil {
+				next = &models.Sprint{
+					ID:        primitive.NewObjectID(),
+					ProjectID: projectID,
+					Name:      nextSprintName(sprint.Name),
+					State:     "planned",
+					CreatedBy: userID,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if sprint.StartDate != nil && sprint.EndDate != nil {
+					start := *sprint.EndDate
+					end := start.Add(sprint.EndDate.Sub(*sprint.StartDate))
+					next.StartDate, next.EndDate = &start, &end
+				}
+				createNext = true
+			}
+		}
+	}
+
+	done := doneColumnSet(ctx, projectID)
+	completed := []primitive.ObjectID{}
+	completedCards := []models.Card{}
+	unfinished := []primitive.ObjectID{}
+	for _, card := range findCards(ctx, bson.M{"sprint_id": sprintID, "archived_at": nil}) {
+		if done[card.ColumnID] {
+			completed = append(completed, card.ID)
+			completedCards = append(completedCards, card)
+		} else {
+			unfinished = append(unfinished, card.ID)
+		}
+	}
+
+	// Closing the sprint is conditional on it still being active, so of two
+	// concurrent completions only one creates a next sprint and rolls the
+	// open cards over.
+	sprint.State = "closed"
+	sprint.CompletedAt = &now
+	sprint.CompletedCardIDs = completed
+	sprint.CompletedTotals = sprintTotals(ctx, projectID, completedCards)
+	sprint.RolledOver = len(unfinished)
+	sprint.UpdatedAt = now
+	res, err := col.ReplaceOne(ctx, bson.M{"_id": sprintID, "state": "active"}, sprint)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to complete sprint"})
+	}
+	if res.ModifiedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Only the active sprint can be completed"})
+	}
+
+	if createNext {
+		col.InsertOne(ctx, next)
+		recordActivity(ctx, projectID, userID, "created", "sprint", next.ID, next.Name, nil)
+	}
+	if len(unfinished) > 0 {
+		update := bson.M{"$unset": bson.M{"sprint_id": ""}, "$inc": bumpVersion}
+		if next != nil {
+			update = bson.M{"$set": bson.M{"sprint_id": next.ID}, "$inc": bumpVersion}
+		}
+		database.GetCollection("cards").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": unfinished}}, update)
+	}
+
+	changes := []models.ActivityChange{
+		{Field: "completed", To: len(completed)},
+		{Field: "rolled_over", To: len(unfinished)},
+	}
+	if next != nil {
+		changes = append(changes, models.ActivityChange{Field: "next_sprint", To: next.Name})
+	}
+	recordActivity(ctx, projectID, userID, "completed", "sprint", sprint.ID, sprint.Name, changes)
+
+	return c.JSON(fiber.Map{"sprint": sprint, "next_sprint": next})
+}
+
+// GetBacklog lists cards that belong to no sprint, excluding finished ones
+// unless include_done=true. q and sort work as on the board.
+func GetBacklog(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	filter, err := compileBoardQuery(ctx, c.Query("q"), newQueryScope(ctx, userID, []primitive.ObjectID{projectID}))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid query: " + err.Error()})
+	}
+	cardSort, err := compileBoardSort(ctx, projectID, c.Query("sort"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filter["project_id"] = projectID
+	filter["sprint_id"] = nil
+	filter["archived_at"] = nil
+	if c.Query("include_done") != "true" {
+		filter["column_id"] = bson.M{"$nin": doneColumnIDs(ctx, []primitive.ObjectID{projectID})}
+	}
+
+	return c.JSON(findCards(ctx, filter, options.Find().SetSort(cardSort)))
+}
+
+// GetSprintVelocity reports committed and completed scope for the latest
+// sprints, measured by field (see sprintMeasure). The average covers closed
+// sprints only.
+func GetSprintVelocity(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	limit := int64(6)
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.ParseInt(l, 10, 64); err == nil && n > 0 && n <= 26 {
+			limit = n
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	measure, measureName, measureKey, err := sprintMeasure(ctx, projectID, c.Query("field"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	cursor, err := database.GetCollection("sprints").Find(ctx,
+		bson.M{"project_id": projectID, "state": bson.M{"$in": bson.A{"active", "closed"}}},
+		options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch sprints"})
+	}
+	var sprints []models.Sprint
+	cursor.All(ctx, &sprints)
+	cursor.Close(ctx)
+
+	cardIDs := []primitive.ObjectID{}
+	for _, s := range sprints {
+		cardIDs = append(cardIDs, s.CommittedCardIDs...)
+		cardIDs = append(cardIDs, s.CompletedCardIDs...)
+	}
+	cards := map[primitive.ObjectID]models.Card{}
+	for _, card := range findCards(ctx, bson.M{"$or": bson.A{
+		bson.M{"_id": bson.M{"$in": cardIDs}},
+		bson.M{"project_id": projectID, "sprint_id": bson.M{"$ne": nil}},
+	}}) {
+		cards[card.ID] = card
+	}
+	done := doneColumnSet(ctx, projectID)
+
+	// Totals stored when the sprint started or closed win over the cards'
+	// current values; sprints from before totals were stored fall back to
+	// the cards.
+	sum := func(totals map[string]float64, ids []primitive.ObjectID) float64 {
+		if totals != nil {
+			return totals[measureKey]
+		}
+		total := 0.0
+		for _, id := range ids {
+			if card, ok := cards[id]; ok {
+				total += measure(card)
+			}
+		}
+		return total
+	}
+
+	type SprintVelocity struct {
+		ID        primitive.ObjectID `json:"id"`
+		Name      string             `json:"name"`
+		State     string             `json:"state"`
+		StartDate *time.Time         `json:"start_date,omitempty"`
+		EndDate   *time.Time         `json:"end_date,omitempty"`
+		Committed float64            `json:"committed"`
+		Completed float64            `json:"completed"`
+	}
+
+	result := []SprintVelocity{}
+	closedTotal, closedCount := 0.0, 0
+	for i := len(sprints) - 1; i >= 0; i-- {
+		s := sprints[i]
+		completedIDs, completedTotals := s.CompletedCardIDs, s.CompletedTotals
+		if s.State == "active" {
+			completedIDs, completedTotals = nil, nil
+			for _, card := range cards {
+				if card.SprintID != nil && *card.SprintID == s.ID && done[card.ColumnID] {
+					completedIDs = append(completedIDs, card.ID)
+				}
+			}
+		}
+		v := SprintVelocity{
+			ID:        s.ID,
+			Name:      s.Name,
+			State:     s.State,
+			StartDate: s.StartDate,
+			EndDate:   s.EndDate,
+			Committed: sum(s.CommittedTotals, s.CommittedCardIDs),
+			Completed: sum(completedTotals, completedIDs),
+		}
+		if s.State == "closed" {
+			closedTotal += v.Completed
+			closedCount++
+		}
+		result = append(result, v)
+	}
+
+	average := 0.0
+	if closedCount > 0 {
+		average = closedTotal / float64(closedCount)
+	}
+
+	return c.JSON(fiber.Map{"field": measureName, "sprints": result, "average": average})
+}
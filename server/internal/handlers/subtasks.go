@@ -0,0 +1,847 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Subtasks and checklists take their IDs from one counter per card,
+// subtask_seq, so concurrent adds never collide. Checklist 0 is the card's
+// default, unnamed checklist.
+
+// allocateSubtaskIDs reserves n consecutive IDs on the card and returns
+// the first. Cards from before the counter start above their highest ID.
+func allocateSubtaskIDs(ctx context.Context, card models.Card, n int) (int, error) {
+	highest := card.SubtaskSeq
+	for _, s := range card.Subtasks {
+		if s.ID > highest {
+			highest = s.ID
+		}
+	}
+	for _, l := range card.Checklists {
+		if l.ID > highest {
+			highest = l.ID
+		}
+	}
+
+	col := database.GetCollection("cards")
+	if highest > card.SubtaskSeq {
+		col.UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{"$max": bson.M{"subtask_seq": highest}})
+	}
+
+	var counter struct {
+		Seq int `bson:"subtask_seq"`
+	}
+	err := col.FindOneAndUpdate(ctx, bson.M{"_id": card.ID},
+		bson.M{"$inc": bson.M{"subtask_seq": n}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"subtask_seq": 1}),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq - n + 1, nil
+}
+
+// numberSubtasks gives the subtasks of a new card IDs 1..n in the default
+// checklist and returns the counter to store with them.
+func numberSubtasks(subtasks []models.Subtask) int {
+	for i := range subtasks {
+		subtasks[i].ID = i + 1
+		subtasks[i].ChecklistID = 0
+	}
+	return len(subtasks)
+}
+
+// subtaskInput is one subtask of a whole-list save from UpdateCard.
+// Checklist, assignee and due date are pointers so that leaving them out
+// keeps the stored value, while 0 or "" clears it.
+type subtaskInput struct {
+	ID          int     `json:"id"`
+	Text        string  `json:"text"`
+	Done        bool    `json:"done"`
+	ChecklistID *int    `json:"checklist_id"`
+	Assignee    *string `json:"assignee"`
+	DueDate     *string `json:"due_date"`
+}
+
+var (
+	errSubtaskDueDate  = errors.New("subtask due_date must be YYYY-MM-DD")
+	errSubtaskAssignee = errors.New("subtask assignee must be a project member")
+)
+
+// freshSubtasks lists the positions in incoming that need a new ID: those
+// the card does not know and repeats of an ID already used.
+func freshSubtasks(card models.Card, incoming []subtaskInput) []int {
+	known := map[int]bool{}
+	for _, s := range card.Subtasks {
+		known[s.ID] = true
+	}
+	seen := map[int]bool{}
+	fresh := []int{}
+	for i, s := range incoming {
+		if !known[s.ID] || seen[s.ID] {
+			fresh = append(fresh, i)
+		}
+		seen[s.ID] = true
+	}
+	return fresh
+}
+
+// mergeSubtaskList builds the saved list from incoming, giving the entries
+// at fresh the IDs from first on. The list sets text, done and order; a
+// known subtask keeps its checklist, assignee and due date unless the entry
+// gives them.
+func mergeSubtaskList(card models.Card, incoming []subtaskInput, fresh []int, first int) ([]models.Subtask, error) {
+	known := map[int]models.Subtask{}
+	for _, s := range card.Subtasks {
+		known[s.ID] = s
+	}
+	ids := make([]int, len(incoming))
+	for i, s := range incoming {
+		ids[i] = s.ID
+	}
+	for n, i := range fresh {
+		ids[i] = first + n
+	}
+
+	merged := make([]models.Subtask, len(incoming))
+	for i, in := range incoming {
+		s := models.Subtask{ID: ids[i], Text: in.Text, Done: in.Done}
+		if prev, ok := known[s.ID]; ok {
+			s.ChecklistID, s.Assignee, s.DueDate = prev.ChecklistID, prev.Assignee, prev.DueDate
+		}
+		if in.ChecklistID != nil {
+			s.ChecklistID = *in.ChecklistID
+		}
+		if in.Assignee != nil {
+			s.Assignee = strings.TrimSpace(*in.Assignee)
+		}
+		if in.DueDate != nil {
+			dueDate, ok := parseSubtaskDue(*in.DueDate)
+			if !ok {
+				return nil, errSubtaskDueDate
+			}
+			s.DueDate = dueDate
+		}
+		if !hasChecklist(card, s.ChecklistID) {
+			s.ChecklistID = 0
+		}
+		merged[i] = s
+	}
+	return merged, nil
+}
+
+// mergeSubtasks applies a whole-list save from UpdateCard, allocating IDs
+// for new subtasks and checking that newly set assignees are members.
+func mergeSubtasks(ctx context.Context, card models.Card, incoming []subtaskInput) ([]models.Subtask, error) {
+	fresh := freshSubtasks(card, incoming)
+	first := 0
+	if len(fresh) > 0 {
+		var err error
+		if first, err = allocateSubtaskIDs(ctx, card, len(fresh)); err != nil {
+			return nil, err
+		}
+	}
+
+	merged, err := mergeSubtaskList(card, incoming, fresh, first)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := map[string]bool{}
+	for _, s := range card.Subtasks {
+		assigned[s.Assignee] = true
+	}
+	for _, s := range merged {
+		if s.Assignee != "" && !assigned[s.Assignee] && !isProjectMemberEmail(ctx, card.ProjectID, s.Assignee) {
+			return nil, errSubtaskAssignee
+		}
+	}
+	return merged, nil
+}
+
+func hasChecklist(card models.Card, checklistID int) bool {
+	if checklistID == 0 {
+		return true
+	}
+	for _, l := range card.Checklists {
+		if l.ID == checklistID {
+			return true
+		}
+	}
+	return false
+}
+
+func subtaskIndex(card models.Card, subtaskID int) int {
+	for i, s := range card.Subtasks {
+		if s.ID == subtaskID {
+			return i
+		}
+	}
+	return -1
+}
+
+func checklistIndex(card models.Card, checklistID int) int {
+	for i, l := range card.Checklists {
+		if l.ID == checklistID {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseSubtaskDue reads a YYYY-MM-DD due date, or the full timestamp the
+// API returns it as; empty clears it.
+func parseSubtaskDue(raw string) (*time.Time, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		if parsed, err = time.Parse(time.RFC3339, raw); err != nil {
+			return nil, false
+		}
+		parsed = parsed.UTC()
+	}
+	return &parsed, true
+}
+
+// editableCard loads the card behind :cardId for a subtask or checklist
+// change, writing the error response itself when it cannot be edited.
+func editableCard(c *fiber.Ctx, ctx context.Context, userID primitive.ObjectID) (*models.Card, error) {
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return nil, projectWriteError(c, err)
+	}
+
+	if card.ArchivedAt != nil {
+		return nil, c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is archived; restore it first"})
+	}
+	return &card, nil
+}
+
+// finishCardEdit records a subtask or checklist change, tells watchers and
+// live boards, and returns the card as it now is.
+func finishCardEdit(ctx context.Context, userID primitive.ObjectID, before models.Card, changes []models.ActivityChange) models.Card {
+	var card models.Card
+	database.GetCollection("cards").FindOne(ctx, bson.M{"_id": before.ID}).Decode(&card)
+
+	changes = append(cardChanges(before, card), changes...)
+	if len(changes) > 0 {
+		recordActivity(ctx, card.ProjectID, userID, "updated", "card", card.ID, card.Title, changes)
+	}
+	notifyWatchers(ctx, card, userID, watchEvents(ctx, before, card))
+	publishBoardEvent(ctx, card.ProjectID, userID, "card.updated", card)
+	if len(changedCardFields(before, card)) > 0 {
+		fireAutomations(automationEvent{Type: "card.updated", Card: card, Before: &before})
+	}
+	return card
+}
+
+// notifySubtaskAssignee tells a subtask's new assignee, provided they can
+// still see the card.
+func notifySubtaskAssignee(ctx context.Context, card models.Card, subtask models.Subtask, actorID primitive.ObjectID) {
+	if subtask.Assignee == "" {
+		return
+	}
+	var assignee models.User
+	if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": subtask.Assignee}).Decode(&assignee); err != nil {
+		return
+	}
+	if assignee.ID == actorID {
+		return
+	}
+	if _, err := getProjectRole(ctx, card.ProjectID, assignee.ID); err != nil {
+		return
+	}
+	createNotification(ctx, assignee.ID, "assign",
+		"You have been assigned the subtask \""+subtask.Text+"\" on \""+card.Title+"\"",
+		card.ProjectID, card.ID)
+}
+
+func CreateSubtask(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	var body struct {
+		Text        string `json:"text"`
+		ChecklistID int    `json:"checklist_id"`
+		Assignee    string `json:"assignee"`
+		DueDate     string `json:"due_date"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Text) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "text is required"})
+	}
+	dueDate, ok := parseSubtaskDue(body.DueDate)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "due_date must be YYYY-MM-DD"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	if !hasChecklist(*card, body.ChecklistID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown checklist_id"})
+	}
+	assignee := strings.TrimSpace(body.Assignee)
+	if assignee != "" && !isProjectMemberEmail(ctx, card.ProjectID, assignee) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "assignee must be a project member"})
+	}
+
+	id, err := allocateSubtaskIDs(ctx, *card, 1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add subtask"})
+	}
+	subtask := models.Subtask{
+		ID:          id,
+		ChecklistID: body.ChecklistID,
+		Text:        strings.TrimSpace(body.Text),
+		Assignee:    assignee,
+		DueDate:     dueDate,
+	}
+
+	_, err = database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{
+		"$push": bson.M{"subtasks": subtask},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$inc":  bumpVersion,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add subtask"})
+	}
+
+	updated := finishCardEdit(ctx, userID, *card, appendChange(nil, "subtask", nil, subtask.Text))
+	notifySubtaskAssignee(ctx, updated, subtask, userID)
+
+	setVersionETag(c, updated.Version)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"subtask": subtask, "card": withLinks(ctx, userID, updated)})
+}
+
+func UpdateSubtask(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	subtaskID, err := strconv.Atoi(c.Params("subtaskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid subtask ID"})
+	}
+
+	var body struct {
+		Text     *string `json:"text"`
+		Done     *bool   `json:"done"`
+		Assignee *string `json:"assignee"`
+		DueDate  *string `json:"due_date"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	idx := subtaskIndex(*card, subtaskID)
+	if idx < 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+	}
+	before := card.Subtasks[idx]
+	after := before
+
+	filter := bson.M{"_id": card.ID, "subtasks.id": subtaskID}
+	if err := matchVersion(c, filter, card.Version); err != nil {
+		return versionError(c, err, card.Version, card)
+	}
+
+	// Only the given fields are written, through the subtask's own array
+	// element, so concurrent edits to other subtasks are untouched.
+	set := bson.M{"updated_at": time.Now()}
+	if body.Text != nil {
+		text := strings.TrimSpace(*body.Text)
+		if text == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "text cannot be empty"})
+		}
+		set["subtasks.$[s].text"] = text
+		after.Text = text
+	}
+	if body.Done != nil {
+		set["subtasks.$[s].done"] = *body.Done
+		after.Done = *body.Done
+	}
+	if body.Assignee != nil {
+		assignee := strings.TrimSpace(*body.Assignee)
+		if assignee != "" && assignee != before.Assignee && !isProjectMemberEmail(ctx, card.ProjectID, assignee) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "assignee must be a project member"})
+		}
+		set["subtasks.$[s].assignee"] = assignee
+		after.Assignee = assignee
+	}
+	if body.DueDate != nil {
+		dueDate, ok := parseSubtaskDue(*body.DueDate)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "due_date must be YYYY-MM-DD"})
+		}
+		set["subtasks.$[s].due_date"] = dueDate
+		after.DueDate = dueDate
+	}
+
+	res, err := database.GetCollection("cards").UpdateOne(ctx, filter,
+		bson.M{"$set": set, "$inc": bumpVersion},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"s.id": subtaskID}}}),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update subtask"})
+	}
+	if res.MatchedCount == 0 {
+		return subtaskWriteMissed(c, ctx, card.ID, subtaskID)
+	}
+
+	changes := appendChange(nil, "subtask", before.Text, after.Text)
+	changes = appendChange(changes, "subtask_assignee", before.Assignee, after.Assignee)
+	changes = appendChange(changes, "subtask_due_date", formatDueDate(before.DueDate), formatDueDate(after.DueDate))
+	updated := finishCardEdit(ctx, userID, *card, changes)
+	if after.Assignee != before.Assignee {
+		notifySubtaskAssignee(ctx, updated, after, userID)
+	}
+
+	setVersionETag(c, updated.Version)
+	return c.JSON(withLinks(ctx, userID, updated))
+}
+
+// subtaskWriteMissed answers a subtask write that matched nothing: the
+// subtask is gone, or the card moved past the version in If-Match.
+func subtaskWriteMissed(c *fiber.Ctx, ctx context.Context, cardID primitive.ObjectID, subtaskID int) error {
+	var current models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&current); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+	if subtaskIndex(current, subtaskID) < 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+	}
+	return versionConflict(c, current.Version, current)
+}
+
+// placeSubtask inserts subtask into list at position among the items of
+// its checklist; a negative or out-of-range position appends it there.
+func placeSubtask(list []models.Subtask, subtask models.Subtask, position int) []models.Subtask {
+	at, seen := len(list), 0
+	for i, s := range list {
+		if s.ChecklistID != subtask.ChecklistID {
+			continue
+		}
+		if seen == position {
+			at = i
+			break
+		}
+		seen++
+		at = i + 1
+	}
+
+	placed := make([]models.Subtask, 0, len(list)+1)
+	placed = append(placed, list[:at]...)
+	placed = append(placed, subtask)
+	return append(placed, list[at:]...)
+}
+
+func MoveSubtask(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	subtaskID, err := strconv.Atoi(c.Params("subtaskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid subtask ID"})
+	}
+
+	var body struct {
+		Position    *int `json:"position"`
+		ChecklistID *int `json:"checklist_id"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Position == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "position is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	if body.ChecklistID != nil && !hasChecklist(*card, *body.ChecklistID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown checklist_id"})
+	}
+
+	// Reordering rewrites the list, so it is pinned to the card's version
+	// and retried if another edit lands in between.
+	col := database.GetCollection("cards")
+	current := *card
+	for attempt := 0; attempt < 3; attempt++ {
+		idx := subtaskIndex(current, subtaskID)
+		if idx < 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+		}
+		subtask := current.Subtasks[idx]
+		if body.ChecklistID != nil {
+			subtask.ChecklistID = *body.ChecklistID
+		}
+		rest := append(append([]models.Subtask{}, current.Subtasks[:idx]...), current.Subtasks[idx+1:]...)
+		list := placeSubtask(rest, subtask, *body.Position)
+
+		res, err := col.UpdateOne(ctx,
+			bson.M{"_id": current.ID, "version": versionFilter(current.Version)},
+			bson.M{"$set": bson.M{"subtasks": list, "updated_at": time.Now()}, "$inc": bumpVersion},
+		)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to move subtask"})
+		}
+		if res.MatchedCount > 0 {
+			var changes []models.ActivityChange
+			if subtask.ChecklistID != current.Subtasks[idx].ChecklistID {
+				changes = appendChange(nil, "subtask_checklist", subtask.Text, checklistName(current, subtask.ChecklistID))
+			}
+			updated := finishCardEdit(ctx, userID, *card, changes)
+			setVersionETag(c, updated.Version)
+			return c.JSON(withLinks(ctx, userID, updated))
+		}
+
+		if err := col.FindOne(ctx, bson.M{"_id": card.ID}).Decode(&current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+		}
+	}
+
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The card kept changing; try again"})
+}
+
+func checklistName(card models.Card, checklistID int) string {
+	if i := checklistIndex(card, checklistID); i >= 0 {
+		return card.Checklists[i].Name
+	}
+	return "Checklist"
+}
+
+func DeleteSubtask(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	subtaskID, err := strconv.Atoi(c.Params("subtaskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid subtask ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	idx := subtaskIndex(*card, subtaskID)
+	if idx < 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+	}
+
+	filter := bson.M{"_id": card.ID, "subtasks.id": subtaskID}
+	if err := matchVersion(c, filter, card.Version); err != nil {
+		return versionError(c, err, card.Version, card)
+	}
+
+	res, err := database.GetCollection("cards").UpdateOne(ctx, filter, bson.M{
+		"$pull": bson.M{"subtasks": bson.M{"id": subtaskID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$inc":  bumpVersion,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete subtask"})
+	}
+	if res.MatchedCount == 0 {
+		return subtaskWriteMissed(c, ctx, card.ID, subtaskID)
+	}
+
+	updated := finishCardEdit(ctx, userID, *card, appendChange(nil, "subtask", card.Subtasks[idx].Text, nil))
+	setVersionETag(c, updated.Version)
+	return c.JSON(withLinks(ctx, userID, updated))
+}
+
+// ConvertSubtask turns a subtask into a card of its own, in the same
+// column unless column_id says otherwise, linked to the card it came from.
+func ConvertSubtask(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	subtaskID, err := strconv.Atoi(c.Params("subtaskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid subtask ID"})
+	}
+
+	var body struct {
+		ColumnID string `json:"column_id"`
+		Force    bool   `json:"force"`
+	}
+	c.BodyParser(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	idx := subtaskIndex(*card, subtaskID)
+	if idx < 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+	}
+	subtask := card.Subtasks[idx]
+
+	columnID := card.ColumnID
+	if body.ColumnID != "" {
+		columnID, err = primitive.ObjectIDFromHex(body.ColumnID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid column_id"})
+		}
+	}
+	var column models.BoardColumn
+	if err := database.GetCollection("board_columns").FindOne(ctx, bson.M{"_id": columnID, "project_id": card.ProjectID, "archived_at": nil}).Decode(&column); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+	}
+
+	assignees := []string{}
+	if subtask.Assignee != "" {
+		assignees = append(assignees, subtask.Assignee)
+	}
+	now := time.Now()
+	converted := &models.Card{
+		ID:        primitive.NewObjectID(),
+		ColumnID:  columnID,
+		LaneID:    card.LaneID,
+		SprintID:  card.SprintID,
+		ProjectID: card.ProjectID,
+		Title:     subtask.Text,
+		Priority:  "Medium",
+		Color:     "neutral",
+		DueDate:   subtask.DueDate,
+		Assignees: assignees,
+		LabelIDs:  []primitive.ObjectID{},
+		Subtasks:  []models.Subtask{},
+		Rank:      rankAt(ctx, "cards", bson.M{"column_id": columnID}, primitive.NilObjectID, -1),
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	violations := checkWIP(ctx, column, *converted)
+	if len(violations) > 0 && wipBlocks(column) && !body.Force {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "WIP limit reached", "violations": violations})
+	}
+
+	converted.Number, converted.Key = nextCardKey(ctx, card.ProjectID)
+	if _, err := database.GetCollection("cards").InsertOne(ctx, converted); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create card"})
+	}
+	database.GetCollection("card_links").InsertOne(ctx, &models.CardLink{
+		ID:              primitive.NewObjectID(),
+		Type:            "relates_to",
+		SourceCardID:    card.ID,
+		TargetCardID:    converted.ID,
+		SourceProjectID: card.ProjectID,
+		TargetProjectID: card.ProjectID,
+		CreatedBy:       userID,
+		CreatedAt:       now,
+	})
+	database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{
+		"$pull": bson.M{"subtasks": bson.M{"id": subtaskID}},
+		"$set":  bson.M{"updated_at": now},
+		"$inc":  bumpVersion,
+	})
+
+	recordCardTransition(ctx, *converted, nil, &columnID, userID)
+	if len(violations) > 0 {
+		notifyWIPExceeded(ctx, column, *converted, userID, violations)
+	}
+	recordActivity(ctx, converted.ProjectID, userID, "created", "card", converted.ID, converted.Title,
+		[]models.ActivityChange{{Field: "column", To: column.Title}, {Field: "subtask_of", To: card.Title}})
+	autoWatchCard(ctx, *converted, userID)
+	publishBoardEvent(ctx, converted.ProjectID, userID, "card.created", converted)
+	fireAutomations(automationEvent{Type: "card.created", Card: *converted})
+
+	source := finishCardEdit(ctx, userID, *card, []models.ActivityChange{{Field: "converted_subtask", To: subtask.Text}})
+	if subtask.Assignee != "" {
+		var assignee models.User
+		if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": subtask.Assignee}).Decode(&assignee); err == nil && assignee.ID != userID {
+			createNotification(ctx, assignee.ID, "assign",
+				"You have been assigned to the task \""+converted.Title+"\"",
+				converted.ProjectID, converted.ID)
+		}
+	}
+
+	created := withLinks(ctx, userID, *converted)
+	created.WIPWarnings = violations
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"card": created, "source": withLinks(ctx, userID, source)})
+}
+
+func CreateChecklist(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+
+	id, err := allocateSubtaskIDs(ctx, *card, 1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add checklist"})
+	}
+	checklist := models.Checklist{ID: id, Name: strings.TrimSpace(body.Name)}
+
+	_, err = database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{
+		"$push": bson.M{"checklists": checklist},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$inc":  bumpVersion,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add checklist"})
+	}
+
+	updated := finishCardEdit(ctx, userID, *card, appendChange(nil, "checklist", nil, checklist.Name))
+	setVersionETag(c, updated.Version)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"checklist": checklist, "card": withLinks(ctx, userID, updated)})
+}
+
+func UpdateChecklist(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	checklistID, err := strconv.Atoi(c.Params("checklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid checklist ID"})
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	idx := checklistIndex(*card, checklistID)
+	if idx < 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Checklist not found"})
+	}
+
+	name := strings.TrimSpace(body.Name)
+	res, err := database.GetCollection("cards").UpdateOne(ctx,
+		bson.M{"_id": card.ID, "checklists.id": checklistID},
+		bson.M{"$set": bson.M{"checklists.$[l].name": name, "updated_at": time.Now()}, "$inc": bumpVersion},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"l.id": checklistID}}}),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update checklist"})
+	}
+	if res.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Checklist not found"})
+	}
+
+	updated := finishCardEdit(ctx, userID, *card, appendChange(nil, "checklist", card.Checklists[idx].Name, name))
+	setVersionETag(c, updated.Version)
+	return c.JSON(withLinks(ctx, userID, updated))
+}
+
+// DeleteChecklist removes a named checklist together with its subtasks.
+func DeleteChecklist(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	checklistID, err := strconv.Atoi(c.Params("checklistId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid checklist ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	card, errResp := editableCard(c, ctx, userID)
+	if card == nil {
+		return errResp
+	}
+	idx := checklistIndex(*card, checklistID)
+	if idx < 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Checklist not found"})
+	}
+
+	_, err = database.GetCollection("cards").UpdateOne(ctx, bson.M{"_id": card.ID}, bson.M{
+		"$pull": bson.M{
+			"checklists": bson.M{"id": checklistID},
+			"subtasks":   bson.M{"checklist_id": checklistID},
+		},
+		"$set": bson.M{"updated_at": time.Now()},
+		"$inc": bumpVersion,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete checklist"})
+	}
+
+	updated := finishCardEdit(ctx, userID, *card, appendChange(nil, "checklist", card.Checklists[idx].Name, nil))
+	setVersionETag(c, updated.Version)
+	return c.JSON(withLinks(ctx, userID, updated))
+}
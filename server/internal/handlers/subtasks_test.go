@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fpmb/server/internal/models"
+)
+
+func TestFreshSubtasks(t *testing.T) {
+	card := models.Card{Subtasks: []models.Subtask{{ID: 1}, {ID: 2}}}
+	tests := []struct {
+		name     string
+		incoming []subtaskInput
+		want     []int
+	}{
+		{"all known", []subtaskInput{{ID: 2}, {ID: 1}}, []int{}},
+		{"new subtask", []subtaskInput{{ID: 1}, {ID: 0}}, []int{1}},
+		{"unknown id", []subtaskInput{{ID: 7}}, []int{0}},
+		{"repeated id", []subtaskInput{{ID: 1}, {ID: 1}, {ID: 2}}, []int{1}},
+	}
+	for _, tt := range tests {
+		if got := freshSubtasks(card, tt.incoming); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: freshSubtasks = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeSubtaskList(t *testing.T) {
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC)
+	card := models.Card{
+		Checklists: []models.Checklist{{ID: 3, Name: "QA"}},
+		Subtasks: []models.Subtask{
+			{ID: 1, ChecklistID: 3, Text: "Write", Assignee: "ann@example.com", DueDate: &due},
+			{ID: 2, Text: "Ship"},
+		},
+	}
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		incoming []subtaskInput
+		fresh    []int
+		first    int
+		want     []models.Subtask
+		wantErr  error
+	}{
+		{
+			name:     "absent fields keep stored values",
+			incoming: []subtaskInput{{ID: 2, Text: "Ship it", Done: true}, {ID: 1, Text: "Write"}},
+			want: []models.Subtask{
+				{ID: 2, Text: "Ship it", Done: true},
+				{ID: 1, ChecklistID: 3, Text: "Write", Assignee: "ann@example.com", DueDate: &due},
+			},
+		},
+		{
+			name:     "empty values clear",
+			incoming: []subtaskInput{{ID: 1, Text: "Write", ChecklistID: num(0), Assignee: str(""), DueDate: str("")}},
+			want:     []models.Subtask{{ID: 1, Text: "Write"}},
+		},
+		{
+			name:     "given values replace",
+			incoming: []subtaskInput{{ID: 2, Text: "Ship", ChecklistID: num(3), Assignee: str(" bob@example.com "), DueDate: str("2026-04-02")}},
+			want:     []models.Subtask{{ID: 2, ChecklistID: 3, Text: "Ship", Assignee: "bob@example.com", DueDate: &later}},
+		},
+		{
+			name:     "due date as returned by the API",
+			incoming: []subtaskInput{{ID: 2, Text: "Ship", DueDate: str("2026-04-02T00:00:00Z")}},
+			want:     []models.Subtask{{ID: 2, Text: "Ship", DueDate: &later}},
+		},
+		{
+			name:     "unknown checklist falls back to the default",
+			incoming: []subtaskInput{{ID: 2, Text: "Ship", ChecklistID: num(9)}},
+			want:     []models.Subtask{{ID: 2, Text: "Ship"}},
+		},
+		{
+			name:     "fresh subtasks get allocated ids",
+			incoming: []subtaskInput{{ID: 1, Text: "Write"}, {ID: 0, Text: "Test"}, {ID: 1, Text: "Copy"}},
+			fresh:    []int{1, 2},
+			first:    5,
+			want: []models.Subtask{
+				{ID: 1, ChecklistID: 3, Text: "Write", Assignee: "ann@example.com", DueDate: &due},
+				{ID: 5, Text: "Test"},
+				{ID: 6, Text: "Copy"},
+			},
+		},
+		{
+			name:     "invalid due date",
+			incoming: []subtaskInput{{ID: 2, Text: "Ship", DueDate: str("next week")}},
+			wantErr:  errSubtaskDueDate,
+		},
+	}
+	for _, tt := range tests {
+		got, err := mergeSubtaskList(card, tt.incoming, tt.fresh, tt.first)
+		if err != tt.wantErr {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: mergeSubtaskList = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// noLaneKey is the lane key for cards without a lane, assignee or label.
+const noLaneKey = "none"
+
+var priorityOrder = []string{"Urgent", "High", "Medium", "Low"}
+
+var errInvalidLane = errors.New("invalid lane")
+
+type laneCell struct {
+	ColumnID primitive.ObjectID   `json:"column_id"`
+	CardIDs  []primitive.ObjectID `json:"card_ids"`
+}
+
+type boardLane struct {
+	Key   string     `json:"key"`
+	Title string     `json:"title"`
+	Cells []laneCell `json:"cells"`
+}
+
+func laneTitle(ctx context.Context, laneID *primitive.ObjectID) string {
+	if laneID == nil {
+		return ""
+	}
+	var lane models.Swimlane
+	if err := database.GetCollection("swimlanes").FindOne(ctx, bson.M{"_id": *laneID}).Decode(&lane); err != nil {
+		return ""
+	}
+	return lane.Title
+}
+
+func validGroupBy(groupBy string) bool {
+	switch groupBy {
+	case "lane", "assignee", "priority", "label":
+		return true
+	}
+	return false
+}
+
+// cardLaneKeys returns the lanes a card belongs to under groupBy. Cards with
+// several assignees or labels appear in each matching lane.
+func cardLaneKeys(card models.Card, groupBy string) []string {
+	switch groupBy {
+	case "lane":
+		if card.LaneID != nil {
+			return []string{card.LaneID.Hex()}
+		}
+	case "assignee":
+		if len(card.Assignees) > 0 {
+			return card.Assignees
+		}
+	case "priority":
+		if card.Priority != "" {
+			return []string{card.Priority}
+		}
+	case "label":
+		if len(card.LabelIDs) > 0 {
+			keys := []string{}
+			for _, id := range card.LabelIDs {
+				keys = append(keys, id.Hex())
+			}
+			return keys
+		}
+	}
+	return []string{noLaneKey}
+}
+
+// laneHeaders lists the lanes for groupBy in display order, always ending
+// with the catch-all lane.
+func laneHeaders(ctx context.Context, projectID primitive.ObjectID, groupBy string, cards []models.Card) []boardLane {
+	lanes := []boardLane{}
+	switch groupBy {
+	case "lane":
+		cursor, err := database.GetCollection("swimlanes").Find(ctx, bson.M{"project_id": projectID},
+			options.Find().SetSort(rankSort))
+		if err == nil {
+			var defined []models.Swimlane
+			cursor.All(ctx, &defined)
+			cursor.Close(ctx)
+			for _, l := range defined {
+				lanes = append(lanes, boardLane{Key: l.ID.Hex(), Title: l.Title})
+			}
+		}
+		lanes = append(lanes, boardLane{Key: noLaneKey, Title: "No lane"})
+
+	case "assignee":
+		seen := map[string]bool{}
+		emails := []string{}
+		for _, card := range cards {
+			for _, email := range card.Assignees {
+				if !seen[email] {
+					seen[email] = true
+					emails = append(emails, email)
+				}
+			}
+		}
+		sort.Strings(emails)
+		for _, email := range emails {
+			title := email
+			var user models.User
+			if err := database.GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err == nil {
+				title = user.Name
+			}
+			lanes = append(lanes, boardLane{Key: email, Title: title})
+		}
+		lanes = append(lanes, boardLane{Key: noLaneKey, Title: "Unassigned"})
+
+	case "priority":
+		known := map[string]bool{}
+		for _, p := range priorityOrder {
+			known[p] = true
+			lanes = append(lanes, boardLane{Key: p, Title: p})
+		}
+		extra := []string{}
+		for _, card := range cards {
+			if card.Priority != "" && !known[card.Priority] {
+				known[card.Priority] = true
+				extra = append(extra, card.Priority)
+			}
+		}
+		sort.Strings(extra)
+		for _, p := range extra {
+			lanes = append(lanes, boardLane{Key: p, Title: p})
+		}
+		lanes = append(lanes, boardLane{Key: noLaneKey, Title: "No priority"})
+
+	case "label":
+		cursor, err := database.GetCollection("labels").Find(ctx, bson.M{"project_id": projectID},
+			options.Find().SetSort(bson.M{"name": 1}))
+		if err == nil {
+			var labels []models.Label
+			cursor.All(ctx, &labels)
+			cursor.Close(ctx)
+			for _, l := range labels {
+				lanes = append(lanes, boardLane{Key: l.ID.Hex(), Title: l.Name})
+			}
+		}
+		lanes = append(lanes, boardLane{Key: noLaneKey, Title: "No label"})
+	}
+	return lanes
+}
+
+// buildLanes arranges the already ordered cards of each column into a
+// lanes × columns matrix. Cells keep the column's card order.
+func buildLanes(ctx context.Context, projectID primitive.ObjectID, groupBy string, columns []models.BoardColumn, columnCards [][]models.Card) []boardLane {
+	all := []models.Card{}
+	for _, cards := range columnCards {
+		all = append(all, cards...)
+	}
+
+	lanes := laneHeaders(ctx, projectID, groupBy, all)
+	index := map[string]int{}
+	for i := range lanes {
+		index[lanes[i].Key] = i
+		lanes[i].Cells = make([]laneCell, len(columns))
+		for j, col := range columns {
+			lanes[i].Cells[j] = laneCell{ColumnID: col.ID, CardIDs: []primitive.ObjectID{}}
+		}
+	}
+
+	for j, cards := range columnCards {
+		for _, card := range cards {
+			for _, key := range cardLaneKeys(card, groupBy) {
+				i, ok := index[key]
+				if !ok {
+					i = index[noLaneKey]
+				}
+				lanes[i].Cells[j].CardIDs = append(lanes[i].Cells[j].CardIDs, card.ID)
+			}
+		}
+	}
+	return lanes
+}
+
+// laneMoveUpdate translates moving a card from one lane to another under
+// groupBy into a card update. For multi-valued groupings only the value of
+// the source lane is swapped for the target's. cell matches the cards of
+// the target lane, for ranking the card among them.
+func laneMoveUpdate(ctx context.Context, card models.Card, groupBy, fromKey, toKey string) (update, cell bson.M, err error) {
+	update = bson.M{}
+	empty := bson.M{"$in": bson.A{nil, bson.A{}}}
+	switch groupBy {
+	case "lane":
+		if toKey == noLaneKey || toKey == "" {
+			update["lane_id"] = nil
+			return update, bson.M{"lane_id": nil}, nil
+		}
+		laneID, err := primitive.ObjectIDFromHex(toKey)
+		if err != nil {
+			return nil, nil, errInvalidLane
+		}
+		if n, _ := database.GetCollection("swimlanes").CountDocuments(ctx, bson.M{"_id": laneID, "project_id": card.ProjectID}); n == 0 {
+			return nil, nil, errInvalidLane
+		}
+		update["lane_id"] = laneID
+		cell = bson.M{"lane_id": laneID}
+
+	case "priority":
+		if toKey == noLaneKey {
+			toKey = ""
+		}
+		// Besides the standard priorities, a card may only join a lane
+		// that other cards of the project already have.
+		if toKey != "" && !containsString(priorityOrder, toKey) {
+			if n, _ := database.GetCollection("cards").CountDocuments(ctx, bson.M{"project_id": card.ProjectID, "priority": toKey}); n == 0 {
+				return nil, nil, errInvalidLane
+			}
+		}
+		update["priority"] = toKey
+		cell = bson.M{"priority": toKey}
+		if toKey == "" {
+			cell = bson.M{"priority": bson.M{"$in": bson.A{nil, ""}}}
+		}
+
+	case "assignee":
+		if toKey != noLaneKey && !isProjectMemberEmail(ctx, card.ProjectID, toKey) {
+			return nil, nil, errInvalidLane
+		}
+		assignees := []string{}
+		for _, email := range card.Assignees {
+			if email != fromKey && email != toKey {
+				assignees = append(assignees, email)
+			}
+		}
+		if toKey == noLaneKey {
+			assignees = []string{}
+			cell = bson.M{"assignees": empty}
+		} else {
+			assignees = append(assignees, toKey)
+			cell = bson.M{"assignees": toKey}
+		}
+		update["assignees"] = assignees
+
+	case "label":
+		labelIDs := []primitive.ObjectID{}
+		for _, id := range card.LabelIDs {
+			if id.Hex() != fromKey && id.Hex() != toKey {
+				labelIDs = append(labelIDs, id)
+			}
+		}
+		if toKey == noLaneKey {
+			labelIDs = []primitive.ObjectID{}
+			cell = bson.M{"label_ids": empty}
+		} else {
+			resolved, err := resolveLabelIDs(ctx, card.ProjectID, []string{toKey})
+			if err != nil || len(resolved) == 0 {
+				return nil, nil, errInvalidLane
+			}
+			labelIDs = append(labelIDs, resolved...)
+			cell = bson.M{"label_ids": resolved[0]}
+		}
+		update["label_ids"] = labelIDs
+
+	default:
+		return nil, nil, errInvalidLane
+	}
+	return update, cell, nil
+}
+
+func ListSwimlanes(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	cursor, err := database.GetCollection("swimlanes").Find(ctx, bson.M{"project_id": projectID},
+		options.Find().SetSort(rankSort))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch lanes"})
+	}
+	defer cursor.Close(ctx)
+
+	var lanes []models.Swimlane
+	cursor.All(ctx, &lanes)
+	if lanes == nil {
+		lanes = []models.Swimlane{}
+	}
+	return c.JSON(lanes)
+}
+
+func CreateSwimlane(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	var body struct {
+		Title string `json:"title"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Title) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	now := time.Now()
+	lane := &models.Swimlane{
+		ID:        primitive.NewObjectID(),
+		ProjectID: projectID,
+		Title:     strings.TrimSpace(body.Title),
+		Rank:      rankAt(ctx, "swimlanes", bson.M{"project_id": projectID}, primitive.NilObjectID, -1),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	database.GetCollection("swimlanes").InsertOne(ctx, lane)
+	recordActivity(ctx, projectID, userID, "created", "lane", lane.ID, lane.Title, nil)
+	return c.Status(fiber.StatusCreated).JSON(lane)
+}
+
+func UpdateSwimlane(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	laneID, err := primitive.ObjectIDFromHex(c.Params("laneId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lane ID"})
+	}
+
+	var body struct {
+		Title    string `json:"title"`
+		Position *int   `json:"position"`
+	}
+	c.BodyParser(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	col := database.GetCollection("swimlanes")
+	var before models.Swimlane
+	if err := col.FindOne(ctx, bson.M{"_id": laneID, "project_id": projectID}).Decode(&before); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lane not found"})
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if title := strings.TrimSpace(body.Title); title != "" {
+		update["title"] = title
+	}
+	if body.Position != nil {
+		update["rank"] = rankAt(ctx, "swimlanes", bson.M{"project_id": projectID}, laneID, *body.Position)
+	}
+	col.UpdateOne(ctx, bson.M{"_id": laneID}, bson.M{"$set": update})
+
+	var lane models.Swimlane
+	col.FindOne(ctx, bson.M{"_id": laneID}).Decode(&lane)
+
+	if changes := appendChange(nil, "title", before.Title, lane.Title); len(changes) > 0 {
+		recordActivity(ctx, projectID, userID, "updated", "lane", laneID, lane.Title, changes)
+	}
+
+	return c.JSON(lane)
+}
+
+func DeleteSwimlane(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	laneID, err := primitive.ObjectIDFromHex(c.Params("laneId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lane ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	roleFlags, err := getProjectRole(ctx, projectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	var lane models.Swimlane
+	if err := database.GetCollection("swimlanes").FindOne(ctx, bson.M{"_id": laneID, "project_id": projectID}).Decode(&lane); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lane not found"})
+	}
+
+	database.GetCollection("swimlanes").DeleteOne(ctx, bson.M{"_id": laneID})
+	database.GetCollection("cards").UpdateMany(ctx,
+		bson.M{"project_id": projectID, "lane_id": laneID},
+		bson.M{"$unset": bson.M{"lane_id": ""}, "$inc": bumpVersion},
+	)
+	database.GetCollection("recurrences").UpdateMany(ctx,
+		bson.M{"project_id": projectID, "lane_id": laneID},
+		bson.M{"$unset": bson.M{"lane_id": ""}},
+	)
+	recordActivity(ctx, projectID, userID, "deleted", "lane", laneID, lane.Title, nil)
+
+	return c.JSON(fiber.Map{"message": "Lane deleted"})
+}
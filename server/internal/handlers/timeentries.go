@@ -0,0 +1,679 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxEntryMinutes = 24 * 60
+
+// EnsureTimeEntryIndexes enforces one running timer per user.
+func EnsureTimeEntryIndexes(ctx context.Context) {
+	_, err := database.GetCollection("time_entries").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().
+			SetName("one_running_timer").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"running": true}),
+	})
+	if err != nil {
+		log.Printf("EnsureTimeEntryIndexes: %v", err)
+	}
+}
+
+func entryMinutes(start, end time.Time) int {
+	m := int(math.Round(end.Sub(start).Minutes()))
+	if m < 1 {
+		m = 1
+	}
+	return m
+}
+
+// syncActualMinutes derives a card's actual_minutes from its finished time
+// entries. Cards without entries keep their manually entered value, which
+// is set aside while entries are tracked and comes back once they are all
+// deleted.
+func syncActualMinutes(ctx context.Context, cardID primitive.ObjectID) {
+	cursor, err := database.GetCollection("time_entries").Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"card_id": cardID, "running": false}},
+		bson.M{"$group": bson.M{"_id": nil, "minutes": bson.M{"$sum": "$minutes"}}},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		Minutes int `bson:"minutes"`
+	}
+	cursor.All(ctx, &totals)
+	if len(totals) == 0 {
+		return
+	}
+
+	cards := database.GetCollection("cards")
+	var card models.Card
+	if err := cards.FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return
+	}
+	set := bson.M{"actual_minutes": totals[0].Minutes}
+	if !card.MinutesTracked {
+		set["minutes_tracked"] = true
+		set["manual_minutes"] = card.ActualMinutes
+	}
+	cards.UpdateOne(ctx, bson.M{"_id": cardID}, bson.M{"$set": set, "$inc": bumpVersion})
+}
+
+// restoreManualMinutes hands actual_minutes back to the value entered by
+// hand once a card's last time entry is gone.
+func restoreManualMinutes(ctx context.Context, cardID primitive.ObjectID) {
+	cards := database.GetCollection("cards")
+	var card models.Card
+	if err := cards.FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil || !card.MinutesTracked {
+		return
+	}
+	update := bson.M{
+		"$unset": bson.M{"manual_minutes": "", "minutes_tracked": ""},
+		"$inc":   bumpVersion,
+	}
+	if card.ManualMinutes != nil {
+		update["$set"] = bson.M{"actual_minutes": *card.ManualMinutes}
+	} else {
+		update["$unset"].(bson.M)["actual_minutes"] = ""
+	}
+	cards.UpdateOne(ctx, bson.M{"_id": cardID}, update)
+}
+
+func cardHasTimeEntries(ctx context.Context, cardID primitive.ObjectID) bool {
+	n, _ := database.GetCollection("time_entries").CountDocuments(ctx, bson.M{"card_id": cardID})
+	return n > 0
+}
+
+// csvCell keeps user-entered text from being read as a formula when the
+// export is opened in a spreadsheet.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// stopRunningTimer ends the user's running timer, if any.
+func stopRunningTimer(ctx context.Context, userID primitive.ObjectID) *models.TimeEntry {
+	col := database.GetCollection("time_entries")
+	var entry models.TimeEntry
+	if err := col.FindOne(ctx, bson.M{"user_id": userID, "running": true}).Decode(&entry); err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	entry.EndedAt = &now
+	entry.Minutes = entryMinutes(entry.StartedAt, now)
+	entry.Running = false
+	entry.UpdatedAt = now
+	res, err := col.UpdateOne(ctx, bson.M{"_id": entry.ID, "running": true}, bson.M{"$set": bson.M{
+		"ended_at":   now,
+		"minutes":    entry.Minutes,
+		"running":    false,
+		"updated_at": now,
+	}})
+	if err != nil || res.ModifiedCount == 0 {
+		return nil
+	}
+	syncActualMinutes(ctx, entry.CardID)
+	return &entry
+}
+
+// parseEntryTimes resolves the start, end and length of a manual entry from
+// any two of started_at, ended_at and minutes.
+func parseEntryTimes(startRaw, endRaw string, minutes int) (time.Time, time.Time, int, error) {
+	var start, end time.Time
+	var err error
+	if startRaw != "" {
+		if start, err = time.Parse(time.RFC3339, startRaw); err != nil {
+			return start, end, 0, fmt.Errorf("started_at must be RFC 3339")
+		}
+	}
+	if endRaw != "" {
+		if end, err = time.Parse(time.RFC3339, endRaw); err != nil {
+			return start, end, 0, fmt.Errorf("ended_at must be RFC 3339")
+		}
+	}
+
+	switch {
+	case !start.IsZero() && !end.IsZero():
+		if !end.After(start) {
+			return start, end, 0, fmt.Errorf("ended_at must be after started_at")
+		}
+		minutes = entryMinutes(start, end)
+	case minutes <= 0:
+		return start, end, 0, fmt.Errorf("give minutes or both started_at and ended_at")
+	case !start.IsZero():
+		end = start.Add(time.Duration(minutes) * time.Minute)
+	default:
+		if end.IsZero() {
+			end = time.Now()
+		}
+		start = end.Add(-time.Duration(minutes) * time.Minute)
+	}
+
+	if minutes > maxEntryMinutes {
+		return start, end, 0, fmt.Errorf("an entry cannot exceed 24 hours")
+	}
+	if end.After(time.Now().Add(time.Minute)) {
+		return start, end, 0, fmt.Errorf("entries cannot end in the future")
+	}
+	return start.UTC(), end.UTC(), minutes, nil
+}
+
+type timeEntryResponse struct {
+	models.TimeEntry
+	UserName string `json:"user_name"`
+}
+
+func ListCardTimeEntries(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	if _, err := getProjectRole(ctx, card.ProjectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	cursor, err := database.GetCollection("time_entries").Find(ctx, bson.M{"card_id": cardID},
+		options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch time entries"})
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.TimeEntry
+	cursor.All(ctx, &entries)
+
+	names := map[primitive.ObjectID]string{}
+	result := []timeEntryResponse{}
+	total := 0
+	for _, e := range entries {
+		if _, ok := names[e.UserID]; !ok {
+			names[e.UserID] = userName(ctx, e.UserID)
+		}
+		result = append(result, timeEntryResponse{TimeEntry: e, UserName: names[e.UserID]})
+		total += e.Minutes
+	}
+
+	return c.JSON(fiber.Map{"entries": result, "total_minutes": total})
+}
+
+func CreateTimeEntry(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		StartedAt string `json:"started_at"`
+		EndedAt   string `json:"ended_at"`
+		Minutes   int    `json:"minutes"`
+		Note      string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	start, end, minutes, err := parseEntryTimes(body.StartedAt, body.EndedAt, body.Minutes)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	now := time.Now()
+	entry := &models.TimeEntry{
+		ID:        primitive.NewObjectID(),
+		ProjectID: card.ProjectID,
+		CardID:    cardID,
+		UserID:    userID,
+		StartedAt: start,
+		EndedAt:   &end,
+		Minutes:   minutes,
+		Note:      strings.TrimSpace(body.Note),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := database.GetCollection("time_entries").InsertOne(ctx, entry); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log time"})
+	}
+	syncActualMinutes(ctx, cardID)
+	recordActivity(ctx, card.ProjectID, userID, "logged_time", "card", card.ID, card.Title,
+		[]models.ActivityChange{{Field: "minutes", To: minutes}})
+
+	return c.Status(fiber.StatusCreated).JSON(entry)
+}
+
+// ownTimeEntry loads an entry the caller may change: their own, or any
+// entry of a project they administer.
+func ownTimeEntry(c *fiber.Ctx, ctx context.Context, userID primitive.ObjectID) (*models.TimeEntry, error) {
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+	entryID, err := primitive.ObjectIDFromHex(c.Params("entryId"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid time entry ID"})
+	}
+
+	var entry models.TimeEntry
+	if err := database.GetCollection("time_entries").FindOne(ctx, bson.M{"_id": entryID, "card_id": cardID}).Decode(&entry); err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Time entry not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, entry.ProjectID, userID)
+	if err != nil || (entry.UserID != userID && !hasPermission(roleFlags, RoleAdmin)) {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, entry.ProjectID); err != nil {
+		return nil, projectWriteError(c, err)
+	}
+	return &entry, nil
+}
+
+func UpdateTimeEntry(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	var body struct {
+		StartedAt *string `json:"started_at"`
+		EndedAt   *string `json:"ended_at"`
+		Minutes   *int    `json:"minutes"`
+		Note      *string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entry, errResp := ownTimeEntry(c, ctx, userID)
+	if entry == nil {
+		return errResp
+	}
+
+	if body.StartedAt != nil || body.EndedAt != nil || body.Minutes != nil {
+		if entry.Running {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Stop the timer before changing its times"})
+		}
+		startRaw := entry.StartedAt.Format(time.RFC3339)
+		endRaw := ""
+		minutes := 0
+		if body.StartedAt != nil {
+			startRaw = *body.StartedAt
+		}
+		if body.Minutes != nil {
+			minutes = *body.Minutes
+		} else if body.EndedAt != nil {
+			endRaw = *body.EndedAt
+		} else {
+			minutes = entry.Minutes
+		}
+		start, end, m, err := parseEntryTimes(startRaw, endRaw, minutes)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		entry.StartedAt, entry.EndedAt, entry.Minutes = start, &end, m
+	}
+	if body.Note != nil {
+		entry.Note = strings.TrimSpace(*body.Note)
+	}
+	entry.UpdatedAt = time.Now()
+
+	database.GetCollection("time_entries").ReplaceOne(ctx, bson.M{"_id": entry.ID}, entry)
+	syncActualMinutes(ctx, entry.CardID)
+
+	return c.JSON(entry)
+}
+
+func DeleteTimeEntry(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entry, errResp := ownTimeEntry(c, ctx, userID)
+	if entry == nil {
+		return errResp
+	}
+
+	database.GetCollection("time_entries").DeleteOne(ctx, bson.M{"_id": entry.ID})
+	if cardHasTimeEntries(ctx, entry.CardID) {
+		syncActualMinutes(ctx, entry.CardID)
+	} else {
+		restoreManualMinutes(ctx, entry.CardID)
+	}
+
+	return c.JSON(fiber.Map{"message": "Time entry deleted"})
+}
+
+// StartTimer starts a timer on the card, stopping the caller's running
+// timer first.
+func StartTimer(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	cardID, err := primitive.ObjectIDFromHex(c.Params("cardId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	c.BodyParser(&body)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	roleFlags, err := getProjectRole(ctx, card.ProjectID, userID)
+	if err != nil || !hasPermission(roleFlags, RoleEditor) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	if err := ensureProjectWritable(ctx, card.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	if card.ArchivedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is archived; restore it first"})
+	}
+
+	stopped := stopRunningTimer(ctx, userID)
+
+	now := time.Now()
+	entry := &models.TimeEntry{
+		ID:        primitive.NewObjectID(),
+		ProjectID: card.ProjectID,
+		CardID:    cardID,
+		UserID:    userID,
+		StartedAt: now,
+		Running:   true,
+		Note:      strings.TrimSpace(body.Note),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := database.GetCollection("time_entries").InsertOne(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Another timer was started at the same time"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start timer"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"entry": entry, "stopped": stopped})
+}
+
+func GetTimer(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry models.TimeEntry
+	if err := database.GetCollection("time_entries").FindOne(ctx, bson.M{"user_id": userID, "running": true}).Decode(&entry); err != nil {
+		return c.JSON(fiber.Map{"entry": nil})
+	}
+
+	var card models.Card
+	database.GetCollection("cards").FindOne(ctx, bson.M{"_id": entry.CardID}).Decode(&card)
+	return c.JSON(fiber.Map{"entry": entry, "card_title": card.Title})
+}
+
+func StopTimer(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entry := stopRunningTimer(ctx, userID)
+	if entry == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No timer is running"})
+	}
+
+	var card models.Card
+	if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": entry.CardID}).Decode(&card); err == nil {
+		recordActivity(ctx, card.ProjectID, userID, "logged_time", "card", card.ID, card.Title,
+			[]models.ActivityChange{{Field: "minutes", To: entry.Minutes}})
+	}
+
+	return c.JSON(entry)
+}
+
+// GetTimesheet reports finished time entries for one week (the week
+// containing ?week=YYYY-MM-DD, default this week). Without project_id it
+// covers the caller's own time; with it, project admins see every member
+// and may narrow to user_id. format=csv returns one row per entry.
+func GetTimesheet(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	day := time.Now()
+	if raw := c.Query("week"); raw != "" {
+		if day, err = time.Parse("2006-01-02", raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "week must be YYYY-MM-DD"})
+		}
+	}
+	from := weekStart(day)
+	to := from.AddDate(0, 0, 7)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"running": false, "started_at": bson.M{"$gte": from, "$lt": to}}
+
+	targetUser := userID
+	if raw := c.Query("user_id"); raw != "" {
+		if targetUser, err = primitive.ObjectIDFromHex(raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+	}
+
+	if raw := c.Query("project_id"); raw != "" {
+		projectID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+		}
+		roleFlags, err := getProjectRole(ctx, projectID, userID)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+		}
+		isAdmin := hasPermission(roleFlags, RoleAdmin)
+		if targetUser != userID && !isAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only project admins can view other members' time"})
+		}
+		filter["project_id"] = projectID
+		if c.Query("user_id") != "" || !isAdmin {
+			filter["user_id"] = targetUser
+		}
+	} else {
+		if targetUser != userID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Give project_id to view other members' time"})
+		}
+		filter["user_id"] = userID
+	}
+
+	cursor, err := database.GetCollection("time_entries").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch time entries"})
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.TimeEntry
+	cursor.All(ctx, &entries)
+
+	users := map[primitive.ObjectID]models.User{}
+	projectNames := map[primitive.ObjectID]string{}
+	cardTitles := map[primitive.ObjectID]string{}
+	for _, e := range entries {
+		if _, ok := users[e.UserID]; !ok {
+			var u models.User
+			database.GetCollection("users").FindOne(ctx, bson.M{"_id": e.UserID}).Decode(&u)
+			users[e.UserID] = u
+		}
+		if _, ok := projectNames[e.ProjectID]; !ok {
+			var p models.Project
+			database.GetCollection("projects").FindOne(ctx, bson.M{"_id": e.ProjectID}).Decode(&p)
+			projectNames[e.ProjectID] = p.Name
+		}
+		if _, ok := cardTitles[e.CardID]; !ok {
+			title := "(deleted card)"
+			var card models.Card
+			if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": e.CardID}).Decode(&card); err == nil {
+				title = card.Title
+			}
+			cardTitles[e.CardID] = title
+		}
+	}
+
+	if c.Query("format") == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"date", "user", "email", "project", "card", "started_at", "ended_at", "minutes", "hours", "note"})
+		for _, e := range entries {
+			ended := ""
+			if e.EndedAt != nil {
+				ended = e.EndedAt.UTC().Format(time.RFC3339)
+			}
+			w.Write([]string{
+				e.StartedAt.UTC().Format("2006-01-02"),
+				csvCell(users[e.UserID].Name),
+				csvCell(users[e.UserID].Email),
+				csvCell(projectNames[e.ProjectID]),
+				csvCell(cardTitles[e.CardID]),
+				e.StartedAt.UTC().Format(time.RFC3339),
+				ended,
+				strconv.Itoa(e.Minutes),
+				strconv.FormatFloat(float64(e.Minutes)/60, 'f', 2, 64),
+				csvCell(e.Note),
+			})
+		}
+		w.Flush()
+
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="timesheet-%s.csv"`, from.Format("2006-01-02")))
+		return c.Send(buf.Bytes())
+	}
+
+	type TimesheetRow struct {
+		UserID      primitive.ObjectID `json:"user_id"`
+		UserName    string             `json:"user_name"`
+		ProjectID   primitive.ObjectID `json:"project_id"`
+		ProjectName string             `json:"project_name"`
+		CardID      primitive.ObjectID `json:"card_id"`
+		CardTitle   string             `json:"card_title"`
+		Days        [7]int             `json:"days"`
+		Total       int                `json:"total"`
+	}
+
+	type rowKey struct{ user, card primitive.ObjectID }
+	index := map[rowKey]int{}
+	rows := []TimesheetRow{}
+	var dayTotals [7]int
+	total := 0
+	for _, e := range entries {
+		key := rowKey{e.UserID, e.CardID}
+		i, ok := index[key]
+		if !ok {
+			i = len(rows)
+			index[key] = i
+			rows = append(rows, TimesheetRow{
+				UserID:      e.UserID,
+				UserName:    users[e.UserID].Name,
+				ProjectID:   e.ProjectID,
+				ProjectName: projectNames[e.ProjectID],
+				CardID:      e.CardID,
+				CardTitle:   cardTitles[e.CardID],
+			})
+		}
+		d := int(e.StartedAt.UTC().Sub(from).Hours() / 24)
+		rows[i].Days[d] += e.Minutes
+		rows[i].Total += e.Minutes
+		dayTotals[d] += e.Minutes
+		total += e.Minutes
+	}
+
+	return c.JSON(fiber.Map{
+		"week_start": from.Format("2006-01-02"),
+		"week_end":   to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"rows":       rows,
+		"day_totals": dayTotals,
+		"total":      total,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEntryTimes(t *testing.T) {
+	start := time.Date(2026, 2, 3, 9, 0, 0, 0, time.UTC)
+	rfc := func(t time.Time) string { return t.Format(time.RFC3339) }
+	future := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		start, end  string
+		minutes     int
+		wantStart   time.Time
+		wantEnd     time.Time
+		wantMinutes int
+		wantErr     bool
+	}{
+		{name: "start and end", start: rfc(start), end: rfc(start.Add(90 * time.Minute)),
+			wantStart: start, wantEnd: start.Add(90 * time.Minute), wantMinutes: 90},
+		{name: "start and end override minutes", start: rfc(start), end: rfc(start.Add(30 * time.Minute)), minutes: 500,
+			wantStart: start, wantEnd: start.Add(30 * time.Minute), wantMinutes: 30},
+		{name: "start and minutes", start: rfc(start), minutes: 45,
+			wantStart: start, wantEnd: start.Add(45 * time.Minute), wantMinutes: 45},
+		{name: "end and minutes", end: rfc(start), minutes: 60,
+			wantStart: start.Add(-time.Hour), wantEnd: start, wantMinutes: 60},
+		{name: "offset times come back in UTC", start: "2026-02-03T10:00:00+01:00", minutes: 15,
+			wantStart: start, wantEnd: start.Add(15 * time.Minute), wantMinutes: 15},
+		{name: "a few seconds count as a minute", start: rfc(start), end: rfc(start.Add(10 * time.Second)),
+			wantStart: start, wantEnd: start.Add(10 * time.Second), wantMinutes: 1},
+		{name: "nothing given", wantErr: true},
+		{name: "start only", start: rfc(start), wantErr: true},
+		{name: "negative minutes", start: rfc(start), minutes: -5, wantErr: true},
+		{name: "end before start", start: rfc(start), end: rfc(start.Add(-time.Minute)), wantErr: true},
+		{name: "longer than a day", start: rfc(start), minutes: 24*60 + 1, wantErr: true},
+		{name: "ends in the future", start: rfc(future), minutes: 10, wantErr: true},
+		{name: "bad start", start: "yesterday", minutes: 10, wantErr: true},
+		{name: "bad end", end: "2026-02-03", minutes: 10, wantErr: true},
+	}
+	for _, tt := range tests {
+		gotStart, gotEnd, gotMinutes, err := parseEntryTimes(tt.start, tt.end, tt.minutes)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got %v-%v (%d)", tt.name, gotStart, gotEnd, gotMinutes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !gotStart.Equal(tt.wantStart) || !gotEnd.Equal(tt.wantEnd) || gotMinutes != tt.wantMinutes {
+			t.Errorf("%s: got %v-%v (%d), want %v-%v (%d)", tt.name,
+				gotStart, gotEnd, gotMinutes, tt.wantStart, tt.wantEnd, tt.wantMinutes)
+		}
+		if gotStart.Location() != time.UTC || gotEnd.Location() != time.UTC {
+			t.Errorf("%s: times not in UTC", tt.name)
+		}
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"Fix login", "Fix login"},
+		{"=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"+1 review", "'+1 review"},
+		{"-2 hours", "'-2 hours"},
+		{"@sum", "'@sum"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := csvCell(tt.in); got != tt.want {
+			t.Errorf("csvCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
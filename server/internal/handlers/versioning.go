@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Cards, columns and docs carry a version that every write increments.
+// Clients send it back in If-Match; a stale version gets 412 with the
+// current document so they can merge instead of overwriting.
+
+var (
+	errBadIfMatch      = errors.New("invalid If-Match header")
+	errVersionMismatch = errors.New("version mismatch")
+)
+
+// bumpVersion is the $inc every write to a versioned document includes.
+var bumpVersion = bson.M{"version": 1}
+
+func setVersionETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// ifMatchVersion reads the version the client last saw. ok is false when
+// the header is absent or "*", in which case the write is unconditional.
+func ifMatchVersion(c *fiber.Ctx) (version int64, ok bool, err error) {
+	raw := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if raw == "" || raw == "*" {
+		return 0, false, nil
+	}
+	raw = strings.TrimPrefix(raw, "W/")
+	raw = strings.Trim(raw, `"`)
+	version, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil || version < 0 {
+		return 0, false, errBadIfMatch
+	}
+	return version, true, nil
+}
+
+// versionFilter matches a document still at version. Documents written
+// before versioning have no field and count as version 0.
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// matchVersion checks If-Match against the stored version and, when the
+// client sent one, pins filter to it so a concurrent write in between
+// matches nothing.
+func matchVersion(c *fiber.Ctx, filter bson.M, version int64) error {
+	expected, ok, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if expected != version {
+		return errVersionMismatch
+	}
+	filter["version"] = versionFilter(version)
+	return nil
+}
+
+// versionError reports a matchVersion failure.
+func versionError(c *fiber.Ctx, err error, version int64, current interface{}) error {
+	if errors.Is(err, errBadIfMatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid If-Match header"})
+	}
+	return versionConflict(c, version, current)
+}
+
+// versionConflict answers a write whose If-Match is stale, handing back
+// the current document so the client can merge.
+func versionConflict(c *fiber.Ctx, version int64, current interface{}) error {
+	setVersionETag(c, version)
+	return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+		"error":   "Version mismatch; reload and merge your changes",
+		"version": version,
+		"current": current,
+	})
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultView picks the board view applied when GetBoard is called without
+// a query: the user's own default for the project, else the project's
+// shared default.
+func defaultView(ctx context.Context, userID, projectID primitive.ObjectID) *models.SavedView {
+	col := database.GetCollection("saved_views")
+	var view models.SavedView
+	if err := col.FindOne(ctx, bson.M{
+		"project_id": projectID, "owner_id": userID, "shared": false, "is_default": true,
+	}).Decode(&view); err == nil {
+		return &view
+	}
+	if err := col.FindOne(ctx, bson.M{
+		"project_id": projectID, "shared": true, "is_default": true,
+	}).Decode(&view); err == nil {
+		return &view
+	}
+	return nil
+}
+
+// readableView loads a view the user may apply: their own, or a view shared
+// in a project they can read.
+func readableView(ctx context.Context, userID, viewID primitive.ObjectID) (*models.SavedView, error) {
+	var view models.SavedView
+	if err := database.GetCollection("saved_views").FindOne(ctx, bson.M{"_id": viewID}).Decode(&view); err != nil {
+		return nil, err
+	}
+	if view.OwnerID == userID {
+		return &view, nil
+	}
+	if view.Shared && view.ProjectID != nil {
+		if _, err := getProjectRole(ctx, *view.ProjectID, userID); err == nil {
+			return &view, nil
+		}
+	}
+	return nil, fiber.ErrForbidden
+}
+
+// clearOtherDefaults keeps at most one default per owner and project for
+// personal views, and one per project for shared views.
+func clearOtherDefaults(ctx context.Context, view models.SavedView) {
+	filter := bson.M{"_id": bson.M{"$ne": view.ID}, "project_id": view.ProjectID, "shared": view.Shared, "is_default": true}
+	if !view.Shared {
+		filter["owner_id"] = view.OwnerID
+	}
+	database.GetCollection("saved_views").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"is_default": false}})
+}
+
+type savedViewBody struct {
+	Name      *string `json:"name"`
+	Query     *string `json:"query"`
+	GroupBy   *string `json:"group_by"`
+	Sort      *string `json:"sort"`
+	Shared    *bool   `json:"shared"`
+	IsDefault *bool   `json:"is_default"`
+}
+
+// applyViewBody validates and copies the body onto view. Shared views need
+// editor rights and a shared default needs admin rights on the project.
+func applyViewBody(ctx context.Context, userID primitive.ObjectID, view *models.SavedView, body savedViewBody) (int, string) {
+	if body.Name != nil {
+		view.Name = strings.TrimSpace(*body.Name)
+	}
+	if view.Name == "" {
+		return fiber.StatusBadRequest, "Name is required"
+	}
+	if body.GroupBy != nil {
+		if *body.GroupBy != "" && !validGroupBy(*body.GroupBy) {
+			return fiber.StatusBadRequest, "group_by must be one of lane, assignee, priority, label"
+		}
+		view.GroupBy = *body.GroupBy
+	}
+	if body.Sort != nil {
+		view.Sort = *body.Sort
+	}
+	if body.Shared != nil {
+		view.Shared = *body.Shared
+	}
+	if body.IsDefault != nil {
+		view.IsDefault = *body.IsDefault
+	}
+
+	projectIDs := []primitive.ObjectID{}
+	if view.ProjectID != nil {
+		projectIDs = append(projectIDs, *view.ProjectID)
+		roleFlags, err := getProjectRole(ctx, *view.ProjectID, userID)
+		if err != nil {
+			return fiber.StatusForbidden, "Access denied"
+		}
+		if view.Shared && !hasPermission(roleFlags, RoleEditor) {
+			return fiber.StatusForbidden, "Only editors can share views"
+		}
+		if view.Shared && view.IsDefault && !hasPermission(roleFlags, RoleAdmin) {
+			return fiber.StatusForbidden, "Only admins can set the project default view"
+		}
+		if _, err := compileBoardSort(ctx, *view.ProjectID, view.Sort); err != nil {
+			return fiber.StatusBadRequest, err.Error()
+		}
+	} else {
+		if view.Sort != "" {
+			return fiber.StatusBadRequest, "Only project views can be sorted"
+		}
+		if view.Shared {
+			return fiber.StatusBadRequest, "Only project views can be shared"
+		}
+		projectIDs = accessibleProjectIDs(ctx, userID, false)
+	}
+
+	if body.Query != nil {
+		view.Query = strings.TrimSpace(*body.Query)
+	}
+	if _, err := compileBoardQuery(ctx, view.Query, newQueryScope(ctx, userID, projectIDs)); err != nil {
+		return fiber.StatusBadRequest, "Invalid query: " + err.Error()
+	}
+	return 0, ""
+}
+
+func listViews(c *fiber.Ctx, ctx context.Context, filter bson.M) error {
+	cursor, err := database.GetCollection("saved_views").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch views"})
+	}
+	defer cursor.Close(ctx)
+
+	views := []models.SavedView{}
+	cursor.All(ctx, &views)
+	return c.JSON(views)
+}
+
+func createView(c *fiber.Ctx, ctx context.Context, userID primitive.ObjectID, projectID *primitive.ObjectID) error {
+	var body savedViewBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	now := time.Now()
+	view := models.SavedView{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   userID,
+		ProjectID: projectID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if status, msg := applyViewBody(ctx, userID, &view, body); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	if _, err := database.GetCollection("saved_views").InsertOne(ctx, view); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create view"})
+	}
+	if view.IsDefault {
+		clearOtherDefaults(ctx, view)
+	}
+	if view.Shared {
+		recordActivity(ctx, *projectID, userID, "created", "view", view.ID, view.Name, nil)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(view)
+}
+
+func ListProjectViews(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	return listViews(c, ctx, bson.M{
+		"project_id": projectID,
+		"$or":        bson.A{bson.M{"owner_id": userID}, bson.M{"shared": true}},
+	})
+}
+
+func CreateProjectView(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
+	return createView(c, ctx, userID, &projectID)
+}
+
+func ListViews(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return listViews(c, ctx, bson.M{"owner_id": userID, "project_id": nil})
+}
+
+func CreateView(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return createView(c, ctx, userID, nil)
+}
+
+// editableView loads a view the user may change: their own, or a shared
+// view in a project where they are an admin.
+func editableView(c *fiber.Ctx, ctx context.Context, userID primitive.ObjectID) (*models.SavedView, error) {
+	viewID, err := primitive.ObjectIDFromHex(c.Params("viewId"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid view ID"})
+	}
+
+	var view models.SavedView
+	if err := database.GetCollection("saved_views").FindOne(ctx, bson.M{"_id": viewID}).Decode(&view); err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "View not found"})
+	}
+
+	if view.OwnerID != userID {
+		if !view.Shared || view.ProjectID == nil {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "View not found"})
+		}
+		roleFlags, err := getProjectRole(ctx, *view.ProjectID, userID)
+		if err != nil || !hasPermission(roleFlags, RoleAdmin) {
+			return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		}
+	}
+
+	if view.ProjectID != nil {
+		if err := ensureProjectWritable(ctx, *view.ProjectID); err != nil {
+			return nil, projectWriteError(c, err)
+		}
+	}
+	return &view, nil
+}
+
+func UpdateView(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	var body savedViewBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	view, errResp := editableView(c, ctx, userID)
+	if view == nil {
+		return errResp
+	}
+
+	if status, msg := applyViewBody(ctx, userID, view, body); msg != "" {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	view.UpdatedAt = time.Now()
+
+	database.GetCollection("saved_views").ReplaceOne(ctx, bson.M{"_id": view.ID}, view)
+	if view.IsDefault {
+		clearOtherDefaults(ctx, *view)
+	}
+
+	return c.JSON(view)
+}
+
+func DeleteView(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	view, errResp := editableView(c, ctx, userID)
+	if view == nil {
+		return errResp
+	}
+
+	database.GetCollection("saved_views").DeleteOne(ctx, bson.M{"_id": view.ID})
+	if view.Shared {
+		recordActivity(ctx, *view.ProjectID, userID, "deleted", "view", view.ID, view.Name, nil)
+	}
+
+	return c.JSON(fiber.Map{"message": "View deleted"})
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Unread watch notifications about the same card are merged while they are
+// younger than this, so a burst of edits produces one notification.
+const watchCoalesceWindow = 10 * time.Minute
+
+func EnsureWatchIndexes(ctx context.Context) {
+	_, err := database.GetCollection("watches").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}},
+			Options: options.Index().SetName("one_watch_per_target").SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("EnsureWatchIndexes: %v", err)
+	}
+}
+
+func watchTarget(ctx context.Context, userID, projectID primitive.ObjectID, targetType string, targetID primitive.ObjectID, auto bool) {
+	database.GetCollection("watches").UpdateOne(ctx,
+		bson.M{"user_id": userID, "target_type": targetType, "target_id": targetID},
+		bson.M{"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"project_id": projectID,
+			"auto":       auto,
+			"created_at": time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+}
+
+// autoWatchCard subscribes the given users and the card's assignees.
+func autoWatchCard(ctx context.Context, card models.Card, userIDs ...primitive.ObjectID) {
+	for _, id := range append(userIDs, assigneeIDs(ctx, card.Assignees)...) {
+		watchTarget(ctx, id, card.ProjectID, "card", card.ID, true)
+	}
+}
+
+func assigneeIDs(ctx context.Context, emails []string) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	if len(emails) == 0 {
+		return ids
+	}
+	cursor, err := database.GetCollection("users").Find(ctx, bson.M{"email": bson.M{"$in": emails}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return ids
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	cursor.All(ctx, &users)
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+// cardWatchers returns everyone watching the card, one of the given
+// columns, or its project.
+func cardWatchers(ctx context.Context, card models.Card, columnIDs ...primitive.ObjectID) []primitive.ObjectID {
+	targets := bson.A{
+		bson.M{"target_type": "card", "target_id": card.ID},
+		bson.M{"target_type": "project", "target_id": card.ProjectID},
+	}
+	for _, id := range append(columnIDs, card.ColumnID) {
+		targets = append(targets, bson.M{"target_type": "column", "target_id": id})
+	}
+
+	cursor, err := database.GetCollection("watches").Find(ctx, bson.M{"$or": targets})
+	if err != nil {
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var watches []models.Watch
+	cursor.All(ctx, &watches)
+
+	seen := map[primitive.ObjectID]bool{}
+	ids := []primitive.ObjectID{}
+	for _, w := range watches {
+		if !seen[w.UserID] {
+			seen[w.UserID] = true
+			ids = append(ids, w.UserID)
+		}
+	}
+	return ids
+}
+
+// watchEvents describes the changes between two versions of a card that
+// watchers are told about.
+func watchEvents(ctx context.Context, before, after models.Card) []string {
+	events := []string{}
+	if before.ColumnID != after.ColumnID {
+		events = append(events, "moved to \""+columnTitle(ctx, after.ColumnID)+"\"")
+	}
+	if due := formatDueDate(after.DueDate); due != formatDueDate(before.DueDate) {
+		if due == nil {
+			events = append(events, "due date removed")
+		} else {
+			events = append(events, "due date changed to "+due.(string))
+		}
+	}
+	if before.Description != after.Description {
+		events = append(events, "description edited")
+	}
+	wasDone := map[int]bool{}
+	for _, s := range before.Subtasks {
+		if s.Done {
+			wasDone[s.ID] = true
+		}
+	}
+	for _, s := range after.Subtasks {
+		if s.Done && !wasDone[s.ID] {
+			events = append(events, "completed subtask \""+s.Text+"\"")
+		}
+	}
+	return events
+}
+
+// notifyWatchers tells the card's watchers, other than the actor, about
+// events. An unread notification about the same card from within
+// watchCoalesceWindow is extended instead of adding another.
+func notifyWatchers(ctx context.Context, card models.Card, actorID primitive.ObjectID, events []string, columnIDs ...primitive.ObjectID) {
+	if len(events) == 0 {
+		return
+	}
+	col := database.GetCollection("notifications")
+	now := time.Now()
+
+	for _, watcherID := range cardWatchers(ctx, card, columnIDs...) {
+		if watcherID == actorID {
+			continue
+		}
+		if _, err := getProjectRole(ctx, card.ProjectID, watcherID); err != nil {
+			continue
+		}
+
+		var existing models.Notification
+		err := col.FindOne(ctx, bson.M{
+			"user_id":    watcherID,
+			"type":       "watch",
+			"card_id":    card.ID,
+			"read":       false,
+			"created_at": bson.M{"$gte": now.Add(-watchCoalesceWindow)},
+		}).Decode(&existing)
+		if err != nil {
+			n := &models.Notification{
+				ID:        primitive.NewObjectID(),
+				UserID:    watcherID,
+				Type:      "watch",
+				Message:   watchMessage(card.Title, events),
+				ProjectID: card.ProjectID,
+				CardID:    card.ID,
+				Changes:   events,
+				CreatedAt: now,
+			}
+			col.InsertOne(ctx, n)
+			continue
+		}
+
+		changes := existing.Changes
+		for _, e := range events {
+			if !containsString(changes, e) {
+				changes = append(changes, e)
+			}
+		}
+		col.UpdateOne(ctx, bson.M{"_id": existing.ID}, bson.M{"$set": bson.M{
+			"message":    watchMessage(card.Title, changes),
+			"changes":    changes,
+			"created_at": now,
+		}})
+	}
+}
+
+func watchMessage(title string, changes []string) string {
+	return "\"" + title + "\" was updated: " + strings.Join(changes, "; ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveWatchTarget reads the watch target from the route and checks the
+// caller can see it.
+func resolveWatchTarget(c *fiber.Ctx, ctx context.Context, userID primitive.ObjectID) (string, primitive.ObjectID, primitive.ObjectID, error) {
+	if raw := c.Params("cardId"); raw != "" {
+		cardID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return "", cardID, cardID, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+		}
+		var card models.Card
+		if err := database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card); err != nil {
+			return "", cardID, cardID, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+		}
+		if _, err := getProjectRole(ctx, card.ProjectID, userID); err != nil {
+			return "", cardID, cardID, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+		}
+		return "card", cardID, card.ProjectID, nil
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Params("projectId"))
+	if err != nil {
+		return "", projectID, projectID, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project ID"})
+	}
+	if _, err := getProjectRole(ctx, projectID, userID); err != nil {
+		return "", projectID, projectID, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	}
+
+	if raw := c.Params("columnId"); raw != "" {
+		columnID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return "", columnID, projectID, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid column ID"})
+		}
+		if n, _ := database.GetCollection("board_columns").CountDocuments(ctx, bson.M{"_id": columnID, "project_id": projectID}); n == 0 {
+			return "", columnID, projectID, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Column not found"})
+		}
+		return "column", columnID, projectID, nil
+	}
+	return "project", projectID, projectID, nil
+}
+
+// Watch subscribes the caller to the card, column or project in the route.
+func Watch(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	targetType, targetID, projectID, errResp := resolveWatchTarget(c, ctx, userID)
+	if targetType == "" {
+		return errResp
+	}
+
+	col := database.GetCollection("watches")
+	filter := bson.M{"user_id": userID, "target_type": targetType, "target_id": targetID}
+	watchTarget(ctx, userID, projectID, targetType, targetID, false)
+	col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"auto": false}})
+
+	var watch models.Watch
+	col.FindOne(ctx, filter).Decode(&watch)
+	return c.JSON(watch)
+}
+
+func Unwatch(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	targetType, targetID, _, errResp := resolveWatchTarget(c, ctx, userID)
+	if targetType == "" {
+		return errResp
+	}
+
+	database.GetCollection("watches").DeleteOne(ctx,
+		bson.M{"user_id": userID, "target_type": targetType, "target_id": targetID})
+	return c.JSON(fiber.Map{"message": "Stopped watching"})
+}
+
+// ListCardWatchers reports who watches a card directly and whether the
+// caller receives its notifications, and through which target.
+func ListCardWatchers(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	targetType, cardID, _, errResp := resolveWatchTarget(c, ctx, userID)
+	if targetType == "" {
+		return errResp
+	}
+
+	var card models.Card
+	database.GetCollection("cards").FindOne(ctx, bson.M{"_id": cardID}).Decode(&card)
+
+	cursor, err := database.GetCollection("watches").Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"target_type": "card", "target_id": card.ID},
+		bson.M{"target_type": "column", "target_id": card.ColumnID},
+		bson.M{"target_type": "project", "target_id": card.ProjectID},
+	}})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch watchers"})
+	}
+	defer cursor.Close(ctx)
+
+	var watches []models.Watch
+	cursor.All(ctx, &watches)
+
+	type WatcherResponse struct {
+		UserID primitive.ObjectID `json:"user_id"`
+		Name   string             `json:"name"`
+		Auto   bool               `json:"auto"`
+	}
+
+	watchers := []WatcherResponse{}
+	var via interface{}
+	for _, w := range watches {
+		if w.UserID == userID && (via == nil || w.TargetType == "card") {
+			via = w.TargetType
+		}
+		if w.TargetType == "card" {
+			watchers = append(watchers, WatcherResponse{UserID: w.UserID, Name: userName(ctx, w.UserID), Auto: w.Auto})
+		}
+	}
+
+	return c.JSON(fiber.Map{"watching": via != nil, "via": via, "watchers": watchers})
+}
+
+func ListMyWatches(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Locals("user_id").(string))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := database.GetCollection("watches").Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch watches"})
+	}
+	defer cursor.Close(ctx)
+
+	var watches []models.Watch
+	cursor.All(ctx, &watches)
+
+	type WatchResponse struct {
+		models.Watch
+		TargetName string `json:"target_name"`
+	}
+
+	result := []WatchResponse{}
+	for _, w := range watches {
+		var name string
+		switch w.TargetType {
+		case "card":
+			var card models.Card
+			database.GetCollection("cards").FindOne(ctx, bson.M{"_id": w.TargetID}).Decode(&card)
+			name = card.Title
+		case "column":
+			name = columnTitle(ctx, w.TargetID)
+		case "project":
+			var project models.Project
+			database.GetCollection("projects").FindOne(ctx, bson.M{"_id": w.TargetID}).Decode(&project)
+			name = project.Name
+		}
+		result = append(result, WatchResponse{Watch: w, TargetName: name})
+	}
+
+	return c.JSON(result)
+}
@@ -75,6 +75,10 @@ func CreateWebhook(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	wType := body.Type
 	if wType == "" {
 		wType = "custom"
@@ -125,6 +129,10 @@ func UpdateWebhook(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, wh.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	var body struct {
 		Name string `json:"name"`
 		URL  string `json:"url"`
@@ -175,6 +183,10 @@ func ToggleWebhook(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, wh.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	newStatus := "active"
 	if wh.Status == "active" {
 		newStatus = "inactive"
@@ -215,6 +227,10 @@ func DeleteWebhook(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, wh.ProjectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	database.GetCollection("webhooks").DeleteOne(ctx, bson.M{"_id": webhookID})
 	return c.JSON(fiber.Map{"message": "Webhook deleted"})
 }
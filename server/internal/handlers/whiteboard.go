@@ -68,6 +68,10 @@ func SaveWhiteboard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
 
+	if err := ensureProjectWritable(ctx, projectID); err != nil {
+		return projectWriteError(c, err)
+	}
+
 	now := time.Now()
 	col := database.GetCollection("whiteboards")
 
@@ -84,6 +88,7 @@ func SaveWhiteboard(c *fiber.Ctx) error {
 			UpdatedAt: now,
 		}
 		col.InsertOne(ctx, wb)
+		recordCoalescedActivity(ctx, projectID, userID, "saved", "whiteboard", wb.ID, "Whiteboard", 15*time.Minute)
 		return c.JSON(fiber.Map{"id": wb.ID, "project_id": projectID, "updated_at": now})
 	}
 
@@ -91,6 +96,7 @@ func SaveWhiteboard(c *fiber.Ctx) error {
 		"data":       body.Data,
 		"updated_at": now,
 	}})
+	recordCoalescedActivity(ctx, projectID, userID, "saved", "whiteboard", existing.ID, "Whiteboard", 15*time.Minute)
 
 	return c.JSON(fiber.Map{"id": existing.ID, "project_id": projectID, "updated_at": now})
 }
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fpmb/server/internal/database"
+	"github.com/fpmb/server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type wipViolation struct {
+	Scope    string `json:"scope"`
+	Assignee string `json:"assignee,omitempty"`
+	Limit    int    `json:"limit"`
+	Count    int    `json:"count"`
+}
+
+func validWIPMode(mode string) bool {
+	return mode == "" || mode == "warn" || mode == "block"
+}
+
+// wipBlocks reports whether the column rejects cards over its limits rather
+// than only warning about them.
+func wipBlocks(col models.BoardColumn) bool {
+	return col.WIPMode == "block"
+}
+
+// checkWIP returns the limits of col that would be exceeded if card were
+// added to it. Count is the number of cards after the addition.
+func checkWIP(ctx context.Context, col models.BoardColumn, card models.Card) []wipViolation {
+	violations := []wipViolation{}
+	if col.WIPLimit <= 0 && col.AssigneeWIPLimit <= 0 {
+		return violations
+	}
+
+	filter := bson.M{"column_id": col.ID, "archived_at": nil}
+	if !card.ID.IsZero() {
+		filter["_id"] = bson.M{"$ne": card.ID}
+	}
+	cards := database.GetCollection("cards")
+
+	if col.WIPLimit > 0 {
+		n, _ := cards.CountDocuments(ctx, filter)
+		if int(n)+1 > col.WIPLimit {
+			violations = append(violations, wipViolation{Scope: "column", Limit: col.WIPLimit, Count: int(n) + 1})
+		}
+	}
+
+	if col.AssigneeWIPLimit > 0 {
+		for _, email := range card.Assignees {
+			filter["assignees"] = email
+			n, _ := cards.CountDocuments(ctx, filter)
+			if int(n)+1 > col.AssigneeWIPLimit {
+				violations = append(violations, wipViolation{Scope: "assignee", Assignee: email, Limit: col.AssigneeWIPLimit, Count: int(n) + 1})
+			}
+		}
+	}
+	return violations
+}
+
+// projectAdmins returns the users whose effective role on the project is
+// admin or higher.
+func projectAdmins(ctx context.Context, projectID primitive.ObjectID) []primitive.ObjectID {
+	var project models.Project
+	if err := database.GetCollection("projects").FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+		return nil
+	}
+
+	candidates := map[primitive.ObjectID]bool{project.CreatedBy: true}
+	var members []models.ProjectMember
+	if cursor, err := database.GetCollection("project_members").Find(ctx, bson.M{"project_id": projectID}); err == nil {
+		cursor.All(ctx, &members)
+		cursor.Close(ctx)
+	}
+	for _, m := range members {
+		candidates[m.UserID] = true
+	}
+	if project.TeamID != primitive.NilObjectID {
+		var teamMembers []models.TeamMember
+		if cursor, err := database.GetCollection("team_members").Find(ctx, bson.M{"team_id": project.TeamID}); err == nil {
+			cursor.All(ctx, &teamMembers)
+			cursor.Close(ctx)
+		}
+		for _, m := range teamMembers {
+			candidates[m.UserID] = true
+		}
+	}
+
+	admins := []primitive.ObjectID{}
+	for id := range candidates {
+		if role, err := getProjectRole(ctx, projectID, id); err == nil && hasPermission(role, RoleAdmin) {
+			admins = append(admins, id)
+		}
+	}
+	return admins
+}
+
+// notifyWIPExceeded tells project admins that a warn-mode limit was crossed.
+// Only the card that pushes a count just past the limit triggers a notice.
+func notifyWIPExceeded(ctx context.Context, col models.BoardColumn, card models.Card, actorID primitive.ObjectID, violations []wipViolation) {
+	var messages []string
+	for _, v := range violations {
+		if v.Count != v.Limit+1 {
+			continue
+		}
+		if v.Scope == "column" {
+			messages = append(messages, fmt.Sprintf("Column \"%s\" is over its WIP limit of %d", col.Title, v.Limit))
+		} else {
+			messages = append(messages, fmt.Sprintf("%s has more than %d cards in \"%s\"", v.Assignee, v.Limit, col.Title))
+		}
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	for _, adminID := range projectAdmins(ctx, col.ProjectID) {
+		if adminID == actorID {
+			continue
+		}
+		for _, msg := range messages {
+			createNotification(ctx, adminID, "wip_limit", msg, col.ProjectID, card.ID)
+		}
+	}
+}
+
+// columnWIPStatus reports whether a column is currently over its limits and
+// which assignees exceed the per-assignee limit.
+func columnWIPStatus(ctx context.Context, col models.BoardColumn) (bool, []string) {
+	over := false
+	assignees := []string{}
+	if col.WIPLimit > 0 {
+		n, _ := database.GetCollection("cards").CountDocuments(ctx, bson.M{"column_id": col.ID, "archived_at": nil})
+		over = int(n) > col.WIPLimit
+	}
+	if col.AssigneeWIPLimit > 0 {
+		cursor, err := database.GetCollection("cards").Aggregate(ctx, bson.A{
+			bson.M{"$match": bson.M{"column_id": col.ID, "archived_at": nil}},
+			bson.M{"$unwind": "$assignees"},
+			bson.M{"$group": bson.M{"_id": "$assignees", "count": bson.M{"$sum": 1}}},
+			bson.M{"$match": bson.M{"count": bson.M{"$gt": col.AssigneeWIPLimit}}},
+		})
+		if err == nil {
+			var rows []struct {
+				ID string `bson:"_id"`
+			}
+			cursor.All(ctx, &rows)
+			cursor.Close(ctx)
+			for _, r := range rows {
+				assignees = append(assignees, r.ID)
+			}
+		}
+		if len(assignees) > 0 {
+			over = true
+		}
+	}
+	return over, assignees
+}
@@ -44,6 +44,9 @@ type Project struct {
 	Visibility  string             `bson:"visibility"           json:"visibility"`
 	IsPublic    bool               `bson:"is_public"            json:"is_public"`
 	IsArchived  bool               `bson:"is_archived"          json:"is_archived"`
+	Key         string             `bson:"key,omitempty"        json:"key,omitempty"`
+	FormerKeys  []string           `bson:"former_keys,omitempty" json:"former_keys,omitempty"`
+	CardSeq     int                `bson:"card_seq,omitempty"   json:"-"`
 	CreatedBy   primitive.ObjectID `bson:"created_by"           json:"created_by"`
 	CreatedAt   time.Time          `bson:"created_at"           json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at"           json:"updated_at"`
@@ -57,38 +60,312 @@ type ProjectMember struct {
 	AddedAt   time.Time          `bson:"added_at"      json:"added_at"`
 }
 
+type ProjectTransferMove struct {
+	FileID primitive.ObjectID `bson:"file_id" json:"file_id"`
+	From   string             `bson:"from"    json:"from"`
+	To     string             `bson:"to"      json:"to"`
+	Done   bool               `bson:"done"    json:"done"`
+}
+
+type ProjectTransfer struct {
+	ID          primitive.ObjectID    `bson:"_id,omitempty"          json:"id"`
+	ProjectID   primitive.ObjectID    `bson:"project_id"             json:"project_id"`
+	FromTeamID  primitive.ObjectID    `bson:"from_team_id"           json:"from_team_id"`
+	ToTeamID    primitive.ObjectID    `bson:"to_team_id"             json:"to_team_id"`
+	Status      string                `bson:"status"                 json:"status"`
+	Moves       []ProjectTransferMove `bson:"moves"                  json:"moves"`
+	RequestedBy primitive.ObjectID    `bson:"requested_by"           json:"requested_by"`
+	CreatedAt   time.Time             `bson:"created_at"             json:"created_at"`
+	UpdatedAt   time.Time             `bson:"updated_at"             json:"updated_at"`
+	CompletedAt *time.Time            `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
 type BoardColumn struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"                json:"id"`
+	ProjectID        primitive.ObjectID `bson:"project_id"                   json:"project_id"`
+	Title            string             `bson:"title"                        json:"title"`
+	Rank             string             `bson:"rank"                         json:"rank"`
+	IsDone           bool               `bson:"is_done"                      json:"is_done"`
+	WIPLimit         int                `bson:"wip_limit,omitempty"          json:"wip_limit,omitempty"`
+	AssigneeWIPLimit int                `bson:"assignee_wip_limit,omitempty" json:"assignee_wip_limit,omitempty"`
+	WIPMode          string             `bson:"wip_mode,omitempty"           json:"wip_mode,omitempty"`
+	ArchivedAt       *time.Time         `bson:"archived_at,omitempty"        json:"archived_at,omitempty"`
+	Version          int64              `bson:"version"                      json:"version"`
+	CreatedAt        time.Time          `bson:"created_at"                   json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at"                   json:"updated_at"`
+}
+
+type Swimlane struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	ProjectID primitive.ObjectID `bson:"project_id"    json:"project_id"`
 	Title     string             `bson:"title"         json:"title"`
-	Position  int                `bson:"position"      json:"position"`
+	Rank      string             `bson:"rank"          json:"rank"`
 	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at"    json:"updated_at"`
 }
 
+type Label struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID   primitive.ObjectID `bson:"project_id"    json:"project_id"`
+	Name        string             `bson:"name"          json:"name"`
+	Color       string             `bson:"color"         json:"color"`
+	Description string             `bson:"description"   json:"description"`
+	CreatedBy   primitive.ObjectID `bson:"created_by"    json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at"    json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"    json:"updated_at"`
+}
+
 type Subtask struct {
+	ID          int        `bson:"id"                     json:"id"`
+	ChecklistID int        `bson:"checklist_id,omitempty" json:"checklist_id,omitempty"`
+	Text        string     `bson:"text"                   json:"text"`
+	Done        bool       `bson:"done"                   json:"done"`
+	Assignee    string     `bson:"assignee,omitempty"     json:"assignee,omitempty"`
+	DueDate     *time.Time `bson:"due_date,omitempty"     json:"due_date,omitempty"`
+}
+
+type Checklist struct {
 	ID   int    `bson:"id"   json:"id"`
-	Text string `bson:"text" json:"text"`
-	Done bool   `bson:"done" json:"done"`
+	Name string `bson:"name" json:"name"`
 }
 
 type Card struct {
-	ID               primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
-	ColumnID         primitive.ObjectID `bson:"column_id"            json:"column_id"`
-	ProjectID        primitive.ObjectID `bson:"project_id"           json:"project_id"`
-	Title            string             `bson:"title"                json:"title"`
-	Description      string             `bson:"description"          json:"description"`
-	Priority         string             `bson:"priority"             json:"priority"`
-	Color            string             `bson:"color"                json:"color"`
-	DueDate          *time.Time         `bson:"due_date,omitempty"   json:"due_date,omitempty"`
-	Assignees        []string           `bson:"assignees"            json:"assignees"`
-	EstimatedMinutes *int               `bson:"estimated_minutes,omitempty" json:"estimated_minutes,omitempty"`
-	ActualMinutes    *int               `bson:"actual_minutes,omitempty"    json:"actual_minutes,omitempty"`
-	Subtasks         []Subtask          `bson:"subtasks"             json:"subtasks"`
-	Position         int                `bson:"position"             json:"position"`
-	CreatedBy        primitive.ObjectID `bson:"created_by"           json:"created_by"`
-	CreatedAt        time.Time          `bson:"created_at"           json:"created_at"`
-	UpdatedAt        time.Time          `bson:"updated_at"           json:"updated_at"`
+	ID               primitive.ObjectID     `bson:"_id,omitempty"        json:"id"`
+	ColumnID         primitive.ObjectID     `bson:"column_id"            json:"column_id"`
+	LaneID           *primitive.ObjectID    `bson:"lane_id,omitempty"    json:"lane_id,omitempty"`
+	RecurrenceID     *primitive.ObjectID    `bson:"recurrence_id,omitempty" json:"recurrence_id,omitempty"`
+	SprintID         *primitive.ObjectID    `bson:"sprint_id,omitempty" json:"sprint_id,omitempty"`
+	ProjectID        primitive.ObjectID     `bson:"project_id"           json:"project_id"`
+	Number           int                    `bson:"number,omitempty"     json:"number,omitempty"`
+	Key              string                 `bson:"key,omitempty"        json:"key,omitempty"`
+	Title            string                 `bson:"title"                json:"title"`
+	Description      string                 `bson:"description"          json:"description"`
+	Priority         string                 `bson:"priority"             json:"priority"`
+	Color            string                 `bson:"color"                json:"color"`
+	DueDate          *time.Time             `bson:"due_date,omitempty"   json:"due_date,omitempty"`
+	Assignees        []string               `bson:"assignees"            json:"assignees"`
+	LabelIDs         []primitive.ObjectID   `bson:"label_ids"            json:"label_ids"`
+	AttachmentIDs    []primitive.ObjectID   `bson:"attachment_ids,omitempty" json:"attachment_ids,omitempty"`
+	CoverFileID      *primitive.ObjectID    `bson:"cover_file_id,omitempty"  json:"cover_file_id,omitempty"`
+	EstimatedMinutes *int                   `bson:"estimated_minutes,omitempty" json:"estimated_minutes,omitempty"`
+	ActualMinutes    *int                   `bson:"actual_minutes,omitempty"    json:"actual_minutes,omitempty"`
+	ManualMinutes    *int                   `bson:"manual_minutes,omitempty"    json:"-"`
+	MinutesTracked   bool                   `bson:"minutes_tracked,omitempty"   json:"-"`
+	Subtasks         []Subtask              `bson:"subtasks"             json:"subtasks"`
+	Checklists       []Checklist            `bson:"checklists,omitempty" json:"checklists,omitempty"`
+	SubtaskSeq       int                    `bson:"subtask_seq,omitempty" json:"-"`
+	CustomFields     map[string]interface{} `bson:"custom_fields,omitempty" json:"custom_fields,omitempty"`
+	Rank             string                 `bson:"rank"                 json:"rank"`
+	ArchivedAt       *time.Time             `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	ColumnArchived   bool                   `bson:"column_archived,omitempty" json:"column_archived,omitempty"`
+	Version          int64                  `bson:"version"              json:"version"`
+	CreatedBy        primitive.ObjectID     `bson:"created_by"           json:"created_by"`
+	CreatedAt        time.Time              `bson:"created_at"           json:"created_at"`
+	UpdatedAt        time.Time              `bson:"updated_at"           json:"updated_at"`
+}
+
+type CardComment struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty"       json:"id"`
+	CardID    primitive.ObjectID   `bson:"card_id"             json:"card_id"`
+	ProjectID primitive.ObjectID   `bson:"project_id"          json:"project_id"`
+	UserID    primitive.ObjectID   `bson:"user_id"             json:"user_id"`
+	Content   string               `bson:"content"             json:"content"`
+	Mentions  []primitive.ObjectID `bson:"mentions"            json:"mentions"`
+	EditedAt  *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	CreatedAt time.Time            `bson:"created_at"          json:"created_at"`
+}
+
+type RecurrenceTemplate struct {
+	Title            string               `bson:"title"                       json:"title"`
+	Description      string               `bson:"description"                 json:"description"`
+	Priority         string               `bson:"priority"                    json:"priority"`
+	Color            string               `bson:"color"                       json:"color"`
+	Assignees        []string             `bson:"assignees"                   json:"assignees"`
+	LabelIDs         []primitive.ObjectID `bson:"label_ids"                   json:"label_ids"`
+	Subtasks         []string             `bson:"subtasks"                    json:"subtasks"`
+	EstimatedMinutes *int                 `bson:"estimated_minutes,omitempty" json:"estimated_minutes,omitempty"`
+}
+
+type Recurrence struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty"             json:"id"`
+	ProjectID     primitive.ObjectID  `bson:"project_id"                json:"project_id"`
+	ColumnID      primitive.ObjectID  `bson:"column_id"                 json:"column_id"`
+	LaneID        *primitive.ObjectID `bson:"lane_id,omitempty"         json:"lane_id,omitempty"`
+	Rule          string              `bson:"rule"                      json:"rule"`
+	StartAt       time.Time           `bson:"start_at"                  json:"start_at"`
+	DueOffsetDays *int                `bson:"due_offset_days,omitempty" json:"due_offset_days,omitempty"`
+	Template      RecurrenceTemplate  `bson:"template"                  json:"template"`
+	Paused        bool                `bson:"paused"                    json:"paused"`
+	NextRunAt     *time.Time          `bson:"next_run_at,omitempty"     json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time          `bson:"last_run_at,omitempty"     json:"last_run_at,omitempty"`
+	LastCardID    *primitive.ObjectID `bson:"last_card_id,omitempty"    json:"last_card_id,omitempty"`
+	Generated     int                 `bson:"generated"                 json:"generated"`
+	CreatedBy     primitive.ObjectID  `bson:"created_by"                json:"created_by"`
+	CreatedAt     time.Time           `bson:"created_at"                json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at"                json:"updated_at"`
+}
+
+type Automation struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	ProjectID  primitive.ObjectID `bson:"project_id"            json:"project_id"`
+	Name       string             `bson:"name"                  json:"name"`
+	Enabled    bool               `bson:"enabled"               json:"enabled"`
+	Trigger    AutomationTrigger  `bson:"trigger"               json:"trigger"`
+	Conditions string             `bson:"conditions"            json:"conditions"`
+	Actions    []AutomationAction `bson:"actions"               json:"actions"`
+	StartAt    *time.Time         `bson:"start_at,omitempty"    json:"start_at,omitempty"`
+	NextRunAt  *time.Time         `bson:"next_run_at,omitempty" json:"next_run_at,omitempty"`
+	CheckedAt  *time.Time         `bson:"checked_at,omitempty"  json:"-"`
+	CreatedBy  primitive.ObjectID `bson:"created_by"            json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at"            json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at"            json:"updated_at"`
+}
+
+type AutomationTrigger struct {
+	Type     string              `bson:"type"                json:"type"`
+	ColumnID *primitive.ObjectID `bson:"column_id,omitempty" json:"column_id,omitempty"`
+	Field    string              `bson:"field,omitempty"     json:"field,omitempty"`
+	Rule     string              `bson:"rule,omitempty"      json:"rule,omitempty"`
+}
+
+type AutomationAction struct {
+	Type     string              `bson:"type"                json:"type"`
+	ColumnID *primitive.ObjectID `bson:"column_id,omitempty" json:"column_id,omitempty"`
+	LabelID  *primitive.ObjectID `bson:"label_id,omitempty"  json:"label_id,omitempty"`
+	Value    string              `bson:"value,omitempty"     json:"value,omitempty"`
+	Target   string              `bson:"target,omitempty"    json:"target,omitempty"`
+	Message  string              `bson:"message,omitempty"   json:"message,omitempty"`
+}
+
+type AutomationRun struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty"     json:"id"`
+	AutomationID primitive.ObjectID  `bson:"automation_id"     json:"automation_id"`
+	ProjectID    primitive.ObjectID  `bson:"project_id"        json:"project_id"`
+	CardID       *primitive.ObjectID `bson:"card_id,omitempty" json:"card_id,omitempty"`
+	Trigger      string              `bson:"trigger"           json:"trigger"`
+	Status       string              `bson:"status"            json:"status"`
+	Actions      []string            `bson:"actions"           json:"actions"`
+	Error        string              `bson:"error,omitempty"   json:"error,omitempty"`
+	Depth        int                 `bson:"depth"             json:"depth"`
+	CreatedAt    time.Time           `bson:"created_at"        json:"created_at"`
+}
+
+type Sprint struct {
+	ID               primitive.ObjectID   `bson:"_id,omitempty"                json:"id"`
+	ProjectID        primitive.ObjectID   `bson:"project_id"                   json:"project_id"`
+	Name             string               `bson:"name"                         json:"name"`
+	Goal             string               `bson:"goal"                         json:"goal"`
+	StartDate        *time.Time           `bson:"start_date,omitempty"         json:"start_date,omitempty"`
+	EndDate          *time.Time           `bson:"end_date,omitempty"           json:"end_date,omitempty"`
+	State            string               `bson:"state"                        json:"state"`
+	CommittedCardIDs []primitive.ObjectID `bson:"committed_card_ids,omitempty" json:"committed_card_ids,omitempty"`
+	CompletedCardIDs []primitive.ObjectID `bson:"completed_card_ids,omitempty" json:"completed_card_ids,omitempty"`
+	CommittedTotals  map[string]float64   `bson:"committed_totals,omitempty"   json:"-"`
+	CompletedTotals  map[string]float64   `bson:"completed_totals,omitempty"   json:"-"`
+	RolledOver       int                  `bson:"rolled_over"                  json:"rolled_over"`
+	StartedAt        *time.Time           `bson:"started_at,omitempty"         json:"started_at,omitempty"`
+	CompletedAt      *time.Time           `bson:"completed_at,omitempty"       json:"completed_at,omitempty"`
+	CreatedBy        primitive.ObjectID   `bson:"created_by"                   json:"created_by"`
+	CreatedAt        time.Time            `bson:"created_at"                   json:"created_at"`
+	UpdatedAt        time.Time            `bson:"updated_at"                   json:"updated_at"`
+}
+
+type TimeEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"      json:"id"`
+	ProjectID primitive.ObjectID `bson:"project_id"         json:"project_id"`
+	CardID    primitive.ObjectID `bson:"card_id"            json:"card_id"`
+	UserID    primitive.ObjectID `bson:"user_id"            json:"user_id"`
+	StartedAt time.Time          `bson:"started_at"         json:"started_at"`
+	EndedAt   *time.Time         `bson:"ended_at,omitempty" json:"ended_at,omitempty"`
+	Minutes   int                `bson:"minutes"            json:"minutes"`
+	Running   bool               `bson:"running"            json:"running"`
+	Note      string             `bson:"note"               json:"note"`
+	CreatedAt time.Time          `bson:"created_at"         json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"         json:"updated_at"`
+}
+
+// Watch subscribes a user to changes on a card, every card in a column, or
+// every card in a project. Auto watches come from creating, being assigned
+// to or commenting on a card.
+type Watch struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id"       json:"user_id"`
+	ProjectID  primitive.ObjectID `bson:"project_id"    json:"project_id"`
+	TargetType string             `bson:"target_type"   json:"target_type"`
+	TargetID   primitive.ObjectID `bson:"target_id"     json:"target_id"`
+	Auto       bool               `bson:"auto"          json:"auto"`
+	CreatedAt  time.Time          `bson:"created_at"    json:"created_at"`
+}
+
+type CustomField struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	ProjectID primitive.ObjectID `bson:"project_id"        json:"project_id"`
+	Name      string             `bson:"name"              json:"name"`
+	Key       string             `bson:"key"               json:"key"`
+	Type      string             `bson:"type"              json:"type"`
+	Options   []string           `bson:"options,omitempty" json:"options,omitempty"`
+	Required  bool               `bson:"required"          json:"required"`
+	CreatedBy primitive.ObjectID `bson:"created_by"        json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at"        json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"        json:"updated_at"`
+}
+
+type SavedView struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"        json:"id"`
+	OwnerID   primitive.ObjectID  `bson:"owner_id"             json:"owner_id"`
+	ProjectID *primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"`
+	Name      string              `bson:"name"                 json:"name"`
+	Query     string              `bson:"query"                json:"query"`
+	GroupBy   string              `bson:"group_by,omitempty"   json:"group_by,omitempty"`
+	Sort      string              `bson:"sort,omitempty"       json:"sort,omitempty"`
+	Shared    bool                `bson:"shared"               json:"shared"`
+	IsDefault bool                `bson:"is_default"           json:"is_default"`
+	CreatedAt time.Time           `bson:"created_at"           json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at"           json:"updated_at"`
+}
+
+type CardLink struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	Type            string             `bson:"type"              json:"type"`
+	SourceCardID    primitive.ObjectID `bson:"source_card_id"    json:"source_card_id"`
+	TargetCardID    primitive.ObjectID `bson:"target_card_id"    json:"target_card_id"`
+	SourceProjectID primitive.ObjectID `bson:"source_project_id" json:"source_project_id"`
+	TargetProjectID primitive.ObjectID `bson:"target_project_id" json:"target_project_id"`
+	CreatedBy       primitive.ObjectID `bson:"created_by"        json:"created_by"`
+	CreatedAt       time.Time          `bson:"created_at"        json:"created_at"`
+}
+
+type CardTransition struct {
+	ID               primitive.ObjectID  `bson:"_id,omitempty"               json:"id"`
+	ProjectID        primitive.ObjectID  `bson:"project_id"                  json:"project_id"`
+	CardID           primitive.ObjectID  `bson:"card_id"                     json:"card_id"`
+	Type             string              `bson:"type"                        json:"type"`
+	FromColumnID     *primitive.ObjectID `bson:"from_column_id,omitempty"    json:"from_column_id,omitempty"`
+	ToColumnID       *primitive.ObjectID `bson:"to_column_id,omitempty"      json:"to_column_id,omitempty"`
+	FromDone         bool                `bson:"from_done"                   json:"from_done"`
+	ToDone           bool                `bson:"to_done"                     json:"to_done"`
+	EstimatedMinutes *int                `bson:"estimated_minutes,omitempty" json:"estimated_minutes,omitempty"`
+	ActualMinutes    *int                `bson:"actual_minutes,omitempty"    json:"actual_minutes,omitempty"`
+	UserID           primitive.ObjectID  `bson:"user_id"                     json:"user_id"`
+	CreatedAt        time.Time           `bson:"created_at"                  json:"created_at"`
+}
+
+type ActivityChange struct {
+	Field string      `bson:"field"          json:"field"`
+	From  interface{} `bson:"from,omitempty" json:"from,omitempty"`
+	To    interface{} `bson:"to,omitempty"   json:"to,omitempty"`
+}
+
+type Activity struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	ProjectID  primitive.ObjectID `bson:"project_id"        json:"project_id"`
+	ActorID    primitive.ObjectID `bson:"actor_id"          json:"actor_id"`
+	Verb       string             `bson:"verb"              json:"verb"`
+	ObjectType string             `bson:"object_type"       json:"object_type"`
+	ObjectID   primitive.ObjectID `bson:"object_id"         json:"object_id"`
+	ObjectName string             `bson:"object_name"       json:"object_name"`
+	Changes    []ActivityChange   `bson:"changes,omitempty" json:"changes,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at"        json:"created_at"`
 }
 
 type Event struct {
@@ -112,6 +389,7 @@ type Notification struct {
 	Message   string             `bson:"message"              json:"message"`
 	ProjectID primitive.ObjectID `bson:"project_id"           json:"project_id"`
 	CardID    primitive.ObjectID `bson:"card_id,omitempty"    json:"card_id,omitempty"`
+	Changes   []string           `bson:"changes,omitempty"    json:"changes,omitempty"`
 	Read      bool               `bson:"read"                 json:"read"`
 	CreatedAt time.Time          `bson:"created_at"           json:"created_at"`
 }
@@ -121,6 +399,7 @@ type Doc struct {
 	TeamID    primitive.ObjectID `bson:"team_id"       json:"team_id"`
 	Title     string             `bson:"title"         json:"title"`
 	Content   string             `bson:"content"       json:"content"`
+	Version   int64              `bson:"version"       json:"version"`
 	CreatedBy primitive.ObjectID `bson:"created_by"    json:"created_by"`
 	CreatedAt time.Time          `bson:"created_at"    json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at"    json:"updated_at"`
@@ -43,6 +43,22 @@ async function refreshAccessToken(): Promise<string | null> {
 	return data.access_token;
 }
 
+/** Thrown on 412: the document changed since `version` was read. */
+export class VersionConflictError<T = unknown> extends Error {
+	constructor(
+		message: string,
+		public version: number,
+		public current: T
+	) {
+		super(message);
+	}
+}
+
+/** If-Match header for a write that must not clobber newer changes. */
+export function ifMatch(version?: number): Record<string, string> {
+	return version === undefined ? {} : { 'If-Match': `"${version}"` };
+}
+
 export async function apiFetch<T>(
 	path: string,
 	options: RequestInit = {},
@@ -65,6 +81,9 @@ export async function apiFetch<T>(
 
 	if (!res.ok) {
 		const body = await res.json().catch(() => ({}));
+		if (res.status === 412) {
+			throw new VersionConflictError(body.error || 'Version mismatch', body.version, body.current);
+		}
 		throw new Error(body.error || `HTTP ${res.status}`);
 	}
 
@@ -1,4 +1,4 @@
-import { apiFetch, apiFetchFormData } from './client';
+import { apiFetch, apiFetchFormData, ifMatch } from './client';
 import type {
 	AuthResponse,
 	User,
@@ -7,10 +7,40 @@ import type {
 	Project,
 	ProjectMember,
 	BoardData,
+	BoardQueryOptions,
 	Column,
 	Card,
+	CardComment,
+	Checklist,
+	Subtask,
+	CardLinkSummary,
+	Label,
+	Sprint,
+	SprintProgress,
+	SprintVelocity,
+	TimeEntry,
+	Timesheet,
+	BulkCardRequest,
+	BulkCardResponse,
+	ArchivedItems,
+	CustomField,
+	CustomFieldValue,
+	Swimlane,
+	SavedView,
+	SavedViewInput,
+	CardSearchResult,
+	SearchResponse,
+	SearchType,
+	RecurrenceWithUpcoming,
+	RecurrenceInput,
+	Automation,
+	AutomationInput,
+	AutomationRun,
+	AutomationTestResult,
 	Event,
 	Notification,
+	Watch,
+	CardWatchers,
 	Doc,
 	FileItem,
 	Webhook,
@@ -73,7 +103,11 @@ export const users = {
 		const fd = new FormData();
 		fd.append('file', file);
 		return apiFetchFormData<User>('/users/me/avatar', fd);
-	}
+	},
+
+	getTimer: () => apiFetch<{ entry: TimeEntry | null; card_title?: string }>('/users/me/timer'),
+
+	stopTimer: () => apiFetch<TimeEntry>('/users/me/timer/stop', { method: 'PUT' })
 };
 
 export const teams = {
@@ -108,10 +142,10 @@ export const teams = {
 
 	listProjects: (teamId: string) => apiFetch<Project[]>(`/teams/${teamId}/projects`),
 
-	createProject: (teamId: string, name: string, description: string) =>
+	createProject: (teamId: string, name: string, description: string, key?: string) =>
 		apiFetch<Project>(`/teams/${teamId}/projects`, {
 			method: 'POST',
-			body: JSON.stringify({ name, description })
+			body: JSON.stringify({ name, description, key })
 		}),
 
 	listEvents: (teamId: string) => apiFetch<Event[]>(`/teams/${teamId}/events`),
@@ -167,22 +201,25 @@ export const teams = {
 };
 
 export const projects = {
-	list: () => apiFetch<Project[]>('/projects'),
+	list: () => apiFetch<Project[]>('/projects?include_archived=true'),
 
-	createPersonal: (name: string, description: string) =>
+	createPersonal: (name: string, description: string, key?: string) =>
 		apiFetch<Project>('/projects', {
 			method: 'POST',
-			body: JSON.stringify({ name, description })
+			body: JSON.stringify({ name, description, key })
 		}),
 
 	get: (projectId: string) => apiFetch<Project>(`/projects/${projectId}`),
 
-	update: (projectId: string, data: Partial<Pick<Project, 'name' | 'description' | 'visibility'>>) =>
+	update: (projectId: string, data: Partial<Pick<Project, 'name' | 'description' | 'visibility' | 'key'>>) =>
 		apiFetch<Project>(`/projects/${projectId}`, { method: 'PUT', body: JSON.stringify(data) }),
 
 	archive: (projectId: string) =>
 		apiFetch<Project>(`/projects/${projectId}/archive`, { method: 'PUT' }),
 
+	unarchive: (projectId: string) =>
+		apiFetch<Project>(`/projects/${projectId}/unarchive`, { method: 'PUT' }),
+
 	delete: (projectId: string) => apiFetch<void>(`/projects/${projectId}`, { method: 'DELETE' }),
 
 	listMembers: (projectId: string) => apiFetch<ProjectMember[]>(`/projects/${projectId}/members`),
@@ -250,7 +287,192 @@ export const projects = {
 };
 
 export const board = {
-	get: (projectId: string) => apiFetch<BoardData>(`/projects/${projectId}/board`),
+	get: (projectId: string, opts: BoardQueryOptions = {}) => {
+		const params = new URLSearchParams();
+		if (opts.q) params.set('q', opts.q);
+		if (opts.view) params.set('view', opts.view);
+		if (opts.sort) params.set('sort', opts.sort);
+		if (opts.labelIds?.length) params.set('labels', opts.labelIds.join(','));
+		if (opts.groupBy) params.set('group_by', opts.groupBy);
+		const query = params.toString();
+		return apiFetch<BoardData>(`/projects/${projectId}/board${query ? `?${query}` : ''}`);
+	},
+
+	listLanes: (projectId: string) => apiFetch<Swimlane[]>(`/projects/${projectId}/lanes`),
+
+	createLane: (projectId: string, title: string) =>
+		apiFetch<Swimlane>(`/projects/${projectId}/lanes`, {
+			method: 'POST',
+			body: JSON.stringify({ title })
+		}),
+
+	updateLane: (projectId: string, laneId: string, data: { title?: string; position?: number }) =>
+		apiFetch<Swimlane>(`/projects/${projectId}/lanes/${laneId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	deleteLane: (projectId: string, laneId: string) =>
+		apiFetch<void>(`/projects/${projectId}/lanes/${laneId}`, { method: 'DELETE' }),
+
+	listSprints: (projectId: string, state?: Sprint['state']) =>
+		apiFetch<SprintProgress[]>(`/projects/${projectId}/sprints${state ? `?state=${state}` : ''}`),
+
+	getSprint: (projectId: string, sprintId: string) =>
+		apiFetch<{ sprint: SprintProgress; cards: Card[] }>(`/projects/${projectId}/sprints/${sprintId}`),
+
+	createSprint: (projectId: string, data: Pick<Sprint, 'name'> & Partial<Pick<Sprint, 'goal' | 'start_date' | 'end_date'>>) =>
+		apiFetch<Sprint>(`/projects/${projectId}/sprints`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	updateSprint: (projectId: string, sprintId: string, data: Partial<Pick<Sprint, 'name' | 'goal' | 'start_date' | 'end_date'>>) =>
+		apiFetch<Sprint>(`/projects/${projectId}/sprints/${sprintId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	deleteSprint: (projectId: string, sprintId: string) =>
+		apiFetch<void>(`/projects/${projectId}/sprints/${sprintId}`, { method: 'DELETE' }),
+
+	startSprint: (projectId: string, sprintId: string) =>
+		apiFetch<Sprint>(`/projects/${projectId}/sprints/${sprintId}/start`, { method: 'PUT' }),
+
+	completeSprint: (projectId: string, sprintId: string, opts: { next_sprint_id?: string; to_backlog?: boolean } = {}) =>
+		apiFetch<{ sprint: Sprint; next_sprint: Sprint | null }>(`/projects/${projectId}/sprints/${sprintId}/complete`, {
+			method: 'PUT',
+			body: JSON.stringify(opts)
+		}),
+
+	sprintVelocity: (projectId: string, field?: string) =>
+		apiFetch<SprintVelocity>(`/projects/${projectId}/sprints/velocity${field ? `?field=${encodeURIComponent(field)}` : ''}`),
+
+	backlog: (projectId: string, opts: { q?: string; sort?: string; includeDone?: boolean } = {}) => {
+		const params = new URLSearchParams();
+		if (opts.q) params.set('q', opts.q);
+		if (opts.sort) params.set('sort', opts.sort);
+		if (opts.includeDone) params.set('include_done', 'true');
+		const query = params.toString();
+		return apiFetch<Card[]>(`/projects/${projectId}/backlog${query ? `?${query}` : ''}`);
+	},
+
+	listFields: (projectId: string) => apiFetch<CustomField[]>(`/projects/${projectId}/fields`),
+
+	createField: (projectId: string, data: Pick<CustomField, 'name' | 'type'> & Partial<Pick<CustomField, 'key' | 'options' | 'required'>>) =>
+		apiFetch<CustomField>(`/projects/${projectId}/fields`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	updateField: (projectId: string, fieldId: string, data: Partial<Pick<CustomField, 'name' | 'key' | 'options' | 'required'>>) =>
+		apiFetch<CustomField>(`/projects/${projectId}/fields/${fieldId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	deleteField: (projectId: string, fieldId: string) =>
+		apiFetch<void>(`/projects/${projectId}/fields/${fieldId}`, { method: 'DELETE' }),
+
+	listViews: (projectId: string) => apiFetch<SavedView[]>(`/projects/${projectId}/views`),
+
+	createView: (projectId: string, data: SavedViewInput) =>
+		apiFetch<SavedView>(`/projects/${projectId}/views`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	listRecurrences: (projectId: string) =>
+		apiFetch<RecurrenceWithUpcoming[]>(`/projects/${projectId}/recurrences`),
+
+	createRecurrence: (projectId: string, data: RecurrenceInput) =>
+		apiFetch<RecurrenceWithUpcoming>(`/projects/${projectId}/recurrences`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	updateRecurrence: (projectId: string, recurrenceId: string, data: RecurrenceInput) =>
+		apiFetch<RecurrenceWithUpcoming>(`/projects/${projectId}/recurrences/${recurrenceId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	pauseRecurrence: (projectId: string, recurrenceId: string) =>
+		apiFetch<RecurrenceWithUpcoming>(`/projects/${projectId}/recurrences/${recurrenceId}/pause`, {
+			method: 'PUT'
+		}),
+
+	resumeRecurrence: (projectId: string, recurrenceId: string) =>
+		apiFetch<RecurrenceWithUpcoming>(`/projects/${projectId}/recurrences/${recurrenceId}/resume`, {
+			method: 'PUT'
+		}),
+
+	deleteRecurrence: (projectId: string, recurrenceId: string) =>
+		apiFetch<void>(`/projects/${projectId}/recurrences/${recurrenceId}`, { method: 'DELETE' }),
+
+	listAutomations: (projectId: string) =>
+		apiFetch<Automation[]>(`/projects/${projectId}/automations`),
+
+	createAutomation: (projectId: string, data: AutomationInput) =>
+		apiFetch<Automation>(`/projects/${projectId}/automations`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	updateAutomation: (projectId: string, automationId: string, data: AutomationInput) =>
+		apiFetch<Automation>(`/projects/${projectId}/automations/${automationId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	deleteAutomation: (projectId: string, automationId: string) =>
+		apiFetch<void>(`/projects/${projectId}/automations/${automationId}`, { method: 'DELETE' }),
+
+	listAutomationRuns: (
+		projectId: string,
+		params: { automation_id?: string; card_id?: string; status?: string; limit?: number } = {}
+	) => {
+		const search = new URLSearchParams();
+		if (params.automation_id) search.set('automation_id', params.automation_id);
+		if (params.card_id) search.set('card_id', params.card_id);
+		if (params.status) search.set('status', params.status);
+		if (params.limit) search.set('limit', String(params.limit));
+		const query = search.toString();
+		return apiFetch<AutomationRun[]>(
+			`/projects/${projectId}/automations/runs${query ? `?${query}` : ''}`
+		);
+	},
+
+	testAutomation: (
+		projectId: string,
+		cardId: string,
+		rule: AutomationInput & { automation_id?: string }
+	) =>
+		apiFetch<AutomationTestResult>(`/projects/${projectId}/automations/test`, {
+			method: 'POST',
+			body: JSON.stringify({ ...rule, card_id: cardId })
+		}),
+
+	listLabels: (projectId: string) => apiFetch<Label[]>(`/projects/${projectId}/labels`),
+
+	createLabel: (projectId: string, data: Pick<Label, 'name' | 'color' | 'description'>) =>
+		apiFetch<Label>(`/projects/${projectId}/labels`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	updateLabel: (
+		projectId: string,
+		labelId: string,
+		data: Partial<Pick<Label, 'name' | 'color' | 'description'>>
+	) =>
+		apiFetch<Label>(`/projects/${projectId}/labels/${labelId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	deleteLabel: (projectId: string, labelId: string) =>
+		apiFetch<void>(`/projects/${projectId}/labels/${labelId}`, { method: 'DELETE' }),
 
 	createColumn: (projectId: string, title: string) =>
 		apiFetch<Column>(`/projects/${projectId}/columns`, {
@@ -258,45 +480,245 @@ export const board = {
 			body: JSON.stringify({ title })
 		}),
 
-	updateColumn: (projectId: string, columnId: string, title: string) =>
+	updateColumn: (projectId: string, columnId: string, title: string, version?: number) =>
 		apiFetch<Column>(`/projects/${projectId}/columns/${columnId}`, {
 			method: 'PUT',
+			headers: ifMatch(version),
 			body: JSON.stringify({ title })
 		}),
 
-	reorderColumn: (projectId: string, columnId: string, position: number) =>
-		apiFetch<{ id: string; position: number }>(
+	reorderColumn: (projectId: string, columnId: string, position: number, version?: number) =>
+		apiFetch<{ id: string; rank: string; version: number }>(
 			`/projects/${projectId}/columns/${columnId}/position`,
-			{ method: 'PUT', body: JSON.stringify({ position }) }
+			{ method: 'PUT', headers: ifMatch(version), body: JSON.stringify({ position }) }
 		),
 
-	deleteColumn: (projectId: string, columnId: string) =>
-		apiFetch<void>(`/projects/${projectId}/columns/${columnId}`, { method: 'DELETE' }),
+	deleteColumn: (projectId: string, columnId: string, moveTo?: string, version?: number) =>
+		apiFetch<void>(
+			`/projects/${projectId}/columns/${columnId}${moveTo ? `?move_to=${moveTo}` : ''}`,
+			{ method: 'DELETE', headers: ifMatch(version) }
+		),
+
+	archiveColumn: (projectId: string, columnId: string) =>
+		apiFetch<Column>(`/projects/${projectId}/columns/${columnId}/archive`, { method: 'PUT' }),
+
+	restoreColumn: (projectId: string, columnId: string) =>
+		apiFetch<Column>(`/projects/${projectId}/columns/${columnId}/restore`, { method: 'PUT' }),
+
+	listArchived: (projectId: string) => apiFetch<ArchivedItems>(`/projects/${projectId}/archived`),
 
 	createCard: (
 		projectId: string,
 		columnId: string,
-		data: Pick<Card, 'title' | 'description' | 'priority' | 'color' | 'due_date' | 'assignees' | 'estimated_minutes' | 'actual_minutes'>
+		data: Pick<Card, 'title' | 'description' | 'priority' | 'color' | 'due_date' | 'assignees' | 'label_ids' | 'estimated_minutes' | 'actual_minutes'> & {
+			sprint_id?: string;
+			custom_fields?: Record<string, CustomFieldValue>;
+		}
 	) =>
 		apiFetch<Card>(`/projects/${projectId}/columns/${columnId}/cards`, {
 			method: 'POST',
 			body: JSON.stringify(data)
+		}),
+
+	bulkCards: (projectId: string, data: BulkCardRequest) =>
+		apiFetch<BulkCardResponse>(`/projects/${projectId}/cards/bulk`, {
+			method: 'POST',
+			body: JSON.stringify(data)
 		})
 };
 
 export const cards = {
+	search: (q: string, opts: { view?: string; limit?: number; offset?: number; includeArchived?: boolean } = {}) => {
+		const params = new URLSearchParams({ q });
+		if (opts.view) params.set('view', opts.view);
+		if (opts.limit) params.set('limit', String(opts.limit));
+		if (opts.offset) params.set('offset', String(opts.offset));
+		if (opts.includeArchived) params.set('include_archived', 'true');
+		return apiFetch<CardSearchResult>(`/cards/search?${params}`);
+	},
+
+	getByKey: (key: string) => apiFetch<Card>(`/cards/by-key/${encodeURIComponent(key)}`),
+
 	update: (
 		cardId: string,
-		data: Partial<Pick<Card, 'title' | 'description' | 'priority' | 'color' | 'due_date' | 'assignees' | 'subtasks' | 'estimated_minutes' | 'actual_minutes'>>
-	) => apiFetch<Card>(`/cards/${cardId}`, { method: 'PUT', body: JSON.stringify(data) }),
+		data: Partial<Pick<Card, 'title' | 'description' | 'priority' | 'color' | 'due_date' | 'assignees' | 'label_ids' | 'sprint_id' | 'subtasks' | 'estimated_minutes' | 'actual_minutes'>> & {
+			custom_fields?: Record<string, CustomFieldValue | null>;
+		},
+		version?: number
+	) =>
+		apiFetch<Card>(`/cards/${cardId}`, {
+			method: 'PUT',
+			headers: ifMatch(version),
+			body: JSON.stringify(data)
+		}),
 
-	move: (cardId: string, column_id: string, position: number) =>
+	move: (cardId: string, column_id: string, position: number, force = false, version?: number) =>
 		apiFetch<Card>(`/cards/${cardId}/move`, {
 			method: 'PUT',
-			body: JSON.stringify({ column_id, position })
+			headers: ifMatch(version),
+			body: JSON.stringify({ column_id, position, force })
+		}),
+
+	delete: (cardId: string, version?: number, permanent = false) =>
+		apiFetch<void>(`/cards/${cardId}${permanent ? '?permanent=true' : ''}`, {
+			method: 'DELETE',
+			headers: ifMatch(version)
+		}),
+
+	archive: (cardId: string) => apiFetch<void>(`/cards/${cardId}/archive`, { method: 'PUT' }),
+
+	restore: (cardId: string, column_id?: string) =>
+		apiFetch<Card>(`/cards/${cardId}/restore`, {
+			method: 'PUT',
+			body: JSON.stringify({ column_id })
+		}),
+
+	addSubtask: (
+		cardId: string,
+		data: Pick<Subtask, 'text'> & Partial<Pick<Subtask, 'checklist_id' | 'assignee' | 'due_date'>>
+	) =>
+		apiFetch<{ subtask: Subtask; card: Card }>(`/cards/${cardId}/subtasks`, {
+			method: 'POST',
+			body: JSON.stringify(data)
+		}),
+
+	updateSubtask: (
+		cardId: string,
+		subtaskId: number,
+		data: Partial<Pick<Subtask, 'text' | 'done' | 'assignee' | 'due_date'>>,
+		version?: number
+	) =>
+		apiFetch<Card>(`/cards/${cardId}/subtasks/${subtaskId}`, {
+			method: 'PUT',
+			headers: ifMatch(version),
+			body: JSON.stringify(data)
+		}),
+
+	moveSubtask: (cardId: string, subtaskId: number, position: number, checklist_id?: number) =>
+		apiFetch<Card>(`/cards/${cardId}/subtasks/${subtaskId}/position`, {
+			method: 'PUT',
+			body: JSON.stringify({ position, checklist_id })
+		}),
+
+	deleteSubtask: (cardId: string, subtaskId: number, version?: number) =>
+		apiFetch<Card>(`/cards/${cardId}/subtasks/${subtaskId}`, {
+			method: 'DELETE',
+			headers: ifMatch(version)
+		}),
+
+	convertSubtask: (cardId: string, subtaskId: number, column_id?: string, force = false) =>
+		apiFetch<{ card: Card; source: Card }>(`/cards/${cardId}/subtasks/${subtaskId}/convert`, {
+			method: 'POST',
+			body: JSON.stringify({ column_id, force })
+		}),
+
+	addChecklist: (cardId: string, name: string) =>
+		apiFetch<{ checklist: Checklist; card: Card }>(`/cards/${cardId}/checklists`, {
+			method: 'POST',
+			body: JSON.stringify({ name })
+		}),
+
+	renameChecklist: (cardId: string, checklistId: number, name: string) =>
+		apiFetch<Card>(`/cards/${cardId}/checklists/${checklistId}`, {
+			method: 'PUT',
+			body: JSON.stringify({ name })
+		}),
+
+	deleteChecklist: (cardId: string, checklistId: number) =>
+		apiFetch<Card>(`/cards/${cardId}/checklists/${checklistId}`, { method: 'DELETE' }),
+
+	listComments: (cardId: string) => apiFetch<CardComment[]>(`/cards/${cardId}/comments`),
+
+	addComment: (cardId: string, content: string) =>
+		apiFetch<CardComment>(`/cards/${cardId}/comments`, {
+			method: 'POST',
+			body: JSON.stringify({ content })
+		}),
+
+	updateComment: (cardId: string, commentId: string, content: string) =>
+		apiFetch<CardComment>(`/cards/${cardId}/comments/${commentId}`, {
+			method: 'PUT',
+			body: JSON.stringify({ content })
+		}),
+
+	deleteComment: (cardId: string, commentId: string) =>
+		apiFetch<void>(`/cards/${cardId}/comments/${commentId}`, { method: 'DELETE' }),
+
+	listLinks: (cardId: string) => apiFetch<CardLinkSummary[]>(`/cards/${cardId}/links`),
+
+	addLink: (cardId: string, target_card_id: string, type: CardLinkSummary['type']) =>
+		apiFetch<Card>(`/cards/${cardId}/links`, {
+			method: 'POST',
+			body: JSON.stringify({ target_card_id, type })
+		}),
+
+	removeLink: (cardId: string, linkId: string) =>
+		apiFetch<void>(`/cards/${cardId}/links/${linkId}`, { method: 'DELETE' }),
+
+	listAttachments: (cardId: string) =>
+		apiFetch<(FileItem & { is_image: boolean; is_cover: boolean })[]>(
+			`/cards/${cardId}/attachments`
+		),
+
+	uploadAttachment: (cardId: string, file: File) => {
+		const fd = new FormData();
+		fd.append('file', file);
+		return apiFetchFormData<FileItem>(`/cards/${cardId}/attachments`, fd);
+	},
+
+	linkAttachment: (cardId: string, file_id: string) =>
+		apiFetch<FileItem>(`/cards/${cardId}/attachments/link`, {
+			method: 'POST',
+			body: JSON.stringify({ file_id })
+		}),
+
+	unlinkAttachment: (cardId: string, fileId: string) =>
+		apiFetch<void>(`/cards/${cardId}/attachments/${fileId}`, { method: 'DELETE' }),
+
+	setCover: (cardId: string, file_id: string) =>
+		apiFetch<{ cover_file_id: string | null }>(`/cards/${cardId}/cover`, {
+			method: 'PUT',
+			body: JSON.stringify({ file_id })
 		}),
 
-	delete: (cardId: string) => apiFetch<void>(`/cards/${cardId}`, { method: 'DELETE' })
+	listTimeEntries: (cardId: string) =>
+		apiFetch<{ entries: (TimeEntry & { user_name: string })[]; total_minutes: number }>(
+			`/cards/${cardId}/time-entries`
+		),
+
+	logTime: (cardId: string, data: { minutes?: number; started_at?: string; ended_at?: string; note?: string }) =>
+		apiFetch<TimeEntry>(`/cards/${cardId}/time-entries`, { method: 'POST', body: JSON.stringify(data) }),
+
+	updateTimeEntry: (
+		cardId: string,
+		entryId: string,
+		data: Partial<{ minutes: number; started_at: string; ended_at: string; note: string }>
+	) =>
+		apiFetch<TimeEntry>(`/cards/${cardId}/time-entries/${entryId}`, {
+			method: 'PUT',
+			body: JSON.stringify(data)
+		}),
+
+	deleteTimeEntry: (cardId: string, entryId: string) =>
+		apiFetch<void>(`/cards/${cardId}/time-entries/${entryId}`, { method: 'DELETE' }),
+
+	startTimer: (cardId: string, note = '') =>
+		apiFetch<{ entry: TimeEntry; stopped: TimeEntry | null }>(`/cards/${cardId}/timer`, {
+			method: 'POST',
+			body: JSON.stringify({ note })
+		})
+};
+
+export const views = {
+	list: () => apiFetch<SavedView[]>('/views'),
+
+	create: (data: SavedViewInput) =>
+		apiFetch<SavedView>('/views', { method: 'POST', body: JSON.stringify(data) }),
+
+	update: (viewId: string, data: SavedViewInput) =>
+		apiFetch<SavedView>(`/views/${viewId}`, { method: 'PUT', body: JSON.stringify(data) }),
+
+	delete: (viewId: string) => apiFetch<void>(`/views/${viewId}`, { method: 'DELETE' })
 };
 
 export const events = {
@@ -319,13 +741,39 @@ export const notifications = {
 	delete: (notifId: string) => apiFetch<void>(`/notifications/${notifId}`, { method: 'DELETE' })
 };
 
+export const watches = {
+	mine: () => apiFetch<(Watch & { target_name: string })[]>('/users/me/watches'),
+
+	cardWatchers: (cardId: string) => apiFetch<CardWatchers>(`/cards/${cardId}/watchers`),
+
+	watchCard: (cardId: string) => apiFetch<Watch>(`/cards/${cardId}/watch`, { method: 'PUT' }),
+
+	unwatchCard: (cardId: string) => apiFetch<void>(`/cards/${cardId}/watch`, { method: 'DELETE' }),
+
+	watchColumn: (projectId: string, columnId: string) =>
+		apiFetch<Watch>(`/projects/${projectId}/columns/${columnId}/watch`, { method: 'PUT' }),
+
+	unwatchColumn: (projectId: string, columnId: string) =>
+		apiFetch<void>(`/projects/${projectId}/columns/${columnId}/watch`, { method: 'DELETE' }),
+
+	watchProject: (projectId: string) => apiFetch<Watch>(`/projects/${projectId}/watch`, { method: 'PUT' }),
+
+	unwatchProject: (projectId: string) =>
+		apiFetch<void>(`/projects/${projectId}/watch`, { method: 'DELETE' })
+};
+
 export const docs = {
 	get: (docId: string) => apiFetch<Doc>(`/docs/${docId}`),
 
-	update: (docId: string, data: Partial<Pick<Doc, 'title' | 'content'>>) =>
-		apiFetch<Doc>(`/docs/${docId}`, { method: 'PUT', body: JSON.stringify(data) }),
+	update: (docId: string, data: Partial<Pick<Doc, 'title' | 'content'>>, version?: number) =>
+		apiFetch<Doc>(`/docs/${docId}`, {
+			method: 'PUT',
+			headers: ifMatch(version),
+			body: JSON.stringify(data)
+		}),
 
-	delete: (docId: string) => apiFetch<void>(`/docs/${docId}`, { method: 'DELETE' })
+	delete: (docId: string, version?: number) =>
+		apiFetch<void>(`/docs/${docId}`, { method: 'DELETE', headers: ifMatch(version) })
 };
 
 export const files = {
@@ -358,6 +806,30 @@ export const webhooks = {
 	delete: (webhookId: string) => apiFetch<void>(`/webhooks/${webhookId}`, { method: 'DELETE' })
 };
 
+export const timesheets = {
+	get: (opts: { week?: string; project_id?: string; user_id?: string } = {}) => {
+		const params = new URLSearchParams(opts as Record<string, string>);
+		return apiFetch<Timesheet>(`/timesheets?${params}`);
+	},
+
+	downloadCsv: async (opts: { week?: string; project_id?: string; user_id?: string } = {}) => {
+		const { getAccessToken } = await import('./client');
+		const token = getAccessToken();
+		const headers: Record<string, string> = {};
+		if (token) headers['Authorization'] = `Bearer ${token}`;
+		const params = new URLSearchParams({ ...opts, format: 'csv' } as Record<string, string>);
+		const res = await fetch(`/api/timesheets?${params}`, { headers });
+		if (!res.ok) throw new Error(`HTTP ${res.status}`);
+		const blob = await res.blob();
+		const url = URL.createObjectURL(blob);
+		const a = document.createElement('a');
+		a.href = url;
+		a.download = `timesheet-${opts.week ?? 'current'}.csv`;
+		a.click();
+		URL.revokeObjectURL(url);
+	}
+};
+
 export const apiKeys = {
 	list: () => apiFetch<ApiKey[]>('/users/me/api-keys'),
 
@@ -369,3 +841,13 @@ export const apiKeys = {
 
 	revoke: (keyId: string) => apiFetch<void>(`/users/me/api-keys/${keyId}`, { method: 'DELETE' })
 };
+
+export const search = {
+	query: (q: string, opts: { type?: SearchType; limit?: number; offset?: number } = {}) => {
+		const params = new URLSearchParams({ q });
+		if (opts.type) params.set('type', opts.type);
+		if (opts.limit) params.set('limit', String(opts.limit));
+		if (opts.offset) params.set('offset', String(opts.offset));
+		return apiFetch<SearchResponse>(`/search?${params}`);
+	}
+};
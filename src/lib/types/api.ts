@@ -40,6 +40,8 @@ export interface Project {
 	visibility?: string;
 	is_public: boolean;
 	is_archived: boolean;
+	key?: string;
+	former_keys?: string[];
 	created_by: string;
 	created_at: string;
 	updated_at: string;
@@ -56,44 +58,478 @@ export interface ProjectMember {
 	added_at: string;
 }
 
+export interface Label {
+	id: string;
+	project_id: string;
+	name: string;
+	color: string;
+	description: string;
+	created_by: string;
+	created_at: string;
+	updated_at: string;
+}
+
 export interface Subtask {
 	id: number;
+	checklist_id?: number;
 	text: string;
 	done: boolean;
+	assignee?: string;
+	due_date?: string;
+}
+
+export interface Checklist {
+	id: number;
+	name: string;
+}
+
+export interface Swimlane {
+	id: string;
+	project_id: string;
+	title: string;
+	rank: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export interface RecurrenceTemplate {
+	title: string;
+	description: string;
+	priority: string;
+	color: string;
+	assignees: string[];
+	label_ids: string[];
+	subtasks: string[];
+	estimated_minutes?: number;
+}
+
+export interface Recurrence {
+	id: string;
+	project_id: string;
+	column_id: string;
+	lane_id?: string;
+	rule: string;
+	start_at: string;
+	due_offset_days?: number;
+	template: RecurrenceTemplate;
+	paused: boolean;
+	next_run_at?: string;
+	last_run_at?: string;
+	last_card_id?: string;
+	generated: number;
+	created_by: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export interface RecurrenceWithUpcoming {
+	recurrence: Recurrence;
+	upcoming: string[];
+}
+
+export interface RecurrenceInput extends Partial<Omit<RecurrenceTemplate, 'subtasks'>> {
+	column_id?: string;
+	lane_id?: string;
+	rule?: string;
+	start_at?: string;
+	due_offset_days?: number;
+	card_id?: string;
+	subtasks?: string[];
+}
+
+export type AutomationTriggerType =
+	| 'card.created'
+	| 'card.updated'
+	| 'card.moved'
+	| 'card.overdue'
+	| 'schedule';
+
+export interface AutomationTrigger {
+	type: AutomationTriggerType;
+	column_id?: string;
+	field?: string;
+	rule?: string;
+}
+
+export type AutomationActionType =
+	| 'move'
+	| 'set_priority'
+	| 'assign'
+	| 'unassign'
+	| 'add_label'
+	| 'remove_label'
+	| 'archive'
+	| 'notify'
+	| 'prompt_time';
+
+export interface AutomationAction {
+	type: AutomationActionType;
+	column_id?: string;
+	label_id?: string;
+	value?: string;
+	target?: string;
+	message?: string;
+}
+
+export interface Automation {
+	id: string;
+	project_id: string;
+	name: string;
+	enabled: boolean;
+	trigger: AutomationTrigger;
+	conditions: string;
+	actions: AutomationAction[];
+	start_at?: string;
+	next_run_at?: string;
+	created_by: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export type AutomationInput = Partial<
+	Pick<Automation, 'name' | 'enabled' | 'trigger' | 'conditions' | 'actions'>
+>;
+
+export interface AutomationRun {
+	id: string;
+	automation_id: string;
+	project_id: string;
+	card_id?: string;
+	trigger: string;
+	status: 'ok' | 'skipped' | 'failed';
+	actions: string[];
+	error?: string;
+	depth: number;
+	created_at: string;
+}
+
+export interface AutomationTestResult {
+	trigger_matches: boolean;
+	conditions_match: boolean;
+	would_run: boolean;
+	actions: { type: AutomationActionType; description: string; error?: string }[];
+}
+
+export type CustomFieldType =
+	| 'text'
+	| 'number'
+	| 'date'
+	| 'select'
+	| 'multi_select'
+	| 'user'
+	| 'checkbox'
+	| 'url';
+
+export type CustomFieldValue = string | number | boolean | string[];
+
+export interface CustomField {
+	id: string;
+	project_id: string;
+	name: string;
+	key: string;
+	type: CustomFieldType;
+	options?: string[];
+	required: boolean;
+	created_by: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export type SprintState = 'planned' | 'active' | 'closed';
+
+export interface Sprint {
+	id: string;
+	project_id: string;
+	name: string;
+	goal: string;
+	start_date?: string;
+	end_date?: string;
+	state: SprintState;
+	committed_card_ids?: string[];
+	completed_card_ids?: string[];
+	rolled_over: number;
+	started_at?: string;
+	completed_at?: string;
+	created_by: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export interface SprintProgress extends Sprint {
+	card_count: number;
+	done_count: number;
+	estimated_minutes: number;
+	done_minutes: number;
+}
+
+export interface SprintVelocity {
+	field: string;
+	average: number;
+	sprints: {
+		id: string;
+		name: string;
+		state: SprintState;
+		start_date?: string;
+		end_date?: string;
+		committed: number;
+		completed: number;
+	}[];
+}
+
+export type BulkCardOp =
+	| 'move'
+	| 'priority'
+	| 'assignees'
+	| 'labels'
+	| 'due_date'
+	| 'archive'
+	| 'restore'
+	| 'delete';
+
+export interface BulkCardRequest {
+	op: BulkCardOp;
+	card_ids?: string[];
+	query?: string;
+	column_id?: string;
+	priority?: string;
+	assignees?: string[];
+	label_ids?: string[];
+	due_date?: string;
+	mode?: 'set' | 'add' | 'remove';
+	force?: boolean;
+}
+
+export interface BulkCardResponse {
+	op: BulkCardOp;
+	transactional: boolean;
+	succeeded: number;
+	skipped: number;
+	failed: number;
+	results: { card_id: string; status: 'ok' | 'skipped' | 'failed'; error?: string }[];
+}
+
+export interface ArchivedItems {
+	columns: (Column & { card_count: number; purge_at: string | null })[];
+	cards: (Card & { column_title: string; purge_at: string | null })[];
+}
+
+export interface TimeEntry {
+	id: string;
+	project_id: string;
+	card_id: string;
+	user_id: string;
+	started_at: string;
+	ended_at?: string;
+	minutes: number;
+	running: boolean;
+	note: string;
+	created_at: string;
+	updated_at: string;
+}
+
+export interface TimesheetRow {
+	user_id: string;
+	user_name: string;
+	project_id: string;
+	project_name: string;
+	card_id: string;
+	card_title: string;
+	days: number[];
+	total: number;
+}
+
+export interface Timesheet {
+	week_start: string;
+	week_end: string;
+	rows: TimesheetRow[];
+	day_totals: number[];
+	total: number;
 }
 
 export interface Card {
 	id: string;
 	column_id: string;
+	lane_id?: string;
+	recurrence_id?: string;
+	sprint_id?: string;
 	project_id: string;
+	number?: number;
+	key?: string;
 	title: string;
 	description: string;
 	priority: string;
 	color: string;
 	due_date?: string;
 	assignees: string[];
+	label_ids?: string[];
+	attachment_ids?: string[];
+	cover_file_id?: string;
 	estimated_minutes?: number;
 	actual_minutes?: number;
 	subtasks: Subtask[];
-	position: number;
+	checklists?: Checklist[];
+	custom_fields?: Record<string, CustomFieldValue>;
+	rank: string;
+	archived_at?: string;
+	column_archived?: boolean;
+	version: number;
+	comment_count?: number;
+	attachment_count?: number;
+	links?: CardLinkSummary[];
 	created_by: string;
 	created_at: string;
 	updated_at: string;
 }
 
+export interface CardLinkSummary {
+	id: string;
+	type: 'blocks' | 'blocked_by' | 'relates_to' | 'duplicates' | 'duplicated_by';
+	card_id: string;
+	project_id: string;
+	title?: string;
+	done: boolean;
+	archived?: boolean;
+	restricted?: boolean;
+}
+
+export interface CardComment {
+	id: string;
+	card_id: string;
+	project_id: string;
+	user_id: string;
+	user_name?: string;
+	avatar_url?: string;
+	content: string;
+	mentions: string[];
+	edited_at?: string;
+	created_at: string;
+}
+
 export interface Column {
 	id: string;
 	project_id: string;
 	title: string;
-	position: number;
+	rank: string;
+	is_done?: boolean;
+	wip_limit?: number;
+	assignee_wip_limit?: number;
+	wip_mode?: 'warn' | 'block';
+	archived_at?: string;
+	version: number;
+	over_limit?: boolean;
+	over_limit_assignees?: string[];
 	cards?: Card[];
 	created_at: string;
 	updated_at: string;
 }
 
+export type BoardGroupBy = 'lane' | 'assignee' | 'priority' | 'label';
+
+export interface BoardLane {
+	key: string;
+	title: string;
+	cells: { column_id: string; card_ids: string[] }[];
+}
+
 export interface BoardData {
 	project_id: string;
 	columns: Column[];
+	query: string;
+	sort: string;
+	view_id?: string;
+	group_by?: BoardGroupBy;
+	lanes?: BoardLane[];
+	seq: number;
+}
+
+export type BoardEventType =
+	| 'card.created'
+	| 'card.updated'
+	| 'card.moved'
+	| 'card.deleted'
+	| 'card.archived'
+	| 'card.restored'
+	| 'column.created'
+	| 'column.updated'
+	| 'column.moved'
+	| 'column.deleted'
+	| 'column.archived'
+	| 'column.restored'
+	| 'ranks.rebalanced';
+
+export interface BoardPresence {
+	user_id: string;
+	name: string;
+	card_id?: string;
+	editing: boolean;
+}
+
+export type BoardSyncMessage =
+	| { type: 'hello'; seq: number }
+	| {
+			type: 'event';
+			seq: number;
+			event: BoardEventType;
+			actor_id: string;
+			data: Record<string, unknown>;
+			created_at: string;
+	  }
+	| { type: 'presence'; users: BoardPresence[] }
+	| { type: 'resync'; seq: number }
+	| { type: 'error'; payload: string };
+
+export interface BoardQueryOptions {
+	q?: string;
+	view?: string;
+	sort?: string;
+	labelIds?: string[];
+	groupBy?: BoardGroupBy;
+}
+
+export interface SavedView {
+	id: string;
+	owner_id: string;
+	project_id?: string;
+	name: string;
+	query: string;
+	group_by?: BoardGroupBy;
+	sort?: string;
+	shared: boolean;
+	is_default: boolean;
+	created_at: string;
+	updated_at: string;
+}
+
+export type SavedViewInput = Partial<
+	Pick<SavedView, 'name' | 'query' | 'group_by' | 'sort' | 'shared' | 'is_default'>
+>;
+
+export interface CardSearchResult {
+	cards: (Card & { project_name: string; column_title: string })[];
+	total: number;
+	has_more: boolean;
+	query: string;
+}
+
+export type SearchType = 'cards' | 'docs' | 'messages' | 'files' | 'events' | 'projects';
+
+export interface SearchHit {
+	type: SearchType;
+	id: string;
+	title: string;
+	/** HTML-escaped excerpt with matches wrapped in <mark>. */
+	highlight: string;
+	score: number;
+	project_id?: string;
+	project_name?: string;
+	team_id?: string;
+	team_name?: string;
+	updated_at: string;
+}
+
+export interface SearchResponse {
+	query: string;
+	results: Partial<Record<SearchType, { results: SearchHit[]; total: number; has_more: boolean }>>;
 }
 
 export interface Event {
@@ -116,15 +552,45 @@ export interface Notification {
 	type: string;
 	message: string;
 	project_id: string;
+	card_id?: string;
+	changes?: string[];
 	read: boolean;
 	created_at: string;
 }
 
+export type WatchTarget = 'card' | 'column' | 'project';
+
+export interface Watch {
+	id: string;
+	user_id: string;
+	project_id: string;
+	target_type: WatchTarget;
+	target_id: string;
+	auto: boolean;
+	created_at: string;
+}
+
+export interface CardWatchers {
+	watching: boolean;
+	via: WatchTarget | null;
+	watchers: { user_id: string; name: string; auto: boolean }[];
+}
+
+export interface CardRef {
+	key: string;
+	card_id: string;
+	project_id: string;
+	title: string;
+	archived?: boolean;
+}
+
 export interface Doc {
 	id: string;
 	team_id: string;
 	title: string;
 	content: string;
+	version: number;
+	card_refs?: CardRef[];
 	created_by: string;
 	created_at: string;
 	updated_at: string;
@@ -195,5 +661,6 @@ export interface ChatMessage {
 	reply_to?: string;
 	edited_at?: string;
 	deleted?: boolean;
+	card_refs?: CardRef[];
 	created_at: string;
 }